@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/dnsupdate"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/metrics"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/resolver"
+	"go.uber.org/zap"
+)
+
+// MetricsBindAddress enables the Prometheus metrics endpoint when set, e.g. ":9402".
+var MetricsBindAddress = os.Getenv("METRICS_BIND_ADDRESS")
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	flags := flag.NewFlagSet("stackit-dns-update", flag.ContinueOnError)
+	configPath := flags.String("config", os.Getenv("DNSUPDATE_CONFIG_PATH"), "path of the DNS UPDATE config file")
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		return 2
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+
+	cfg, err := dnsupdate.LoadConfig(*configPath)
+	if err != nil {
+		logger.Error("Error loading DNS UPDATE config", zap.Error(err), zap.String("path", *configPath))
+
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if MetricsBindAddress != "" {
+		go metrics.Serve(MetricsBindAddress, logger, ctx.Done())
+	}
+
+	repositories := resolver.NewRepositoryProvider(
+		&http.Client{},
+		logger,
+		repository.NewZoneRepositoryFactory(),
+		repository.NewRRSetRepositoryFactory(),
+		resolver.NewKubeconfigSecretFetcher(ctx),
+	)
+
+	server, err := dnsupdate.NewServer(repositories, logger, cfg)
+	if err != nil {
+		logger.Error("Error creating server", zap.Error(err))
+
+		return 1
+	}
+
+	if err := server.Serve(ctx); err != nil {
+		logger.Error("Error serving", zap.Error(err))
+
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/httpapi"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/metrics"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/resolver"
+	"go.uber.org/zap"
+	"k8s.io/client-go/rest"
+)
+
+// MetricsBindAddress enables the Prometheus metrics endpoint when set, e.g. ":9402".
+var MetricsBindAddress = os.Getenv("METRICS_BIND_ADDRESS")
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	flags := flag.NewFlagSet("stackit-dns-httpapi", flag.ContinueOnError)
+	configPath := flags.String("config", os.Getenv("HTTPAPI_CONFIG_PATH"), "path of the server config file")
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		return 2
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+
+	cfg, err := httpapi.LoadConfig(*configPath)
+	if err != nil {
+		logger.Error("Error loading server config", zap.Error(err), zap.String("path", *configPath))
+
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if MetricsBindAddress != "" {
+		go metrics.Serve(MetricsBindAddress, logger, ctx.Done())
+	}
+
+	solver := resolver.NewResolver(
+		&http.Client{},
+		logger,
+		repository.NewZoneRepositoryFactory(),
+		repository.NewRRSetRepositoryFactory(),
+		resolver.NewKubeconfigSecretFetcher(ctx),
+		resolver.NewConfigProvider(),
+	)
+
+	// Inside Kubernetes the solver caches Secrets, watches credentials and
+	// coordinates with other replicas like the webhook does.
+	if kubeConfig, err := rest.InClusterConfig(); err == nil {
+		if err := solver.Initialize(kubeConfig, ctx.Done()); err != nil {
+			return 1
+		}
+	}
+
+	server, err := httpapi.NewServer(solver, logger, cfg)
+	if err != nil {
+		logger.Error("Error creating server", zap.Error(err))
+
+		return 1
+	}
+
+	if err := server.Serve(ctx); err != nil {
+		logger.Error("Error serving", zap.Error(err))
+
+		return 1
+	}
+
+	return 0
+}
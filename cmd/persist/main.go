@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/resolver"
+	"go.uber.org/zap"
+)
+
+const usage = `Usage: stackit-dns-persist [-config path] apply|audit|run
+
+Maintains the dns-persist-01 authorization records listed in the config.
+
+  apply  write missing and drifted records once and print a report
+  audit  print a report without writing, exiting with 1 on any finding
+  run    apply the config every interval until terminated
+`
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	flags := flag.NewFlagSet("stackit-dns-persist", flag.ContinueOnError)
+	configPath := flags.String("config", os.Getenv("PERSIST_CONFIG_PATH"), "path of the persist config file")
+	flags.Usage = func() {
+		_, _ = flags.Output().Write([]byte(usage + "\nFlags:\n"))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(os.Args[1:]); err != nil || flags.NArg() != 1 {
+		flags.Usage()
+
+		return 2
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+
+	cfg, err := resolver.LoadPersistConfig(*configPath)
+	if err != nil {
+		logger.Error("Error loading persist config", zap.Error(err), zap.String("path", *configPath))
+
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reconciler := resolver.NewPersistReconciler(
+		&http.Client{},
+		logger,
+		repository.NewZoneRepositoryFactory(),
+		repository.NewRRSetRepositoryFactory(),
+		resolver.NewKubeconfigSecretFetcher(ctx),
+		cfg,
+	)
+
+	switch command := flags.Arg(0); command {
+	case "apply", "audit":
+		return report(ctx, reconciler, command == "apply")
+	case "run":
+		reconciler.Run(ctx)
+
+		return 0
+	default:
+		flags.Usage()
+
+		return 2
+	}
+}
+
+func report(ctx context.Context, reconciler *resolver.PersistReconciler, apply bool) int {
+	reports, err := reconciler.Reconcile(ctx, apply)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if encodeErr := encoder.Encode(reports); encodeErr != nil {
+		return 1
+	}
+
+	if err != nil {
+		return 1
+	}
+	for _, report := range reports {
+		if !apply && len(report.Findings) > 0 {
+			return 1
+		}
+	}
+
+	return 0
+}
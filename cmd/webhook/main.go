@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/cert-manager/cert-manager/pkg/acme/webhook/cmd"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/metrics"
 	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
 	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/resolver"
 	"go.uber.org/zap"
@@ -13,6 +14,9 @@ import (
 // GroupName is the K8s API group.
 var GroupName = os.Getenv("GROUP_NAME")
 
+// MetricsBindAddress enables the Prometheus metrics endpoint when set, e.g. ":9402".
+var MetricsBindAddress = os.Getenv("METRICS_BIND_ADDRESS")
+
 func main() {
 	if GroupName == "" {
 		panic("GROUP_NAME must be specified")
@@ -23,6 +27,10 @@ func main() {
 		panic(err)
 	}
 
+	if MetricsBindAddress != "" {
+		go metrics.Serve(MetricsBindAddress, logger, nil)
+	}
+
 	// This will register our custom DNS provider with the webhook serving
 	// library, making it available as an API under the provided GroupName.
 	// You can register multiple DNS provider implementations with a single
@@ -0,0 +1,160 @@
+package dnsupdate
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/resolver"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	defaultListenAddress = ":53"
+	defaultAlgorithm     = dns.HmacSHA256
+)
+
+// algorithms are the TSIG algorithms keys may use.
+var algorithms = []string{dns.HmacSHA1, dns.HmacSHA224, dns.HmacSHA256, dns.HmacSHA384, dns.HmacSHA512}
+
+// Config configures the DNS UPDATE listener.
+type Config struct {
+	// ListenAddress is served over UDP and TCP.
+	ListenAddress string `json:"listenAddress"`
+	// Namespace is used for credential Secrets of providers without
+	// authTokenSecretNamespace. Empty means the namespace of the pod.
+	Namespace string `json:"namespace"`
+	// Provider is the solver config of keys without a provider of their own.
+	Provider resolver.StackitDnsProviderConfig `json:"provider"`
+	Keys     []KeyConfig                       `json:"keys"`
+}
+
+// KeyConfig is a TSIG key and the zones updates signed with it may change.
+type KeyConfig struct {
+	// Name is the key name clients sign with, e.g. "lego.".
+	Name string `json:"name"`
+	// Algorithm defaults to hmac-sha256.
+	Algorithm string `json:"algorithm"`
+	// Secret is the base64 encoded key, as printed by `tsig-keygen`. Exactly
+	// one of Secret and SecretFile must be specified.
+	Secret     string                             `json:"secret"`
+	SecretFile string                             `json:"secretFile"`
+	Zones      []string                           `json:"zones"`
+	Provider   *resolver.StackitDnsProviderConfig `json:"provider"`
+}
+
+// LoadConfig reads a YAML or JSON listener config file.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("error reading DNS UPDATE config: %w", err)
+	}
+
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("error decoding DNS UPDATE config: %w", err)
+	}
+
+	if err := validateConfig(&cfg); err != nil {
+		return cfg, err
+	}
+
+	if cfg.ListenAddress == "" {
+		cfg.ListenAddress = defaultListenAddress
+	}
+
+	return cfg, nil
+}
+
+func validateConfig(cfg *Config) error {
+	if len(cfg.Keys) == 0 {
+		return fmt.Errorf("keys must not be empty")
+	}
+
+	names := map[string]bool{}
+	for i := range cfg.Keys {
+		key := &cfg.Keys[i]
+		if err := validateKeyConfig(key); err != nil {
+			return fmt.Errorf("keys[%d]: %w", i, err)
+		}
+
+		name := keyName(key.Name)
+		if names[name] {
+			return fmt.Errorf("keys[%d]: duplicate name %q", i, key.Name)
+		}
+		names[name] = true
+	}
+
+	return nil
+}
+
+func validateKeyConfig(key *KeyConfig) error {
+	if _, ok := dns.IsDomainName(key.Name); !ok || key.Name == "" || key.Name == "." {
+		return fmt.Errorf("invalid name %q", key.Name)
+	}
+
+	if _, err := keyAlgorithm(key.Algorithm); err != nil {
+		return err
+	}
+
+	switch {
+	case (key.Secret == "") == (key.SecretFile == ""):
+		return fmt.Errorf("exactly one of secret and secretFile must be specified")
+	case key.Secret != "":
+		if _, err := base64.StdEncoding.DecodeString(key.Secret); err != nil {
+			return fmt.Errorf("secret: %w", err)
+		}
+	}
+
+	if len(key.Zones) == 0 {
+		return fmt.Errorf("zones must not be empty")
+	}
+
+	_, err := repository.AbsoluteZoneNames(key.Zones)
+
+	return err
+}
+
+// keySecret returns the base64 encoded secret of key, reading secretFile.
+func keySecret(key *KeyConfig) (string, error) {
+	if key.SecretFile == "" {
+		return key.Secret, nil
+	}
+
+	data, err := os.ReadFile(key.SecretFile)
+	if err != nil {
+		return "", fmt.Errorf("error reading secretFile: %w", err)
+	}
+
+	secret := strings.TrimSpace(string(data))
+	if _, err := base64.StdEncoding.DecodeString(secret); err != nil {
+		return "", fmt.Errorf("secretFile %s: %w", key.SecretFile, err)
+	}
+
+	return secret, nil
+}
+
+// keyName returns the form of a key name TSIG records carry.
+func keyName(name string) string {
+	return dns.CanonicalName(name)
+}
+
+// keyAlgorithm returns the canonical name of a TSIG algorithm, defaulting to
+// hmac-sha256.
+func keyAlgorithm(algorithm string) (string, error) {
+	if algorithm == "" {
+		return defaultAlgorithm, nil
+	}
+
+	canonical := dns.CanonicalName(algorithm)
+	if slices.Contains(algorithms, canonical) {
+		return canonical, nil
+	}
+
+	return "", fmt.Errorf("unsupported algorithm %q", algorithm)
+}
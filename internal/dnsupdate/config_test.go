@@ -0,0 +1,57 @@
+package dnsupdate_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/dnsupdate"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "dnsupdate.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+provider:
+  projectId: project
+keys:
+  - name: lego.
+    secret: `+legoSecret+`
+    zones: [example.com]
+  - name: certbot
+    algorithm: HMAC-SHA512.
+    secretFile: /etc/tsig/certbot
+    zones: [example.com, other.org]
+`), 0o600))
+
+	cfg, err := dnsupdate.LoadConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, ":53", cfg.ListenAddress)
+	require.Equal(t, "project", cfg.Provider.ProjectId)
+	require.Len(t, cfg.Keys, 2)
+
+	for _, tt := range []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{"no keys", `provider: {projectId: project}`, "keys must not be empty"},
+		{"unknown field", `keys: [{name: lego, zone: example.com}]`, "error decoding DNS UPDATE config"},
+		{"name", `keys: [{name: "", secret: ` + legoSecret + `, zones: [a.com]}]`, `keys[0]: invalid name ""`},
+		{"algorithm", `keys: [{name: lego, algorithm: hmac-md4, secret: ` + legoSecret + `, zones: [a.com]}]`,
+			`unsupported algorithm "hmac-md4"`},
+		{"no secret", `keys: [{name: lego, zones: [a.com]}]`, "exactly one of secret and secretFile"},
+		{"both secrets", `keys: [{name: lego, secret: ` + legoSecret + `, secretFile: key, zones: [a.com]}]`,
+			"exactly one of secret and secretFile"},
+		{"secret", `keys: [{name: lego, secret: "not base64", zones: [a.com]}]`, "keys[0]: secret"},
+		{"no zones", `keys: [{name: lego, secret: ` + legoSecret + `}]`, "zones must not be empty"},
+		{"duplicate name", `keys: [{name: lego, secret: ` + legoSecret + `, zones: [a.com]},
+			{name: LEGO., secret: ` + legoSecret + `, zones: [b.com]}]`, `keys[1]: duplicate name "LEGO."`},
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(tt.config), 0o600))
+		_, err := dnsupdate.LoadConfig(path)
+		require.ErrorContains(t, err, tt.err, tt.name)
+	}
+}
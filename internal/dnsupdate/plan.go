@@ -0,0 +1,138 @@
+package dnsupdate
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/miekg/dns"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/resolver"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+)
+
+// rrSetPlan collects the changes of an update to one TXT record set.
+type rrSetPlan struct {
+	existing *stackitdnsclient.RecordSet
+	records  []txtRecord
+	ttl      int32
+	changed  bool
+}
+
+// txtRecord is a record with its content as the API returns it and the
+// character strings it holds.
+type txtRecord struct {
+	content string
+	txt     []string
+}
+
+// fetchRRSetPlan returns a plan starting from the TXT record set named name,
+// which may not exist yet.
+func fetchRRSetPlan(
+	ctx context.Context,
+	rrSetRepository repository.RRSetRepository,
+	name string,
+) (*rrSetPlan, error) {
+	existing, err := rrSetRepository.FetchRRSetForZone(ctx, name, typeTxt)
+	if errors.Is(err, repository.ErrRRSetNotFound) {
+		return &rrSetPlan{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	plan := &rrSetPlan{existing: existing, ttl: existing.Ttl}
+	for _, record := range existing.Records {
+		plan.records = append(plan.records, txtRecord{content: record.Content, txt: txtStrings(record.Content)})
+	}
+
+	return plan, nil
+}
+
+func (p *rrSetPlan) values() [][]string {
+	values := make([][]string, len(p.records))
+	for i, record := range p.records {
+		values[i] = record.txt
+	}
+
+	return values
+}
+
+func (p *rrSetPlan) index(txt []string) int {
+	return slices.IndexFunc(p.records, func(record txtRecord) bool { return slices.Equal(record.txt, txt) })
+}
+
+// apply applies a change as in RFC 2136 section 3.4.2. Adding a value sets
+// the TTL of the whole record set, which the API keeps per record set.
+func (p *rrSetPlan) apply(c change) {
+	switch c.class {
+	case dns.ClassINET:
+		if p.index(c.txt) < 0 {
+			p.records = append(p.records, txtRecord{content: resolver.EncodeTXTStrings(c.txt), txt: c.txt})
+			p.changed = true
+		}
+		if p.ttl != int32(c.ttl) {
+			p.ttl = int32(c.ttl)
+			p.changed = true
+		}
+	case dns.ClassANY:
+		if len(p.records) > 0 {
+			p.records = nil
+			p.changed = true
+		}
+	case dns.ClassNONE:
+		if i := p.index(c.txt); i >= 0 {
+			p.records = slices.Delete(p.records, i, i+1)
+			p.changed = true
+		}
+	}
+}
+
+// write creates, updates or, once it holds no records, deletes the record
+// set.
+func (p *rrSetPlan) write(ctx context.Context, rrSetRepository repository.RRSetRepository, name string) error {
+	if !p.changed {
+		return nil
+	}
+
+	records := make([]stackitdnsclient.Record, len(p.records))
+	for i, record := range p.records {
+		records[i] = stackitdnsclient.Record{Content: record.content}
+	}
+
+	switch {
+	case p.existing == nil && len(records) == 0:
+		return nil
+	case p.existing == nil:
+		return rrSetRepository.CreateRRSet(ctx, stackitdnsclient.RecordSet{
+			Comment: new(rrSetComment),
+			Name:    name,
+			Records: records,
+			Ttl:     p.ttl,
+			Type:    typeTxt,
+		})
+	case len(records) == 0:
+		err := rrSetRepository.DeleteRRSet(ctx, p.existing.Id)
+		if errors.Is(err, repository.ErrRRSetNotFound) {
+			return nil
+		}
+
+		return err
+	default:
+		rrSet := *p.existing
+		rrSet.Records = records
+		rrSet.Ttl = p.ttl
+
+		return rrSetRepository.UpdateRRSet(ctx, rrSet)
+	}
+}
+
+// txtStrings returns the character strings of TXT record content. Content
+// that does not parse is taken as a single string.
+func txtStrings(content string) []string {
+	strs, err := resolver.ParseTXTContent(content)
+	if err != nil {
+		return []string{content}
+	}
+
+	return strs
+}
@@ -0,0 +1,179 @@
+package dnsupdate_test
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/resolver"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+)
+
+// memoryRepositories keeps the record sets of zones in memory.
+type memoryRepositories struct {
+	mu     sync.Mutex
+	zones  map[string][]stackitdnsclient.RecordSet
+	nextId int
+}
+
+func newMemoryRepositories(zones ...string) *memoryRepositories {
+	repositories := &memoryRepositories{zones: map[string][]stackitdnsclient.RecordSet{}}
+	for _, zone := range zones {
+		repositories.zones[zone] = nil
+	}
+
+	return repositories
+}
+
+func (m *memoryRepositories) RRSetRepository(
+	_ context.Context,
+	_ *resolver.StackitDnsProviderConfig,
+	zone string,
+) (repository.RRSetRepository, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	zone = strings.TrimSuffix(zone, ".")
+	if _, ok := m.zones[zone]; !ok {
+		return nil, repository.ErrZoneNotFound
+	}
+
+	return &memoryRRSetRepository{repositories: m, zone: zone}, nil
+}
+
+// add stores a record set with the given record contents.
+func (m *memoryRepositories) add(zone, name, rrSetType string, contents ...string) {
+	_ = (&memoryRRSetRepository{repositories: m, zone: zone}).CreateRRSet(context.Background(),
+		stackitdnsclient.RecordSet{Name: name, Type: stackitdnsclient.RecordSetType(rrSetType), Ttl: 300,
+			Records: records(contents)})
+}
+
+// contents returns the record contents of the TXT record set name in zone.
+func (m *memoryRepositories) contents(zone, name string) []string {
+	rrSet := m.rrSet(zone, name)
+	if rrSet == nil {
+		return nil
+	}
+
+	contents := make([]string, len(rrSet.Records))
+	for i, record := range rrSet.Records {
+		contents[i] = record.Content
+	}
+	slices.Sort(contents)
+
+	return contents
+}
+
+func (m *memoryRepositories) rrSet(zone, name string) *stackitdnsclient.RecordSet {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, rrSet := range m.zones[zone] {
+		if rrSet.Name == name && rrSet.Type == "TXT" {
+			return &rrSet
+		}
+	}
+
+	return nil
+}
+
+type memoryRRSetRepository struct {
+	repositories *memoryRepositories
+	zone         string
+}
+
+func (r *memoryRRSetRepository) FetchRRSetForZone(
+	ctx context.Context,
+	rrSetName string,
+	rrSetType string,
+) (*stackitdnsclient.RecordSet, error) {
+	for rrSet, err := range r.ListRRSets(ctx, repository.RRSetListOptions{Name: rrSetName, Type: rrSetType}) {
+		if err != nil {
+			return nil, err
+		}
+
+		return &rrSet, nil
+	}
+
+	return nil, repository.ErrRRSetNotFound
+}
+
+func (r *memoryRRSetRepository) ListRRSets(
+	_ context.Context,
+	options repository.RRSetListOptions,
+) iter.Seq2[stackitdnsclient.RecordSet, error] {
+	r.repositories.mu.Lock()
+	rrSets := slices.Clone(r.repositories.zones[r.zone])
+	r.repositories.mu.Unlock()
+
+	return func(yield func(stackitdnsclient.RecordSet, error) bool) {
+		for _, rrSet := range rrSets {
+			if options.Name != "" && rrSet.Name != options.Name {
+				continue
+			}
+			if options.Type != "" && string(rrSet.Type) != options.Type {
+				continue
+			}
+			if !yield(rrSet, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (r *memoryRRSetRepository) CreateRRSet(_ context.Context, rrSet stackitdnsclient.RecordSet) error {
+	r.repositories.mu.Lock()
+	defer r.repositories.mu.Unlock()
+
+	r.repositories.nextId++
+	rrSet.Id = fmt.Sprintf("rrset-%d", r.repositories.nextId)
+	rrSet.Active = new(true)
+	r.repositories.zones[r.zone] = append(r.repositories.zones[r.zone], rrSet)
+
+	return nil
+}
+
+func (r *memoryRRSetRepository) UpdateRRSet(_ context.Context, rrSet stackitdnsclient.RecordSet) error {
+	r.repositories.mu.Lock()
+	defer r.repositories.mu.Unlock()
+
+	rrSets := r.repositories.zones[r.zone]
+	i := slices.IndexFunc(rrSets, func(existing stackitdnsclient.RecordSet) bool { return existing.Id == rrSet.Id })
+	if i < 0 {
+		return repository.ErrRRSetNotFound
+	}
+	rrSets[i] = rrSet
+
+	return nil
+}
+
+func (r *memoryRRSetRepository) DeleteRRSet(_ context.Context, rrSetId string) error {
+	r.repositories.mu.Lock()
+	defer r.repositories.mu.Unlock()
+
+	rrSets := r.repositories.zones[r.zone]
+	i := slices.IndexFunc(rrSets, func(rrSet stackitdnsclient.RecordSet) bool { return rrSet.Id == rrSetId })
+	if i < 0 {
+		return repository.ErrRRSetNotFound
+	}
+	r.repositories.zones[r.zone] = slices.Delete(rrSets, i, i+1)
+
+	return nil
+}
+
+func (r *memoryRRSetRepository) RestoreRRSet(context.Context, string) error {
+	return nil
+}
+
+func records(contents []string) []stackitdnsclient.Record {
+	records := make([]stackitdnsclient.Record, len(contents))
+	for i, content := range contents {
+		records[i] = stackitdnsclient.Record{Content: content}
+	}
+
+	return records
+}
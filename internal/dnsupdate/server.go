@@ -0,0 +1,314 @@
+package dnsupdate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/resolver"
+	"go.uber.org/zap"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const (
+	// updateTimeout bounds the DNS API calls of one update.
+	updateTimeout = 30 * time.Second
+	// tsigFudge is the time difference the TSIG of replies allows.
+	tsigFudge       = 300
+	shutdownTimeout = 10 * time.Second
+)
+
+// Repositories returns the record set repository of a STACKIT zone.
+type Repositories interface {
+	RRSetRepository(
+		ctx context.Context,
+		cfg *resolver.StackitDnsProviderConfig,
+		zone string,
+	) (repository.RRSetRepository, error)
+}
+
+// Server answers RFC 2136 DNS UPDATE messages signed with a configured TSIG
+// key by changing the TXT record sets of the zones of the key.
+type Server struct {
+	repositories Repositories
+	logger       *zap.Logger
+	config       Config
+	keys         map[string]*key
+	secrets      map[string]string
+	// zones are the zones of all keys, which the listener is authoritative
+	// for.
+	zones map[string]bool
+	// mu serializes updates, so prerequisites are not checked against record
+	// sets another update is about to change.
+	mu sync.Mutex
+}
+
+type key struct {
+	name      string
+	algorithm string
+	zones     []string
+	provider  resolver.StackitDnsProviderConfig
+}
+
+func NewServer(repositories Repositories, logger *zap.Logger, config Config) (*Server, error) {
+	if err := validateConfig(&config); err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		repositories: repositories,
+		logger:       logger,
+		config:       config,
+		keys:         map[string]*key{},
+		secrets:      map[string]string{},
+		zones:        map[string]bool{},
+	}
+
+	for i := range config.Keys {
+		if err := s.addKey(&config.Keys[i]); err != nil {
+			return nil, fmt.Errorf("keys[%d]: %w", i, err)
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Server) addKey(cfg *KeyConfig) error {
+	secret, err := keySecret(cfg)
+	if err != nil {
+		return err
+	}
+
+	algorithm, err := keyAlgorithm(cfg.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	zones, err := repository.AbsoluteZoneNames(cfg.Zones)
+	if err != nil {
+		return err
+	}
+
+	provider, err := s.provider(cfg.Provider)
+	if err != nil {
+		return err
+	}
+
+	name := keyName(cfg.Name)
+	s.keys[name] = &key{name: name, algorithm: algorithm, zones: zones, provider: provider}
+	s.secrets[name] = secret
+	for _, zone := range zones {
+		s.zones[zone] = true
+	}
+
+	return nil
+}
+
+// provider returns the solver config of provider, or of the server if nil,
+// loaded with the config provider of the webhook, so mistakes are found on
+// start and defaults apply.
+func (s *Server) provider(provider *resolver.StackitDnsProviderConfig) (resolver.StackitDnsProviderConfig, error) {
+	cfg := s.config.Provider
+	if provider != nil {
+		cfg = *provider
+	}
+	if cfg.AuthTokenSecretNamespace == "" {
+		cfg.AuthTokenSecretNamespace = s.config.Namespace
+	}
+
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return cfg, err
+	}
+
+	cfg, err = resolver.NewConfigProvider().LoadConfig(&extapi.JSON{Raw: raw})
+	if err != nil {
+		return cfg, fmt.Errorf("provider: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// NewDNSServer returns a server for network "udp" or "tcp" on the configured
+// address, which verifies TSIG signatures and passes updates to s.
+func (s *Server) NewDNSServer(network string) *dns.Server {
+	return &dns.Server{
+		Addr:          s.config.ListenAddress,
+		Net:           network,
+		Handler:       s,
+		TsigSecret:    s.secrets,
+		MsgAcceptFunc: acceptUpdate,
+	}
+}
+
+// Serve listens on the configured address over UDP and TCP until ctx is done.
+func (s *Server) Serve(ctx context.Context) error {
+	servers := []*dns.Server{s.NewDNSServer("udp"), s.NewDNSServer("tcp")}
+
+	errs := make(chan error, len(servers))
+	for _, server := range servers {
+		go func() {
+			errs <- server.ListenAndServe()
+		}()
+	}
+
+	s.logger.Info("Serving DNS UPDATE", zap.String("listenAddress", s.config.ListenAddress))
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		_ = server.ShutdownContext(shutdownCtx)
+	}
+
+	return err
+}
+
+// acceptUpdate accepts UPDATE requests with a single zone, rejecting every
+// other opcode as not implemented.
+func acceptUpdate(dh dns.Header) dns.MsgAcceptAction {
+	const qrBit = 1 << 15
+	if dh.Bits&qrBit != 0 {
+		return dns.MsgIgnore
+	}
+
+	if opcode := int(dh.Bits>>11) & 0xF; opcode != dns.OpcodeUpdate {
+		return dns.MsgRejectNotImplemented
+	}
+
+	if dh.Qdcount != 1 {
+		return dns.MsgReject
+	}
+
+	return dns.MsgAccept
+}
+
+// ServeDNS answers a DNS UPDATE message. Replies to signed requests carry a
+// TSIG, which is signed unless the signature of the request was rejected.
+func (s *Server) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	k, rcode, tsigError := s.authenticate(w, r)
+	if rcode == dns.RcodeSuccess {
+		rcode = s.update(k, r)
+	}
+
+	reply := new(dns.Msg)
+	reply.SetRcode(r, rcode)
+	if tsig := r.IsTsig(); tsig != nil {
+		reply.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsigFudge, time.Now().Unix())
+		reply.IsTsig().Error = tsigError
+	}
+
+	if err := w.WriteMsg(reply); err != nil {
+		s.logger.Error("Error writing DNS UPDATE reply", zap.Error(err))
+	}
+}
+
+func (s *Server) update(k *key, r *dns.Msg) int {
+	zone, rcode := s.zone(k, r.Question[0])
+	if rcode != dns.RcodeSuccess {
+		return rcode
+	}
+
+	u, rcode := parseUpdate(zone, r)
+	if rcode != dns.RcodeSuccess {
+		return rcode
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), updateTimeout)
+	defer cancel()
+
+	rrSetRepository, err := s.repositories.RRSetRepository(ctx, &k.provider, zone)
+	if err != nil {
+		s.logger.Error("Error getting RRSet repository", zap.Error(err), zap.String("zone", zone))
+		if errors.Is(err, repository.ErrZoneNotFound) {
+			return dns.RcodeNotAuth
+		}
+
+		return dns.RcodeServerFailure
+	}
+
+	rcode, err = u.apply(ctx, rrSetRepository)
+	s.logger.Info(
+		"Processed DNS UPDATE",
+		zap.String("key", k.name),
+		zap.String("zone", zone),
+		zap.Int("prerequisites", len(u.prerequisites)),
+		zap.Int("changes", len(u.changes)),
+		zap.String("rcode", dns.RcodeToString[rcode]),
+		zap.Error(err),
+	)
+
+	return rcode
+}
+
+// authenticate returns the key a request is signed with. Unsigned requests
+// are refused. Requests with an invalid signature are not authorized and get
+// the TSIG error of RFC 8945 section 5.2.
+func (s *Server) authenticate(w dns.ResponseWriter, r *dns.Msg) (*key, int, uint16) {
+	tsig := r.IsTsig()
+	if tsig == nil {
+		return nil, dns.RcodeRefused, dns.RcodeSuccess
+	}
+
+	if err := w.TsigStatus(); err != nil {
+		s.logger.Info("Rejecting DNS UPDATE with invalid TSIG", zap.Error(err), zap.String("key", tsig.Hdr.Name))
+
+		return nil, dns.RcodeNotAuth, tsigError(err)
+	}
+
+	k := s.keys[keyName(tsig.Hdr.Name)]
+	if k == nil || dns.CanonicalName(tsig.Algorithm) != k.algorithm {
+		s.logger.Info("Rejecting DNS UPDATE signed with an unknown key", zap.String("key", tsig.Hdr.Name))
+
+		return nil, dns.RcodeNotAuth, dns.RcodeBadKey
+	}
+
+	return k, dns.RcodeSuccess, dns.RcodeSuccess
+}
+
+func tsigError(err error) uint16 {
+	switch {
+	case errors.Is(err, dns.ErrSecret), errors.Is(err, dns.ErrKeyAlg):
+		return dns.RcodeBadKey
+	case errors.Is(err, dns.ErrTime):
+		return dns.RcodeBadTime
+	default:
+		return dns.RcodeBadSig
+	}
+}
+
+// zone returns the canonical name of the zone section of a request. Zones
+// of no key are not served, zones of other keys are refused.
+func (s *Server) zone(k *key, question dns.Question) (string, int) {
+	if question.Qtype != dns.TypeSOA || question.Qclass != dns.ClassINET {
+		return "", dns.RcodeFormatError
+	}
+
+	zones, err := repository.AbsoluteZoneNames([]string{question.Name})
+	if err != nil {
+		return "", dns.RcodeFormatError
+	}
+
+	switch zone := zones[0]; {
+	case !s.zones[zone]:
+		return "", dns.RcodeNotAuth
+	case !slices.Contains(k.zones, zone):
+		return "", dns.RcodeRefused
+	default:
+		return zone, dns.RcodeSuccess
+	}
+}
@@ -0,0 +1,231 @@
+package dnsupdate_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/dnsupdate"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/resolver"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	legoSecret  = "bGVnby1zZWNyZXQtZm9yLXRoZS10ZXN0cw=="
+	otherSecret = "b3RoZXItc2VjcmV0LWZvci10aGUtdGVzdHM="
+)
+
+// tsigKey signs requests of a test.
+type tsigKey struct {
+	name      string
+	algorithm string
+	secret    string
+}
+
+var (
+	legoKey  = tsigKey{"lego.", dns.HmacSHA256, legoSecret}
+	otherKey = tsigKey{"other.", dns.HmacSHA512, otherSecret}
+)
+
+// testServer listens for DNS UPDATE over UDP and TCP on the loopback address.
+type testServer struct {
+	repositories *memoryRepositories
+	addresses    map[string]string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	secretFile := filepath.Join(t.TempDir(), "other.key")
+	require.NoError(t, os.WriteFile(secretFile, []byte(otherSecret+"\n"), 0o600))
+
+	repositories := newMemoryRepositories("example.com", "other.org")
+	server, err := dnsupdate.NewServer(repositories, zap.NewNop(), dnsupdate.Config{
+		Namespace: "default",
+		Provider:  resolver.StackitDnsProviderConfig{ProjectId: "project"},
+		Keys: []dnsupdate.KeyConfig{
+			{Name: "lego", Secret: legoSecret, Zones: []string{"example.com"}},
+			{Name: "other.", Algorithm: "hmac-sha512", SecretFile: secretFile, Zones: []string{"other.org."}},
+		},
+	})
+	require.NoError(t, err)
+
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	udp, tcp := server.NewDNSServer("udp"), server.NewDNSServer("tcp")
+	udp.PacketConn, tcp.Listener = packetConn, listener
+	for _, dnsServer := range []*dns.Server{udp, tcp} {
+		started := make(chan struct{})
+		dnsServer.NotifyStartedFunc = func() { close(started) }
+		go func() { _ = dnsServer.ActivateAndServe() }()
+		<-started
+		t.Cleanup(func() { _ = dnsServer.Shutdown() })
+	}
+
+	return &testServer{
+		repositories: repositories,
+		addresses:    map[string]string{"udp": packetConn.LocalAddr().String(), "tcp": listener.Addr().String()},
+	}
+}
+
+// exchange sends m over network, signed with key unless its name is empty,
+// and returns the rcode of the reply.
+func (s *testServer) exchange(t *testing.T, network string, m *dns.Msg, key tsigKey) int {
+	t.Helper()
+
+	client := &dns.Client{Net: network, Timeout: 5 * time.Second}
+	if key.name != "" {
+		client.TsigSecret = map[string]string{key.name: key.secret}
+		m.SetTsig(key.name, key.algorithm, 300, time.Now().Unix())
+	}
+
+	reply, _, err := client.Exchange(m, s.addresses[network])
+	if reply == nil || reply.Rcode != dns.RcodeNotAuth {
+		require.NoError(t, err)
+	} else {
+		// Clients reject replies carrying a TSIG error.
+		require.ErrorIs(t, err, dns.ErrAuth)
+	}
+
+	return reply.Rcode
+}
+
+func newUpdate(zone string) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetUpdate(zone)
+
+	return m
+}
+
+func newRRs(t *testing.T, records ...string) []dns.RR {
+	t.Helper()
+
+	rrs := make([]dns.RR, len(records))
+	for i, record := range records {
+		rr, err := dns.NewRR(record)
+		require.NoError(t, err)
+		rrs[i] = rr
+	}
+
+	return rrs
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	name := "_acme-challenge.www.example.com."
+
+	m := newUpdate("example.com.")
+	m.Insert(newRRs(t, name+` 120 IN TXT "first"`))
+	require.Equal(t, dns.RcodeSuccess, server.exchange(t, "udp", m, legoKey))
+	require.Equal(t, []string{`"first"`}, server.repositories.contents("example.com", name))
+	rrSet := server.repositories.rrSet("example.com", name)
+	require.EqualValues(t, 120, rrSet.Ttl)
+	require.NotNil(t, rrSet.Comment)
+
+	// A value of several character strings and a duplicate of the first.
+	m = newUpdate("Example.COM.")
+	m.Insert(newRRs(t, "_ACME-challenge.www.example.com. 60 IN TXT \"part 1\" \"part 2\"",
+		name+` 60 IN TXT "first"`))
+	require.Equal(t, dns.RcodeSuccess, server.exchange(t, "tcp", m, legoKey))
+	require.Equal(t, []string{`"first"`, `"part 1" "part 2"`}, server.repositories.contents("example.com", name))
+	require.EqualValues(t, 60, server.repositories.rrSet("example.com", name).Ttl)
+
+	m = newUpdate("example.com.")
+	m.Remove(newRRs(t, name+` 0 IN TXT "first"`))
+	require.Equal(t, dns.RcodeSuccess, server.exchange(t, "udp", m, legoKey))
+	require.Equal(t, []string{`"part 1" "part 2"`}, server.repositories.contents("example.com", name))
+
+	// Unmet prerequisites leave the zone unchanged.
+	m = newUpdate("example.com.")
+	m.Used(newRRs(t, name+` 0 IN TXT "first"`))
+	m.RemoveRRset(newRRs(t, name+" 0 IN TXT"))
+	require.Equal(t, dns.RcodeNXRrset, server.exchange(t, "udp", m, legoKey))
+	require.Len(t, server.repositories.contents("example.com", name), 1)
+
+	m = newUpdate("example.com.")
+	m.Used(newRRs(t, name+` 0 IN TXT "part 1" "part 2"`))
+	m.RemoveRRset(newRRs(t, name+" 0 IN TXT"))
+	require.Equal(t, dns.RcodeSuccess, server.exchange(t, "udp", m, legoKey))
+	require.Nil(t, server.repositories.rrSet("example.com", name))
+
+	m = newUpdate("other.org.")
+	m.NameNotUsed(newRRs(t, "_acme-challenge.other.org. 0 IN TXT"))
+	m.Insert(newRRs(t, `_acme-challenge.other.org. 300 IN TXT "other"`))
+	require.Equal(t, dns.RcodeSuccess, server.exchange(t, "tcp", m, otherKey))
+	require.Equal(t, []string{`"other"`}, server.repositories.contents("other.org", "_acme-challenge.other.org."))
+}
+
+func TestUpdateRcodes(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	seed := "_acme-challenge.seed.example.com."
+	server.repositories.add("example.com", seed, "TXT", `"seed"`)
+	server.repositories.add("example.com", "www.example.com.", "A", "192.0.2.1")
+
+	insert := func(zone, record string) func(*dns.Msg) {
+		return func(m *dns.Msg) {
+			m.SetUpdate(zone)
+			m.Insert(newRRs(t, record))
+		}
+	}
+	for _, tt := range []struct {
+		name  string
+		key   tsigKey
+		msg   func(*dns.Msg)
+		rcode int
+	}{
+		{"unsigned", tsigKey{}, insert("example.com.", seed+` 60 IN TXT "new"`), dns.RcodeRefused},
+		{"wrong secret", tsigKey{"lego.", dns.HmacSHA256, otherSecret},
+			insert("example.com.", seed+` 60 IN TXT "new"`), dns.RcodeNotAuth},
+		{"unknown key", tsigKey{"unknown.", dns.HmacSHA256, legoSecret},
+			insert("example.com.", seed+` 60 IN TXT "new"`), dns.RcodeNotAuth},
+		{"wrong algorithm", tsigKey{"lego.", dns.HmacSHA512, legoSecret},
+			insert("example.com.", seed+` 60 IN TXT "new"`), dns.RcodeNotAuth},
+		{"query", legoKey, func(m *dns.Msg) { m.SetQuestion(seed, dns.TypeTXT) }, dns.RcodeNotImplemented},
+		{"unknown zone", legoKey, insert("example.net.", `x.example.net. 60 IN TXT "new"`), dns.RcodeNotAuth},
+		{"zone of another key", legoKey, insert("other.org.", `x.other.org. 60 IN TXT "new"`), dns.RcodeRefused},
+		{"outside of zone", legoKey, insert("example.com.", `x.other.org. 60 IN TXT "new"`), dns.RcodeNotZone},
+		{"not TXT", legoKey, insert("example.com.", "x.example.com. 60 IN A 192.0.2.2"), dns.RcodeRefused},
+		{"ttl 0", legoKey, insert("example.com.", seed+` 0 IN TXT "new"`), dns.RcodeRefused},
+		{"persistent authorization", legoKey,
+			insert("example.com.", `_validation-persist.example.com. 60 IN TXT "ca.test; accounturi=x"`),
+			dns.RcodeRefused},
+		{"delete name", legoKey, func(m *dns.Msg) {
+			m.SetUpdate("example.com.")
+			m.RemoveName(newRRs(t, seed+" 0 IN ANY"))
+		}, dns.RcodeRefused},
+		{"value prerequisite", legoKey, func(m *dns.Msg) {
+			insert("example.com.", seed+` 60 IN TXT "new"`)(m)
+			m.Used(newRRs(t, seed+` 0 IN TXT "other"`))
+		}, dns.RcodeNXRrset},
+		{"record set exists", legoKey, func(m *dns.Msg) {
+			insert("example.com.", seed+` 60 IN TXT "new"`)(m)
+			m.RRsetNotUsed(newRRs(t, seed+" 0 IN TXT"))
+		}, dns.RcodeYXRrset},
+		{"name not in use", legoKey, func(m *dns.Msg) {
+			insert("example.com.", seed+` 60 IN TXT "new"`)(m)
+			m.NameUsed(newRRs(t, "mail.example.com. 0 IN A"))
+		}, dns.RcodeNameError},
+		{"name in use", legoKey, func(m *dns.Msg) {
+			insert("example.com.", seed+` 60 IN TXT "new"`)(m)
+			m.NameNotUsed(newRRs(t, "www.example.com. 0 IN A"))
+		}, dns.RcodeYXDomain},
+	} {
+		m := new(dns.Msg)
+		tt.msg(m)
+		require.Equal(t, dns.RcodeToString[tt.rcode], dns.RcodeToString[server.exchange(t, "udp", m, tt.key)], tt.name)
+	}
+
+	require.Equal(t, []string{`"seed"`}, server.repositories.contents("example.com", seed))
+	require.Nil(t, server.repositories.rrSet("example.com", "_validation-persist.example.com."))
+}
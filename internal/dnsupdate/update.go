@@ -0,0 +1,318 @@
+package dnsupdate
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/miekg/dns"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/resolver"
+)
+
+const (
+	typeTxt = "TXT"
+	// rrSetComment marks record sets created through DNS UPDATE.
+	rrSetComment = "This record set is managed by stackit-cert-manager-webhook (DNS UPDATE)"
+)
+
+// metaTypes may not be added to a zone.
+var metaTypes = []uint16{dns.TypeANY, dns.TypeAXFR, dns.TypeIXFR, dns.TypeMAILA, dns.TypeMAILB, dns.TypeOPT}
+
+// update is a prescanned DNS UPDATE message, see RFC 2136 section 3.
+type update struct {
+	prerequisites []prerequisite
+	changes       []change
+}
+
+// prerequisite is an RR of the prerequisite section. Class ANY requires the
+// name or, unless rrType is ANY, the record set to exist, class NONE the
+// opposite. Class IN requires the TXT record set to hold exactly the values
+// of all such prerequisites of the name.
+type prerequisite struct {
+	name   string
+	class  uint16
+	rrType uint16
+	txt    []string
+}
+
+// change is an RR of the update section. Class IN adds the value, class ANY
+// deletes the record set and class NONE deletes the value.
+type change struct {
+	name  string
+	class uint16
+	ttl   uint32
+	txt   []string
+}
+
+// parseUpdate prescans the prerequisite and update sections of r, so a
+// message is rejected before any of it is applied.
+func parseUpdate(zone string, r *dns.Msg) (*update, int) {
+	u := &update{}
+
+	for _, rr := range r.Answer {
+		p, rcode := parsePrerequisite(zone, rr)
+		if rcode != dns.RcodeSuccess {
+			return nil, rcode
+		}
+		u.prerequisites = append(u.prerequisites, p)
+	}
+
+	for _, rr := range r.Ns {
+		c, rcode := parseChange(zone, rr)
+		if rcode != dns.RcodeSuccess {
+			return nil, rcode
+		}
+		u.changes = append(u.changes, c)
+	}
+
+	return u, dns.RcodeSuccess
+}
+
+func parsePrerequisite(zone string, rr dns.RR) (prerequisite, int) {
+	hdr := rr.Header()
+	p := prerequisite{class: hdr.Class, rrType: hdr.Rrtype}
+
+	name, rcode := ownerName(zone, hdr)
+	if rcode != dns.RcodeSuccess {
+		return p, rcode
+	}
+	p.name = name
+
+	if hdr.Ttl != 0 {
+		return p, dns.RcodeFormatError
+	}
+
+	switch hdr.Class {
+	case dns.ClassANY, dns.ClassNONE:
+		if hdr.Rdlength != 0 {
+			return p, dns.RcodeFormatError
+		}
+
+		return p, dns.RcodeSuccess
+	case dns.ClassINET:
+		// Only TXT values can be compared with record sets of the API.
+		txt, ok := rr.(*dns.TXT)
+		if !ok {
+			return p, dns.RcodeNotImplemented
+		}
+		p.txt = txt.Txt
+
+		return p, dns.RcodeSuccess
+	default:
+		return p, dns.RcodeFormatError
+	}
+}
+
+// parseChange accepts changes of TXT record sets only. Deleting every record
+// set of a name is refused, as it would touch other types, and so are adds
+// with a TTL outside of the range the API accepts.
+func parseChange(zone string, rr dns.RR) (change, int) {
+	hdr := rr.Header()
+	c := change{class: hdr.Class, ttl: hdr.Ttl}
+
+	name, rcode := ownerName(zone, hdr)
+	if rcode != dns.RcodeSuccess {
+		return c, rcode
+	}
+	c.name = name
+
+	if rcode := checkChangeHeader(hdr); rcode != dns.RcodeSuccess {
+		return c, rcode
+	}
+
+	if hdr.Rrtype != dns.TypeTXT || resolver.IsPersistentRRSet(name, nil) {
+		return c, dns.RcodeRefused
+	}
+
+	if hdr.Class == dns.ClassANY {
+		return c, dns.RcodeSuccess
+	}
+
+	txt, ok := rr.(*dns.TXT)
+	if !ok {
+		return c, dns.RcodeFormatError
+	}
+	c.txt = txt.Txt
+
+	// Adding a value sets the TTL of the record set, so one the API rejects,
+	// e.g. 0, is refused before anything is written.
+	if hdr.Class == dns.ClassINET && resolver.ValidateTTL(int32(hdr.Ttl)) != nil {
+		return c, dns.RcodeRefused
+	}
+
+	return c, dns.RcodeSuccess
+}
+
+// checkChangeHeader checks the class, TTL and type of an update RR as in RFC
+// 2136 section 3.4.1.3.
+func checkChangeHeader(hdr *dns.RR_Header) int {
+	switch hdr.Class {
+	case dns.ClassINET:
+		if slices.Contains(metaTypes, hdr.Rrtype) {
+			return dns.RcodeFormatError
+		}
+	case dns.ClassANY:
+		if hdr.Ttl != 0 || hdr.Rdlength != 0 {
+			return dns.RcodeFormatError
+		}
+	case dns.ClassNONE:
+		if hdr.Ttl != 0 || slices.Contains(metaTypes, hdr.Rrtype) {
+			return dns.RcodeFormatError
+		}
+	default:
+		return dns.RcodeFormatError
+	}
+
+	return dns.RcodeSuccess
+}
+
+// ownerName returns the canonical name of an RR, which must be in zone.
+func ownerName(zone string, hdr *dns.RR_Header) (string, int) {
+	name, err := repository.CanonicalRRSetName(dns.Fqdn(hdr.Name), "")
+	if err != nil {
+		return "", dns.RcodeFormatError
+	}
+
+	if !dns.IsSubDomain(zone, name) {
+		return "", dns.RcodeNotZone
+	}
+
+	return name, dns.RcodeSuccess
+}
+
+// apply checks the prerequisites and applies the changes with one API call
+// per changed record set. Every record set is read and checked before the
+// first write, but a failing write leaves the earlier ones in place.
+func (u *update) apply(ctx context.Context, rrSetRepository repository.RRSetRepository) (int, error) {
+	if rcode, err := u.checkPrerequisites(ctx, rrSetRepository); rcode != dns.RcodeSuccess {
+		return rcode, err
+	}
+
+	plans := map[string]*rrSetPlan{}
+	var names []string
+	for _, c := range u.changes {
+		plan := plans[c.name]
+		if plan == nil {
+			var err error
+			plan, err = fetchRRSetPlan(ctx, rrSetRepository, c.name)
+			if err != nil {
+				return dns.RcodeServerFailure, err
+			}
+			if resolver.IsPersistentRRSet(c.name, plan.existing) {
+				return dns.RcodeRefused, resolver.ErrPersistentRecord
+			}
+			plans[c.name] = plan
+			names = append(names, c.name)
+		}
+		plan.apply(c)
+	}
+
+	for _, name := range names {
+		if err := plans[name].write(ctx, rrSetRepository, name); err != nil {
+			return dns.RcodeServerFailure, err
+		}
+	}
+
+	return dns.RcodeSuccess, nil
+}
+
+// checkPrerequisites returns the rcode of the first unmet prerequisite, see
+// RFC 2136 section 3.2.5.
+func (u *update) checkPrerequisites(ctx context.Context, rrSetRepository repository.RRSetRepository) (int, error) {
+	values := map[string][][]string{}
+	for _, p := range u.prerequisites {
+		if p.class == dns.ClassINET {
+			values[p.name] = append(values[p.name], p.txt)
+
+			continue
+		}
+
+		exists, err := rrSetExists(ctx, rrSetRepository, p.name, p.rrType)
+		if err != nil {
+			return dns.RcodeServerFailure, err
+		}
+		if rcode := prerequisiteRcode(p, exists); rcode != dns.RcodeSuccess {
+			return rcode, nil
+		}
+	}
+
+	for name, want := range values {
+		plan, err := fetchRRSetPlan(ctx, rrSetRepository, name)
+		if err != nil {
+			return dns.RcodeServerFailure, err
+		}
+		if !sameValues(plan.values(), want) {
+			return dns.RcodeNXRrset, nil
+		}
+	}
+
+	return dns.RcodeSuccess, nil
+}
+
+func prerequisiteRcode(p prerequisite, exists bool) int {
+	switch {
+	case p.class == dns.ClassANY && !exists:
+		if p.rrType == dns.TypeANY {
+			return dns.RcodeNameError
+		}
+
+		return dns.RcodeNXRrset
+	case p.class == dns.ClassNONE && exists:
+		if p.rrType == dns.TypeANY {
+			return dns.RcodeYXDomain
+		}
+
+		return dns.RcodeYXRrset
+	default:
+		return dns.RcodeSuccess
+	}
+}
+
+// rrSetExists reports whether an active record set of the type, or of any
+// type for ANY, is named name.
+func rrSetExists(
+	ctx context.Context,
+	rrSetRepository repository.RRSetRepository,
+	name string,
+	rrType uint16,
+) (bool, error) {
+	if rrType == dns.TypeANY {
+		exists := false
+		for _, err := range rrSetRepository.ListRRSets(ctx, repository.RRSetListOptions{Name: name, ActiveOnly: true}) {
+			if err != nil {
+				return false, err
+			}
+			exists = true
+		}
+
+		return exists, nil
+	}
+
+	_, err := rrSetRepository.FetchRRSetForZone(ctx, name, dns.TypeToString[rrType])
+	if errors.Is(err, repository.ErrRRSetNotFound) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// sameValues reports whether a and b hold the same TXT values, in any order.
+func sameValues(a, b [][]string) bool {
+	contains := func(values [][]string, value []string) bool {
+		return slices.ContainsFunc(values, func(v []string) bool { return slices.Equal(v, value) })
+	}
+
+	for _, value := range a {
+		if !contains(b, value) {
+			return false
+		}
+	}
+	for _, value := range b {
+		if !contains(a, value) {
+			return false
+		}
+	}
+
+	return true
+}
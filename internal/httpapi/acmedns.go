@@ -0,0 +1,369 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const (
+	// acmeDNSKeptValues is the number of TXT values acme-dns keeps per
+	// subdomain, enough for a certificate for a domain and its wildcard.
+	acmeDNSKeptValues = 2
+	// acmeDNSValueLength is the length of a dns-01 key, which acme-dns
+	// requires of every value.
+	acmeDNSValueLength = 43
+)
+
+// Error codes of the acme-dns API.
+const (
+	acmeDNSErrorForbidden    = "forbidden"
+	acmeDNSErrorBadSubdomain = "bad_subdomain"
+	acmeDNSErrorBadTXT       = "bad_txt"
+	acmeDNSErrorBadAllowFrom = "invalid_allowfrom_cidr"
+	acmeDNSErrorBadRequest   = "malformed_json_payload"
+	acmeDNSErrorInternal     = "internal_error"
+)
+
+// acmeDNS serves the acme-dns API. Registrations are kept in memory and
+// written to the registrations file on every change.
+type acmeDNS struct {
+	config        *AcmeDNSConfig
+	zone          string
+	provider      *extapi.JSON
+	allowRegister []netip.Prefix
+
+	mu            sync.Mutex
+	registrations map[string]*registration
+}
+
+// registration is an account of the acme-dns API as stored in the
+// registrations file.
+type registration struct {
+	Username     string   `json:"username"`
+	PasswordHash string   `json:"passwordHash"`
+	Subdomain    string   `json:"subdomain"`
+	AllowFrom    []string `json:"allowFrom,omitempty"`
+	// TXT holds the latest values, oldest first.
+	TXT []string `json:"txt,omitempty"`
+
+	// update serializes updates of the account.
+	update    sync.Mutex
+	allowFrom []netip.Prefix
+}
+
+type registerRequest struct {
+	AllowFrom []string `json:"allowfrom"`
+}
+
+type registerResponse struct {
+	Username   string   `json:"username"`
+	Password   string   `json:"password"`
+	FullDomain string   `json:"fulldomain"`
+	Subdomain  string   `json:"subdomain"`
+	AllowFrom  []string `json:"allowfrom"`
+}
+
+type updateRequest struct {
+	Subdomain string `json:"subdomain"`
+	TXT       string `json:"txt"`
+}
+
+type updateResponse struct {
+	TXT string `json:"txt"`
+}
+
+func (s *Server) newAcmeDNS(cfg *AcmeDNSConfig) (*acmeDNS, error) {
+	zone, err := repository.CanonicalZoneName(cfg.Zone)
+	if err != nil {
+		return nil, err
+	}
+
+	allowRegister, err := parsePrefixes(cfg.AllowRegistrationFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := s.providerJSON(cfg.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	registrations, err := loadRegistrations(cfg.RegistrationsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &acmeDNS{
+		config:        cfg,
+		zone:          zone,
+		provider:      provider,
+		allowRegister: allowRegister,
+		registrations: registrations,
+	}, nil
+}
+
+func loadRegistrations(path string) (map[string]*registration, error) {
+	registrations := map[string]*registration{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return registrations, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading registrations: %w", err)
+	}
+
+	var stored []*registration
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("error decoding registrations: %w", err)
+	}
+
+	for _, r := range stored {
+		if r.allowFrom, err = parsePrefixes(r.AllowFrom); err != nil {
+			return nil, fmt.Errorf("registration %s: %w", r.Username, err)
+		}
+		registrations[r.Username] = r
+	}
+
+	return registrations, nil
+}
+
+// saveLocked writes all registrations to the registrations file, replacing it
+// atomically. The caller holds a.mu.
+func (a *acmeDNS) saveLocked() error {
+	stored := make([]*registration, 0, len(a.registrations))
+	for _, r := range a.registrations {
+		stored = append(stored, r)
+	}
+	slices.SortFunc(stored, func(x, y *registration) int {
+		return strings.Compare(x.Username, y.Username)
+	})
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := a.config.RegistrationsFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("error writing registrations: %w", err)
+	}
+
+	if err := os.Rename(tmp, a.config.RegistrationsFile); err != nil {
+		return fmt.Errorf("error writing registrations: %w", err)
+	}
+
+	return nil
+}
+
+func (a *acmeDNS) fullDomain(subdomain string) string {
+	return subdomain + "." + a.zone
+}
+
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	if s.acmeDNS.config.DisableRegistration || !s.allowed(r, s.acmeDNS.allowRegister) {
+		writeError(w, http.StatusUnauthorized, acmeDNSErrorForbidden)
+
+		return
+	}
+
+	var req registerRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, acmeDNSErrorBadRequest)
+
+		return
+	}
+
+	allowFrom, err := parsePrefixes(req.AllowFrom)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, acmeDNSErrorBadAllowFrom)
+
+		return
+	}
+
+	password := rand.Text()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		s.logger.Error("Error hashing acme-dns password", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, acmeDNSErrorInternal)
+
+		return
+	}
+
+	reg := &registration{
+		Username:     newUUID(),
+		PasswordHash: string(hash),
+		Subdomain:    newUUID(),
+		AllowFrom:    req.AllowFrom,
+		allowFrom:    allowFrom,
+	}
+	if err := s.acmeDNS.add(reg); err != nil {
+		s.logger.Error("Error storing acme-dns registration", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, acmeDNSErrorInternal)
+
+		return
+	}
+
+	s.logger.Info("Registered acme-dns account",
+		zap.String("username", reg.Username), zap.String("subdomain", reg.Subdomain))
+	writeJSON(w, http.StatusCreated, registerResponse{
+		Username:   reg.Username,
+		Password:   password,
+		FullDomain: s.acmeDNS.fullDomain(reg.Subdomain),
+		Subdomain:  reg.Subdomain,
+		AllowFrom:  append([]string{}, req.AllowFrom...),
+	})
+}
+
+func (a *acmeDNS) add(reg *registration) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.registrations[reg.Username] = reg
+	if err := a.saveLocked(); err != nil {
+		delete(a.registrations, reg.Username)
+
+		return err
+	}
+
+	return nil
+}
+
+// authenticateRegistration returns the registration of the X-Api-User and
+// X-Api-Key headers if the request may use it.
+func (s *Server) authenticateRegistration(r *http.Request) (*registration, bool) {
+	username := r.Header.Get("X-Api-User")
+
+	s.acmeDNS.mu.Lock()
+	reg, ok := s.acmeDNS.registrations[username]
+	s.acmeDNS.mu.Unlock()
+
+	if !ok || bcrypt.CompareHashAndPassword([]byte(reg.PasswordHash), []byte(r.Header.Get("X-Api-Key"))) != nil {
+		s.logger.Warn("Rejecting acme-dns update with invalid credentials",
+			zap.String("username", username), zap.String("remoteAddr", r.RemoteAddr))
+
+		return nil, false
+	}
+
+	if !s.allowed(r, reg.allowFrom) {
+		s.logger.Warn("Rejecting acme-dns update from a disallowed address",
+			zap.String("username", username), zap.String("remoteAddr", r.RemoteAddr))
+
+		return nil, false
+	}
+
+	return reg, true
+}
+
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	reg, ok := s.authenticateRegistration(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, acmeDNSErrorForbidden)
+
+		return
+	}
+
+	var req updateRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, acmeDNSErrorBadRequest)
+
+		return
+	}
+
+	switch {
+	case req.Subdomain != reg.Subdomain:
+		writeError(w, http.StatusUnauthorized, acmeDNSErrorBadSubdomain)
+
+		return
+	case len(req.TXT) != acmeDNSValueLength:
+		writeError(w, http.StatusBadRequest, acmeDNSErrorBadTXT)
+
+		return
+	}
+
+	if err := s.updateRegistration(reg, req.TXT); err != nil {
+		s.logger.Error("Error handling acme-dns update", zap.Error(err),
+			zap.String("username", reg.Username), zap.String("subdomain", reg.Subdomain))
+		writeError(w, http.StatusInternalServerError, acmeDNSErrorInternal)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updateResponse{TXT: req.TXT})
+}
+
+// updateRegistration presents value at the subdomain of reg and removes the
+// values beyond the latest acmeDNSKeptValues.
+func (s *Server) updateRegistration(reg *registration, value string) error {
+	reg.update.Lock()
+	defer reg.update.Unlock()
+
+	s.acmeDNS.mu.Lock()
+	values := slices.Clone(reg.TXT)
+	s.acmeDNS.mu.Unlock()
+
+	if slices.Contains(values, value) {
+		return nil
+	}
+
+	if err := s.solver.Present(s.acmeDNS.challenge(reg, value)); err != nil {
+		return err
+	}
+	values = append(values, value)
+
+	for len(values) > acmeDNSKeptValues {
+		if err := s.solver.CleanUp(s.acmeDNS.challenge(reg, values[0])); err != nil {
+			s.logger.Warn("Error removing an old acme-dns value", zap.Error(err), zap.String("subdomain", reg.Subdomain))
+		}
+		values = values[1:]
+	}
+
+	s.acmeDNS.mu.Lock()
+	defer s.acmeDNS.mu.Unlock()
+	reg.TXT = values
+
+	if err := s.acmeDNS.saveLocked(); err != nil {
+		return err
+	}
+
+	s.logger.Info("Updated acme-dns subdomain", zap.String("username", reg.Username),
+		zap.String("subdomain", reg.Subdomain))
+
+	return nil
+}
+
+func (a *acmeDNS) challenge(reg *registration, value string) *v1alpha1.ChallengeRequest {
+	fqdn := a.fullDomain(reg.Subdomain) + "."
+
+	return &v1alpha1.ChallengeRequest{
+		Type:         "dns-01",
+		Key:          value,
+		ResolvedFQDN: fqdn,
+		ResolvedZone: a.zone + ".",
+		Config:       a.provider,
+	}
+}
+
+// newUUID returns a random (version 4) UUID, the format acme-dns uses for
+// usernames and subdomains.
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
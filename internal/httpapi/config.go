@@ -0,0 +1,238 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/resolver"
+	"golang.org/x/crypto/bcrypt"
+	"sigs.k8s.io/yaml"
+)
+
+const defaultListenAddress = ":8080"
+
+// Config configures the HTTP server mode.
+type Config struct {
+	ListenAddress string `json:"listenAddress"`
+	// TLSCertFile and TLSKeyFile make the server use HTTPS.
+	TLSCertFile string `json:"tlsCertFile"`
+	TLSKeyFile  string `json:"tlsKeyFile"`
+	// ClientIPHeader names a header set by a reverse proxy, e.g.
+	// X-Forwarded-For, whose client address is used for allowFrom checks
+	// instead of the peer address. It is only read from peers in
+	// TrustedProxies.
+	ClientIPHeader string `json:"clientIPHeader"`
+	// TrustedProxies are the CIDRs of the reverse proxies setting
+	// ClientIPHeader.
+	TrustedProxies []string `json:"trustedProxies"`
+	// Namespace is used for credential Secrets of providers without
+	// authTokenSecretNamespace. Empty means the namespace of the pod.
+	Namespace string `json:"namespace"`
+	// Provider is the solver config of clients without a provider of their
+	// own.
+	Provider resolver.StackitDnsProviderConfig `json:"provider"`
+	// Clients may use the lego httpreq endpoints /present and /cleanup.
+	Clients []ClientConfig `json:"clients"`
+	// AcmeDNS enables the acme-dns endpoints /register and /update.
+	AcmeDNS *AcmeDNSConfig `json:"acmeDNS"`
+}
+
+// ClientConfig is a client of the httpreq endpoints, authenticated with
+// HTTP basic auth.
+type ClientConfig struct {
+	Username string `json:"username"`
+	// PasswordHash is the bcrypt hash of the password, e.g. from
+	// `htpasswd -nbB <username> <password>`.
+	PasswordHash string `json:"passwordHash"`
+	// Zones are the STACKIT zones the client may write to.
+	Zones []string `json:"zones"`
+	// Domains restricts the names the client may present challenges for.
+	// "*.example.com" allows every name below example.com. Empty allows
+	// every name in Zones.
+	Domains []string `json:"domains"`
+	// AllowFrom restricts the client to these CIDRs.
+	AllowFrom []string                           `json:"allowFrom"`
+	Provider  *resolver.StackitDnsProviderConfig `json:"provider"`
+}
+
+// AcmeDNSConfig configures the acme-dns endpoints. Every registration gets a
+// subdomain of Zone, which holds the two latest TXT values like acme-dns.
+type AcmeDNSConfig struct {
+	Zone string `json:"zone"`
+	// RegistrationsFile stores the registered accounts.
+	RegistrationsFile   string `json:"registrationsFile"`
+	DisableRegistration bool   `json:"disableRegistration"`
+	// AllowRegistrationFrom restricts /register to these CIDRs.
+	AllowRegistrationFrom []string                           `json:"allowRegistrationFrom"`
+	Provider              *resolver.StackitDnsProviderConfig `json:"provider"`
+}
+
+// LoadConfig reads a YAML or JSON server config file.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("error reading server config: %w", err)
+	}
+
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("error decoding server config: %w", err)
+	}
+
+	if err := validateConfig(&cfg); err != nil {
+		return cfg, err
+	}
+
+	if cfg.ListenAddress == "" {
+		cfg.ListenAddress = defaultListenAddress
+	}
+
+	return cfg, nil
+}
+
+func validateConfig(cfg *Config) error {
+	if len(cfg.Clients) == 0 && cfg.AcmeDNS == nil {
+		return fmt.Errorf("at least one of clients and acmeDNS must be specified")
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return fmt.Errorf("tlsCertFile and tlsKeyFile must be specified together")
+	}
+
+	if err := validateClientIPHeader(cfg); err != nil {
+		return err
+	}
+
+	if err := validateClients(cfg.Clients); err != nil {
+		return err
+	}
+
+	if cfg.AcmeDNS != nil {
+		if err := validateAcmeDNSConfig(cfg.AcmeDNS); err != nil {
+			return fmt.Errorf("acmeDNS: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func validateClientIPHeader(cfg *Config) error {
+	if cfg.ClientIPHeader != "" && len(cfg.TrustedProxies) == 0 {
+		return fmt.Errorf("trustedProxies must be specified with clientIPHeader")
+	}
+
+	if _, err := parsePrefixes(cfg.TrustedProxies); err != nil {
+		return fmt.Errorf("trustedProxies: %w", err)
+	}
+
+	return nil
+}
+
+func validateClients(clients []ClientConfig) error {
+	usernames := map[string]bool{}
+	for i := range clients {
+		client := &clients[i]
+		if usernames[client.Username] {
+			return fmt.Errorf("clients[%d]: duplicate username %q", i, client.Username)
+		}
+		usernames[client.Username] = true
+
+		if err := validateClientConfig(client); err != nil {
+			return fmt.Errorf("clients[%d]: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func validateClientConfig(client *ClientConfig) error {
+	if client.Username == "" {
+		return fmt.Errorf("username must be specified")
+	}
+
+	if _, err := bcrypt.Cost([]byte(client.PasswordHash)); err != nil {
+		return fmt.Errorf("passwordHash: %w", err)
+	}
+
+	if len(client.Zones) == 0 {
+		return fmt.Errorf("zones must not be empty")
+	}
+
+	zones, err := repository.AbsoluteZoneNames(client.Zones)
+	if err != nil {
+		return err
+	}
+
+	for _, domain := range client.Domains {
+		name, err := canonicalDomain(domain)
+		if err != nil {
+			return err
+		}
+		if _, ok := zoneOf(zones, strings.TrimPrefix(name, "*.")); !ok {
+			return fmt.Errorf("domain %s is outside of zones %s", domain, strings.Join(client.Zones, ", "))
+		}
+	}
+
+	if _, err := parsePrefixes(client.AllowFrom); err != nil {
+		return fmt.Errorf("allowFrom: %w", err)
+	}
+
+	return nil
+}
+
+func validateAcmeDNSConfig(acmeDNS *AcmeDNSConfig) error {
+	if acmeDNS.Zone == "" {
+		return fmt.Errorf("zone must be specified")
+	}
+
+	if _, err := repository.CanonicalZoneName(acmeDNS.Zone); err != nil {
+		return err
+	}
+
+	if acmeDNS.RegistrationsFile == "" {
+		return fmt.Errorf("registrationsFile must be specified")
+	}
+
+	if _, err := parsePrefixes(acmeDNS.AllowRegistrationFrom); err != nil {
+		return fmt.Errorf("allowRegistrationFrom: %w", err)
+	}
+
+	return nil
+}
+
+// canonicalDomain returns the canonical absolute name of a domain, keeping a
+// leading "*." of wildcards.
+func canonicalDomain(domain string) (string, error) {
+	name, wildcard := strings.CutPrefix(domain, "*.")
+
+	canonical, err := repository.CanonicalRRSetName(dns.Fqdn(name), "")
+	if err != nil {
+		return "", err
+	}
+	if canonical == "" {
+		return "", fmt.Errorf("%w: empty domain", repository.ErrInvalidName)
+	}
+	if wildcard {
+		canonical = "*." + canonical
+	}
+
+	return canonical, nil
+}
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, len(cidrs))
+	for i, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+		prefixes[i] = prefix.Masked()
+	}
+
+	return prefixes, nil
+}
@@ -0,0 +1,67 @@
+package httpapi_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/httpapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	hash := passwordHash(t)
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+provider:
+  projectId: project
+clients:
+  - username: lego
+    passwordHash: `+hash+`
+    zones: [example.com]
+    domains: ["*.Example.com"]
+    allowFrom: [10.0.0.0/8]
+acmeDNS:
+  zone: auth.example.com
+  registrationsFile: /var/lib/acme-dns/registrations.json
+`), 0o600))
+
+	cfg, err := httpapi.LoadConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, ":8080", cfg.ListenAddress)
+	require.Equal(t, "project", cfg.Provider.ProjectId)
+	require.Equal(t, []string{"*.Example.com"}, cfg.Clients[0].Domains)
+	require.Equal(t, "auth.example.com", cfg.AcmeDNS.Zone)
+
+	for _, tt := range []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{"nothing to serve", `provider: {projectId: project}`, "at least one of clients and acmeDNS"},
+		{"unknown field", `clients: [{username: lego, zone: example.com}]`, "error decoding server config"},
+		{"password hash", `clients: [{username: lego, passwordHash: secret, zones: [example.com]}]`,
+			"clients[0]: passwordHash"},
+		{"no zones", `clients: [{username: lego, passwordHash: "` + hash + `"}]`, "zones must not be empty"},
+		{"domain outside of zones",
+			`clients: [{username: lego, passwordHash: "` + hash + `", zones: [example.com], domains: [other.org]}]`,
+			"domain other.org is outside of zones example.com"},
+		{"allowFrom", `clients: [{username: lego, passwordHash: "` + hash + `", zones: [a.com], allowFrom: [a]}]`,
+			"allowFrom"},
+		{"clientIPHeader without trustedProxies", `{clientIPHeader: X-Forwarded-For, acmeDNS: {zone: a.com}}`,
+			"trustedProxies must be specified with clientIPHeader"},
+		{"trustedProxies", `{clientIPHeader: X-Forwarded-For, trustedProxies: [a], acmeDNS: {zone: a.com}}`,
+			"trustedProxies"},
+		{"duplicate username", `clients: [{username: a, passwordHash: "` + hash + `", zones: [a.com]}, {username: a}]`,
+			`duplicate username "a"`},
+		{"registrations file", `acmeDNS: {zone: auth.example.com}`, "acmeDNS: registrationsFile must be specified"},
+		{"tls", `{tlsCertFile: cert.pem, acmeDNS: {zone: a.com, registrationsFile: r.json}}`,
+			"tlsCertFile and tlsKeyFile"},
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(tt.config), 0o600))
+		_, err := httpapi.LoadConfig(path)
+		require.ErrorContains(t, err, tt.err, tt.name)
+	}
+}
@@ -0,0 +1,147 @@
+package httpapi
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/miekg/dns"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	errMissingChallenge = errors.New("fqdn and value, or domain and keyAuth must be specified")
+	errForbiddenName    = errors.New("name is not permitted for this client")
+)
+
+// httpreqRequest is the body lego's httpreq provider sends. In its default
+// mode it holds FQDN and Value, in RAW mode Domain, Token and KeyAuth.
+type httpreqRequest struct {
+	FQDN    string `json:"fqdn"`
+	Value   string `json:"value"`
+	Domain  string `json:"domain"`
+	Token   string `json:"token"`
+	KeyAuth string `json:"keyAuth"`
+}
+
+// challenge returns the canonical record name and TXT value of the request.
+// In RAW mode the value is derived from the key authorization as described in
+// RFC 8555, section 8.4.
+func (req httpreqRequest) challenge() (string, string, error) {
+	fqdn, value := req.FQDN, req.Value
+	if fqdn == "" && req.Domain != "" && req.KeyAuth != "" {
+		digest := sha256.Sum256([]byte(req.KeyAuth))
+		fqdn = acmeChallengeLabel + "." + req.Domain
+		value = base64.RawURLEncoding.EncodeToString(digest[:])
+	}
+	if fqdn == "" || value == "" {
+		return "", "", errMissingChallenge
+	}
+
+	canonical, err := repository.CanonicalRRSetName(dns.Fqdn(fqdn), "")
+
+	return canonical, value, err
+}
+
+func (s *Server) handlePresent(w http.ResponseWriter, r *http.Request) {
+	s.handleHTTPReq(w, r, "present", s.solver.Present)
+}
+
+func (s *Server) handleCleanUp(w http.ResponseWriter, r *http.Request) {
+	s.handleHTTPReq(w, r, "cleanup", s.solver.CleanUp)
+}
+
+func (s *Server) handleHTTPReq(
+	w http.ResponseWriter,
+	r *http.Request,
+	operation string,
+	apply func(ch *v1alpha1.ChallengeRequest) error,
+) {
+	c, ok := s.authenticateClient(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="stackit-cert-manager-webhook"`)
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+
+		return
+	}
+
+	var req httpreqRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+
+		return
+	}
+
+	fqdn, value, err := req.challenge()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	zone, ok := c.zoneFor(fqdn)
+	if !ok || !c.permits(fqdn) {
+		s.logger.Warn("Rejecting challenge outside of the client's domains",
+			zap.String("username", c.username), zap.String("fqdn", fqdn))
+		writeError(w, http.StatusForbidden, fmt.Sprintf("%s: %v", fqdn, errForbiddenName))
+
+		return
+	}
+
+	domain, ok := challengeDomain(fqdn)
+	if !ok {
+		domain = fqdn
+	}
+	ch := &v1alpha1.ChallengeRequest{
+		Type:         "dns-01",
+		DNSName:      domain,
+		Key:          value,
+		ResolvedFQDN: fqdn,
+		ResolvedZone: zone,
+		Config:       c.provider,
+	}
+	if err := apply(ch); err != nil {
+		s.logger.Error("Error handling httpreq request", zap.Error(err),
+			zap.String("operation", operation), zap.String("username", c.username), zap.String("fqdn", fqdn))
+		// Solver errors may name internal resources, e.g. Secrets or zone IDs,
+		// so they are only logged.
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("%s failed", operation))
+
+		return
+	}
+
+	s.logger.Info("Handled httpreq request",
+		zap.String("operation", operation), zap.String("username", c.username), zap.String("fqdn", fqdn))
+	w.WriteHeader(http.StatusOK)
+}
+
+// authenticateClient checks the basic auth credentials and address of the
+// request against the configured clients.
+func (s *Server) authenticateClient(r *http.Request) (*client, bool) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, false
+	}
+
+	c, ok := s.clients[username]
+	if !ok || bcrypt.CompareHashAndPassword(c.passwordHash, []byte(password)) != nil {
+		s.logger.Warn("Rejecting httpreq request with invalid credentials",
+			zap.String("username", username), zap.String("remoteAddr", r.RemoteAddr))
+
+		return nil, false
+	}
+
+	if !s.allowed(r, c.allowFrom) {
+		s.logger.Warn("Rejecting httpreq request from a disallowed address",
+			zap.String("username", username), zap.String("remoteAddr", r.RemoteAddr))
+
+		return nil, false
+	}
+
+	return c, true
+}
@@ -0,0 +1,345 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/resolver"
+	"go.uber.org/zap"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const (
+	acmeChallengeLabel = "_acme-challenge"
+	maxRequestBodySize = 64 << 10
+	shutdownTimeout    = 30 * time.Second
+)
+
+// Solver presents and cleans up challenge records, as the webhook solver
+// does for cert-manager.
+type Solver interface {
+	Present(ch *v1alpha1.ChallengeRequest) error
+	CleanUp(ch *v1alpha1.ChallengeRequest) error
+}
+
+// Server offers the solver to ACME clients outside Kubernetes through the
+// acme-dns API and the lego httpreq API.
+type Server struct {
+	solver         Solver
+	logger         *zap.Logger
+	config         Config
+	clients        map[string]*client
+	acmeDNS        *acmeDNS
+	clientIPHeader string
+	trustedProxies []netip.Prefix
+}
+
+// client is a ClientConfig with canonical names and parsed values.
+type client struct {
+	username     string
+	passwordHash []byte
+	// zones are canonical with a trailing dot, longest first.
+	zones     []string
+	domains   []string
+	allowFrom []netip.Prefix
+	provider  *extapi.JSON
+}
+
+func NewServer(solver Solver, logger *zap.Logger, config Config) (*Server, error) {
+	trustedProxies, err := parsePrefixes(config.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("trustedProxies: %w", err)
+	}
+
+	s := &Server{
+		solver:         solver,
+		logger:         logger,
+		config:         config,
+		clients:        make(map[string]*client, len(config.Clients)),
+		clientIPHeader: config.ClientIPHeader,
+		trustedProxies: trustedProxies,
+	}
+
+	for i := range config.Clients {
+		c, err := s.newClient(&config.Clients[i])
+		if err != nil {
+			return nil, fmt.Errorf("clients[%d]: %w", i, err)
+		}
+		s.clients[c.username] = c
+	}
+
+	if config.AcmeDNS != nil {
+		acmeDNS, err := s.newAcmeDNS(config.AcmeDNS)
+		if err != nil {
+			return nil, fmt.Errorf("acmeDNS: %w", err)
+		}
+		s.acmeDNS = acmeDNS
+	}
+
+	return s, nil
+}
+
+func (s *Server) newClient(cfg *ClientConfig) (*client, error) {
+	zones, err := repository.AbsoluteZoneNames(cfg.Zones)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(zones, func(a, b string) int { return len(b) - len(a) })
+
+	domains := make([]string, len(cfg.Domains))
+	for i, domain := range cfg.Domains {
+		if domains[i], err = canonicalDomain(domain); err != nil {
+			return nil, err
+		}
+	}
+
+	allowFrom, err := parsePrefixes(cfg.AllowFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := s.providerJSON(cfg.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return &client{
+		username:     cfg.Username,
+		passwordHash: []byte(cfg.PasswordHash),
+		zones:        zones,
+		domains:      domains,
+		allowFrom:    allowFrom,
+		provider:     provider,
+	}, nil
+}
+
+// providerJSON returns the solver config of provider, or of the server if nil,
+// as it is passed in challenge requests. It is checked with the config
+// provider of the webhook, so mistakes are found on start.
+func (s *Server) providerJSON(provider *resolver.StackitDnsProviderConfig) (*extapi.JSON, error) {
+	cfg := s.config.Provider
+	if provider != nil {
+		cfg = *provider
+	}
+	if cfg.AuthTokenSecretNamespace == "" {
+		cfg.AuthTokenSecretNamespace = s.config.Namespace
+	}
+
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	providerJSON := &extapi.JSON{Raw: raw}
+	if _, err := resolver.NewConfigProvider().LoadConfig(providerJSON); err != nil {
+		return nil, fmt.Errorf("provider: %w", err)
+	}
+
+	return providerJSON, nil
+}
+
+// Handler returns the routes of the server.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("POST /present", s.handlePresent)
+	mux.HandleFunc("POST /cleanup", s.handleCleanUp)
+
+	if s.acmeDNS != nil {
+		mux.HandleFunc("POST /register", s.handleRegister)
+		mux.HandleFunc("POST /update", s.handleUpdate)
+	}
+
+	return mux
+}
+
+// Serve listens on the configured address until ctx is done.
+func (s *Server) Serve(ctx context.Context) error {
+	server := &http.Server{
+		Addr:              s.config.ListenAddress,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	s.logger.Info("Serving ACME DNS APIs", zap.String("address", server.Addr))
+
+	var err error
+	if s.config.TLSCertFile != "" {
+		err = server.ListenAndServeTLS(s.config.TLSCertFile, s.config.TLSKeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// clientAddr returns the address of the client. Behind one of the
+// trustedProxies it is taken from clientIPHeader, any other peer could set the
+// header to whatever allowFrom permits.
+func (s *Server) clientAddr(r *http.Request) (netip.Addr, error) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	peer = peer.Unmap()
+
+	if s.clientIPHeader == "" || !containsAddr(s.trustedProxies, peer) {
+		return peer, nil
+	}
+	value := r.Header.Get(s.clientIPHeader)
+	if value == "" {
+		return peer, nil
+	}
+
+	return forwardedClientAddr(value, s.trustedProxies)
+}
+
+// forwardedClientAddr returns the client address of a header like
+// X-Forwarded-For, to which every proxy appends the address of its peer.
+// Addresses are read from the right and those of trusted proxies skipped;
+// everything left of the first other address may have been sent by the
+// client itself.
+func forwardedClientAddr(value string, trustedProxies []netip.Prefix) (netip.Addr, error) {
+	var addr netip.Addr
+	for _, field := range slices.Backward(strings.Split(value, ",")) {
+		parsed, err := netip.ParseAddr(strings.TrimSpace(field))
+		if err != nil {
+			return netip.Addr{}, err
+		}
+		addr = parsed.Unmap()
+		if !containsAddr(trustedProxies, addr) {
+			break
+		}
+	}
+
+	return addr, nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	return slices.ContainsFunc(prefixes, func(prefix netip.Prefix) bool {
+		return prefix.Contains(addr)
+	})
+}
+
+// allowed reports whether the client address is in one of prefixes. Empty
+// prefixes allow every address.
+func (s *Server) allowed(r *http.Request, prefixes []netip.Prefix) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+
+	addr, err := s.clientAddr(r)
+	if err != nil {
+		s.logger.Warn("Error determining client address", zap.Error(err), zap.String("remoteAddr", r.RemoteAddr))
+
+		return false
+	}
+
+	return containsAddr(prefixes, addr)
+}
+
+// zoneFor returns the longest of the client's zones that contains fqdn.
+func (c *client) zoneFor(fqdn string) (string, bool) {
+	return zoneOf(c.zones, fqdn)
+}
+
+// permits reports whether the client may present challenges at fqdn, a
+// canonical challenge record name.
+func (c *client) permits(fqdn string) bool {
+	if len(c.domains) == 0 {
+		return true
+	}
+
+	// Restricted clients may only write challenge records, never the
+	// domains themselves.
+	domain, ok := challengeDomain(fqdn)
+	if !ok {
+		return false
+	}
+
+	return slices.ContainsFunc(c.domains, func(pattern string) bool {
+		if parent, ok := strings.CutPrefix(pattern, "*."); ok {
+			return strings.HasSuffix(domain, "."+parent)
+		}
+
+		return domain == pattern
+	})
+}
+
+// zoneOf returns the first of zones that contains the canonical name fqdn.
+func zoneOf(zones []string, fqdn string) (string, bool) {
+	for _, zone := range zones {
+		if fqdn == zone || strings.HasSuffix(fqdn, "."+zone) {
+			return zone, true
+		}
+	}
+
+	return "", false
+}
+
+// challengeDomain returns the domain of a challenge record name, dropping the
+// _acme-challenge label and a dns-account-01 label in front of it. ok is false
+// for other names, e.g. CNAME targets.
+func challengeDomain(fqdn string) (string, bool) {
+	labels := strings.SplitN(fqdn, ".", 3)
+	switch {
+	case labels[0] == acmeChallengeLabel && len(labels) > 1:
+		return strings.TrimPrefix(fqdn, acmeChallengeLabel+"."), true
+	case len(labels) == 3 && labels[1] == acmeChallengeLabel && strings.HasPrefix(labels[0], "_"):
+		return labels[2], true
+	default:
+		return "", false
+	}
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+// decodeBody decodes the JSON request body into v. An empty body leaves v
+// unchanged.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err := decoder.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,330 @@
+package httpapi_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/httpapi"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/resolver"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "secret"
+
+func challengeKey(seed string) string {
+	digest := sha256.Sum256([]byte(seed))
+
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+func passwordHash(t *testing.T) string {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	return string(hash)
+}
+
+// tokenSecretFetcher serves the token of the fake STACKIT API for every
+// Secret.
+type tokenSecretFetcher struct{}
+
+func (tokenSecretFetcher) StringFromSecret(string, string, string) (string, error) {
+	return fakeAPIToken, nil
+}
+
+// testConfig returns a server config using api with a token Secret.
+func testConfig(t *testing.T, api *fakeStackitAPI) httpapi.Config {
+	t.Helper()
+
+	return httpapi.Config{
+		Namespace: "default",
+		Provider: resolver.StackitDnsProviderConfig{
+			ProjectId:          "project",
+			ApiBasePath:        api.URL,
+			AuthMethod:         resolver.AuthMethodAuthTokenSecret,
+			AuthTokenSecretRef: "stackit-token",
+		},
+	}
+}
+
+func newTestServer(t *testing.T, cfg httpapi.Config) *httptest.Server {
+	t.Helper()
+
+	solver := resolver.NewResolver(
+		&http.Client{},
+		zap.NewNop(),
+		repository.NewZoneRepositoryFactory(),
+		repository.NewRRSetRepositoryFactory(),
+		tokenSecretFetcher{},
+		resolver.NewConfigProvider(),
+	)
+
+	server, err := httpapi.NewServer(solver, zap.NewNop(), cfg)
+	require.NoError(t, err)
+
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+
+	return httpServer
+}
+
+// post sends body as JSON and decodes the response into response, if not nil.
+func post(t *testing.T, url string, body any, header http.Header, response any) int {
+	t.Helper()
+
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(data))
+	require.NoError(t, err)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	if response != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(response))
+	}
+
+	return resp.StatusCode
+}
+
+func basicAuth(username, password string) http.Header {
+	req := &http.Request{Header: http.Header{}}
+	req.SetBasicAuth(username, password)
+
+	return req.Header
+}
+
+func newHTTPReqServer(t *testing.T) (*fakeStackitAPI, *httptest.Server) {
+	t.Helper()
+
+	api := newFakeStackitAPI(t, "project", "example.com", "other.org")
+	cfg := testConfig(t, api)
+	cfg.Clients = []httpapi.ClientConfig{
+		{Username: "lego", PasswordHash: passwordHash(t), Zones: []string{"example.com"}},
+		{
+			Username:     "www",
+			PasswordHash: passwordHash(t),
+			Zones:        []string{"example.com"},
+			Domains:      []string{"www.example.com", "*.apps.example.com"},
+		},
+		{
+			Username:     "remote",
+			PasswordHash: passwordHash(t),
+			Zones:        []string{"example.com"},
+			AllowFrom:    []string{"192.0.2.0/24"},
+		},
+	}
+
+	return api, newTestServer(t, cfg)
+}
+
+func TestHTTPReq(t *testing.T) {
+	t.Parallel()
+
+	api, server := newHTTPReqServer(t)
+	lego, www := basicAuth("lego", testPassword), basicAuth("www", testPassword)
+	name := "_acme-challenge.www.example.com."
+	first, second := challengeKey("first"), challengeKey("second")
+
+	status := post(t, server.URL+"/present", map[string]string{"fqdn": name, "value": first}, lego, nil)
+	require.Equal(t, http.StatusOK, status)
+	status = post(t, server.URL+"/present", map[string]string{"fqdn": name, "value": second}, www, nil)
+	require.Equal(t, http.StatusOK, status)
+	require.ElementsMatch(t, []string{first, second}, api.txtValues("example.com", name))
+
+	status = post(t, server.URL+"/cleanup", map[string]string{"fqdn": name, "value": first}, lego, nil)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []string{second}, api.txtValues("example.com", name))
+
+	// RAW mode sends the key authorization instead of the record value.
+	status = post(t, server.URL+"/present", map[string]string{
+		"domain":  "Example.COM",
+		"token":   "token",
+		"keyAuth": "token.thumbprint",
+	}, lego, nil)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []string{challengeKey("token.thumbprint")},
+		api.txtValues("example.com", "_acme-challenge.example.com."))
+
+	status = post(t, server.URL+"/present", map[string]string{"fqdn": name}, lego, nil)
+	require.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestHTTPReqRestrictions(t *testing.T) {
+	t.Parallel()
+
+	api, server := newHTTPReqServer(t)
+	lego, www := basicAuth("lego", testPassword), basicAuth("www", testPassword)
+	name := "_acme-challenge.www.example.com."
+
+	for _, tt := range []struct {
+		name   string
+		header http.Header
+		fqdn   string
+		status int
+	}{
+		{"wrong password", basicAuth("lego", "wrong"), name, http.StatusUnauthorized},
+		{"unknown client", basicAuth("unknown", testPassword), name, http.StatusUnauthorized},
+		{"no credentials", nil, name, http.StatusUnauthorized},
+		{"address not allowed", basicAuth("remote", testPassword), name, http.StatusUnauthorized},
+		{"zone of another client", lego, "_acme-challenge.other.org.", http.StatusForbidden},
+		{"domain not allowed", www, "_acme-challenge.mail.example.com.", http.StatusForbidden},
+		{"domain itself", www, "www.example.com.", http.StatusForbidden},
+		{"wildcard domain", www, "_acme-challenge.a.apps.example.com.", http.StatusOK},
+		{"account scoped name", www, "_ujmmovf2vn55tgye._acme-challenge.www.example.com.", http.StatusOK},
+	} {
+		body := map[string]string{"fqdn": tt.fqdn, "value": challengeKey(tt.name)}
+		status := post(t, server.URL+"/present", body, tt.header, nil)
+		require.Equal(t, tt.status, status, tt.name)
+	}
+
+	require.Empty(t, api.txtValues("example.com", name))
+	require.Empty(t, api.txtValues("other.org", "_acme-challenge.other.org."))
+	require.Empty(t, api.txtValues("example.com", "_acme-challenge.mail.example.com."))
+	require.Empty(t, api.txtValues("example.com", "www.example.com."))
+}
+
+func TestHTTPReqClientIPHeader(t *testing.T) {
+	t.Parallel()
+
+	name := "_acme-challenge.www.example.com."
+	for _, tt := range []struct {
+		name           string
+		trustedProxies []string
+		forwardedFor   string
+		status         int
+	}{
+		{"untrusted peer", []string{"198.51.100.0/24"}, "192.0.2.10", http.StatusUnauthorized},
+		{"trusted peer", []string{"127.0.0.0/8"}, "192.0.2.10", http.StatusOK},
+		{"behind trusted proxies", []string{"127.0.0.0/8", "198.51.100.0/24"}, "192.0.2.10, 198.51.100.1",
+			http.StatusOK},
+		{"spoofed before untrusted proxy", []string{"127.0.0.0/8"}, "192.0.2.10, 203.0.113.1",
+			http.StatusUnauthorized},
+	} {
+		api := newFakeStackitAPI(t, "project", "example.com")
+		cfg := testConfig(t, api)
+		cfg.ClientIPHeader = "X-Forwarded-For"
+		cfg.TrustedProxies = tt.trustedProxies
+		cfg.Clients = []httpapi.ClientConfig{{
+			Username:     "remote",
+			PasswordHash: passwordHash(t),
+			Zones:        []string{"example.com"},
+			AllowFrom:    []string{"192.0.2.0/24"},
+		}}
+		server := newTestServer(t, cfg)
+
+		header := basicAuth("remote", testPassword)
+		header.Set("X-Forwarded-For", tt.forwardedFor)
+		status := post(t, server.URL+"/present", map[string]string{"fqdn": name, "value": challengeKey(tt.name)},
+			header, nil)
+		require.Equal(t, tt.status, status, tt.name)
+	}
+}
+
+func TestHTTPReqHidesSolverErrors(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeStackitAPI(t, "project", "example.com")
+	cfg := testConfig(t, api)
+	cfg.Clients = []httpapi.ClientConfig{
+		{Username: "lego", PasswordHash: passwordHash(t), Zones: []string{"missing.org"}},
+	}
+	server := newTestServer(t, cfg)
+
+	// The zone does not exist, so Present fails in the solver.
+	var response struct {
+		Error string `json:"error"`
+	}
+	body := map[string]string{"fqdn": "_acme-challenge.missing.org.", "value": challengeKey("missing")}
+	status := post(t, server.URL+"/present", body, basicAuth("lego", testPassword), &response)
+	require.Equal(t, http.StatusInternalServerError, status)
+	require.Equal(t, "present failed", response.Error)
+}
+
+func acmeDNSHeader(username, password string) http.Header {
+	return http.Header{"X-Api-User": {username}, "X-Api-Key": {password}}
+}
+
+func TestAcmeDNS(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeStackitAPI(t, "project", "auth.example.com")
+	cfg := testConfig(t, api)
+	cfg.AcmeDNS = &httpapi.AcmeDNSConfig{
+		Zone:              "auth.example.com",
+		RegistrationsFile: filepath.Join(t.TempDir(), "registrations.json"),
+	}
+	server := newTestServer(t, cfg)
+
+	var account struct {
+		Username   string `json:"username"`
+		Password   string `json:"password"`
+		Subdomain  string `json:"subdomain"`
+		FullDomain string `json:"fulldomain"`
+	}
+	status := post(t, server.URL+"/register", map[string]any{}, nil, &account)
+	require.Equal(t, http.StatusCreated, status)
+	subdomain, fullDomain := account.Subdomain, account.FullDomain
+	require.Equal(t, subdomain+".auth.example.com", fullDomain)
+
+	header := acmeDNSHeader(account.Username, account.Password)
+	keys := []string{challengeKey("1"), challengeKey("2"), challengeKey("3"), challengeKey("4")}
+	for _, key := range keys[:3] {
+		var response map[string]string
+		body := map[string]string{"subdomain": subdomain, "txt": key}
+		status := post(t, server.URL+"/update", body, header, &response)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, key, response["txt"])
+	}
+	require.ElementsMatch(t, keys[1:3], api.txtValues("auth.example.com", fullDomain+"."))
+
+	for _, tt := range []struct {
+		name   string
+		header http.Header
+		body   map[string]string
+		status int
+		error  string
+	}{
+		{"wrong password", acmeDNSHeader(account.Username, "wrong"),
+			map[string]string{"subdomain": subdomain, "txt": keys[3]}, http.StatusUnauthorized, "forbidden"},
+		{"other subdomain", header, map[string]string{"subdomain": "other", "txt": keys[3]},
+			http.StatusUnauthorized, "bad_subdomain"},
+		{"bad txt", header, map[string]string{"subdomain": subdomain, "txt": "short"},
+			http.StatusBadRequest, "bad_txt"},
+	} {
+		var response map[string]string
+		status := post(t, server.URL+"/update", tt.body, tt.header, &response)
+		require.Equal(t, tt.status, status, tt.name)
+		require.Equal(t, tt.error, response["error"], tt.name)
+	}
+
+	// Accounts and their values survive a restart.
+	restarted := newTestServer(t, cfg)
+	status = post(t, restarted.URL+"/update", map[string]string{"subdomain": subdomain, "txt": keys[3]}, header, nil)
+	require.Equal(t, http.StatusOK, status)
+	require.ElementsMatch(t, keys[2:], api.txtValues("auth.example.com", fullDomain+"."))
+
+	status = post(t, restarted.URL+"/register", map[string]any{"allowfrom": []string{"not a cidr"}}, nil, nil)
+	require.Equal(t, http.StatusBadRequest, status)
+
+	cfg.AcmeDNS.DisableRegistration = true
+	status = post(t, newTestServer(t, cfg).URL+"/register", map[string]any{}, nil, nil)
+	require.Equal(t, http.StatusUnauthorized, status)
+}
@@ -0,0 +1,226 @@
+package httpapi_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+)
+
+const fakeAPIToken = "fake-token"
+
+// fakeStackitAPI is an in-memory STACKIT DNS API serving the endpoints the
+// repositories use for one project.
+type fakeStackitAPI struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	zones  []stackitdnsclient.Zone
+	rrSets map[string][]stackitdnsclient.RecordSet
+	nextId int
+}
+
+func newFakeStackitAPI(t *testing.T, project string, zones ...string) *fakeStackitAPI {
+	t.Helper()
+
+	api := &fakeStackitAPI{rrSets: map[string][]stackitdnsclient.RecordSet{}}
+	for i, zone := range zones {
+		api.zones = append(api.zones, stackitdnsclient.Zone{
+			Id:            fmt.Sprintf("zone-%d", i),
+			DnsName:       zone,
+			Active:        new(true),
+			DefaultTTL:    3600,
+			NegativeCache: 60,
+			State:         stackitdnsclient.ZONESTATE_CREATE_SUCCEEDED,
+			Type:          stackitdnsclient.ZONETYPE_PRIMARY,
+			Visibility:    stackitdnsclient.ZONEVISIBILITY_PUBLIC,
+		})
+	}
+
+	prefix := "/v1/projects/" + project + "/zones"
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+prefix, api.listZones)
+	mux.HandleFunc("GET "+prefix+"/{zone}/rrsets", api.listRRSets)
+	mux.HandleFunc("POST "+prefix+"/{zone}/rrsets", api.createRRSet)
+	mux.HandleFunc("PATCH "+prefix+"/{zone}/rrsets/{id}", api.updateRRSet)
+	mux.HandleFunc("DELETE "+prefix+"/{zone}/rrsets/{id}", api.deleteRRSet)
+
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+fakeAPIToken {
+			http.Error(w, `{"message":"unauthorized"}`, http.StatusUnauthorized)
+
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(api.Close)
+
+	return api
+}
+
+// txtValues returns the unquoted values of the TXT record set name in zone.
+func (api *fakeStackitAPI) txtValues(zone, name string) []string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	var values []string
+	for _, rrSet := range api.rrSets[api.zoneId(zone)] {
+		if rrSet.Name != name {
+			continue
+		}
+		for _, record := range rrSet.Records {
+			values = append(values, strings.Trim(record.Content, `"`))
+		}
+	}
+	slices.Sort(values)
+
+	return values
+}
+
+func (api *fakeStackitAPI) zoneId(dnsName string) string {
+	for _, zone := range api.zones {
+		if zone.DnsName == dnsName {
+			return zone.Id
+		}
+	}
+
+	return ""
+}
+
+func (api *fakeStackitAPI) listZones(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	dnsName := r.URL.Query().Get("dnsName[eq]")
+	zones := []stackitdnsclient.Zone{}
+	for _, zone := range api.zones {
+		if dnsName == "" || zone.DnsName == dnsName {
+			zones = append(zones, zone)
+		}
+	}
+
+	writeAPIResponse(w, http.StatusOK, stackitdnsclient.ListZonesResponse{
+		ItemsPerPage: 100,
+		TotalItems:   int32(len(zones)),
+		TotalPages:   1,
+		Zones:        zones,
+	})
+}
+
+func (api *fakeStackitAPI) listRRSets(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	query := r.URL.Query()
+	rrSets := []stackitdnsclient.RecordSet{}
+	for _, rrSet := range api.rrSets[r.PathValue("zone")] {
+		if name := query.Get("name[eq]"); name != "" && rrSet.Name != name {
+			continue
+		}
+		if rrSetType := query.Get("type[eq]"); rrSetType != "" && string(rrSet.Type) != rrSetType {
+			continue
+		}
+		rrSets = append(rrSets, rrSet)
+	}
+
+	writeAPIResponse(w, http.StatusOK, stackitdnsclient.ListRecordSetsResponse{
+		ItemsPerPage: 100,
+		RrSets:       rrSets,
+		TotalItems:   int32(len(rrSets)),
+		TotalPages:   1,
+	})
+}
+
+func (api *fakeStackitAPI) createRRSet(w http.ResponseWriter, r *http.Request) {
+	var payload stackitdnsclient.CreateRecordSetPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, `{"message":"bad request"}`, http.StatusBadRequest)
+
+		return
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	api.nextId++
+	rrSet := stackitdnsclient.RecordSet{
+		Id:      fmt.Sprintf("rrset-%d", api.nextId),
+		Active:  new(true),
+		Comment: payload.Comment,
+		Name:    payload.Name,
+		Records: records(payload.Records),
+		State:   stackitdnsclient.RECORDSETSTATE_CREATE_SUCCEEDED,
+		Ttl:     *payload.Ttl,
+		Type:    stackitdnsclient.RecordSetType(payload.Type),
+	}
+	zone := r.PathValue("zone")
+	api.rrSets[zone] = append(api.rrSets[zone], rrSet)
+
+	writeAPIResponse(w, http.StatusAccepted, stackitdnsclient.RecordSetResponse{Rrset: rrSet})
+}
+
+func (api *fakeStackitAPI) updateRRSet(w http.ResponseWriter, r *http.Request) {
+	var payload stackitdnsclient.PartialUpdateRecordSetPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, `{"message":"bad request"}`, http.StatusBadRequest)
+
+		return
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	rrSets := api.rrSets[r.PathValue("zone")]
+	i := slices.IndexFunc(rrSets, func(rrSet stackitdnsclient.RecordSet) bool { return rrSet.Id == r.PathValue("id") })
+	if i < 0 {
+		http.Error(w, `{"message":"record set not found"}`, http.StatusNotFound)
+
+		return
+	}
+
+	rrSets[i].Records = records(payload.Records)
+	if payload.Ttl != nil {
+		rrSets[i].Ttl = *payload.Ttl
+	}
+
+	writeAPIResponse(w, http.StatusAccepted, stackitdnsclient.RecordSetResponse{Rrset: rrSets[i]})
+}
+
+func (api *fakeStackitAPI) deleteRRSet(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	zone := r.PathValue("zone")
+	before := len(api.rrSets[zone])
+	api.rrSets[zone] = slices.DeleteFunc(api.rrSets[zone], func(rrSet stackitdnsclient.RecordSet) bool {
+		return rrSet.Id == r.PathValue("id")
+	})
+	if len(api.rrSets[zone]) == before {
+		http.Error(w, `{"message":"record set not found"}`, http.StatusNotFound)
+
+		return
+	}
+
+	writeAPIResponse(w, http.StatusAccepted, stackitdnsclient.Message{})
+}
+
+func records(payload []stackitdnsclient.RecordPayload) []stackitdnsclient.Record {
+	records := make([]stackitdnsclient.Record, len(payload))
+	for i, record := range payload {
+		records[i] = stackitdnsclient.Record{Content: record.Content}
+	}
+
+	return records
+}
+
+func writeAPIResponse(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+var (
+	diagnosticsMu      sync.RWMutex
+	diagnosticsReports = map[string]func() any{}
+)
+
+// RegisterDiagnostic adds a named report to the /diagnostics endpoint. A
+// report registered under an existing name replaces it.
+func RegisterDiagnostic(name string, report func() any) {
+	diagnosticsMu.Lock()
+	defer diagnosticsMu.Unlock()
+
+	diagnosticsReports[name] = report
+}
+
+// Diagnostics collects all registered reports.
+func Diagnostics() map[string]any {
+	diagnosticsMu.RLock()
+	defer diagnosticsMu.RUnlock()
+
+	reports := make(map[string]any, len(diagnosticsReports))
+	for name, report := range diagnosticsReports {
+		reports[name] = report()
+	}
+
+	return reports
+}
+
+func diagnosticsHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(Diagnostics()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnosticsHandler(t *testing.T) {
+	RegisterDiagnostic("test", func() any { return map[string]string{"status": "ok"} })
+
+	rec := httptest.NewRecorder()
+	diagnosticsHandler(rec, httptest.NewRequest(http.MethodGet, "/diagnostics", http.NoBody))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var body map[string]map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, "ok", body["test"]["status"])
+}
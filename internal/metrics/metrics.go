@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const namespace = "stackit_cert_manager_webhook"
+
+// Registry holds every collector exported by the webhook. A dedicated registry
+// is used so the metrics endpoint is independent of the apiserver library.
+var Registry = prometheus.NewRegistry()
+
+// CredentialRotations counts credential changes picked up at runtime.
+var CredentialRotations = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "credential_rotations_total",
+		Help:      "Number of credential rotations detected, by credential source.",
+	},
+	[]string{"source"},
+)
+
+// SecretCacheRequests counts Secret reads by cache result (hit or miss).
+var SecretCacheRequests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "secret_cache_requests_total",
+		Help:      "Number of Secret reads, by cache result.",
+	},
+	[]string{"result"},
+)
+
+// SecretCacheLastUpdate records when the Secret cache of a namespace last
+// received data from the API server.
+var SecretCacheLastUpdate = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "secret_cache_last_update_timestamp_seconds",
+		Help:      "Unix time the Secret cache of a namespace last received an update.",
+	},
+	[]string{"namespace"},
+)
+
+// CredentialProviderSelections counts which credential provider was used.
+var CredentialProviderSelections = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "credential_provider_selections_total",
+		Help:      "Number of times a credential provider supplied the credential, by provider.",
+	},
+	[]string{"provider"},
+)
+
+// CredentialRefreshes counts credential refreshes triggered by a 401 from the
+// DNS API, by result.
+var CredentialRefreshes = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "credential_refreshes_total",
+		Help:      "Number of credential refreshes after a 401 from the DNS API, by result.",
+	},
+	[]string{"result"},
+)
+
+// CredentialExpiry exports when a loaded credential expires.
+var CredentialExpiry = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "credential_expiry_timestamp_seconds",
+		Help:      "Unix time a loaded credential expires, by credential source and origin.",
+	},
+	[]string{"source", "origin"},
+)
+
+// ChallengeVerifications counts read-after-write checks of challenge records,
+// by operation (present or cleanup) and result.
+var ChallengeVerifications = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "challenge_verifications_total",
+		Help:      "Number of read-after-write checks of challenge records, by operation and result.",
+	},
+	[]string{"operation", "result"},
+)
+
+func init() {
+	Registry.MustRegister(
+		ChallengeVerifications,
+		CredentialRotations,
+		CredentialExpiry,
+		CredentialRefreshes,
+		CredentialProviderSelections,
+		SecretCacheRequests,
+		SecretCacheLastUpdate,
+	)
+}
+
+// Serve exposes the registry on addr under /metrics and the registered
+// diagnostics under /diagnostics until stopCh is closed.
+func Serve(addr string, logger *zap.Logger, stopCh <-chan struct{}) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/diagnostics", diagnosticsHandler)
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-stopCh
+		_ = server.Close()
+	}()
+
+	logger.Info("Serving metrics", zap.String("address", addr))
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("Error serving metrics", zap.Error(err))
+	}
+}
@@ -0,0 +1,143 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+)
+
+// duplicatesTestServer serves duplicate zones and record sets and records
+// the updates and deletions it receives.
+type duplicatesTestServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	patched  map[string][]string
+	deleted  []string
+	rrSetsOf map[string][]stackitdnsclient.RecordSet
+}
+
+func newDuplicatesTestServer(t *testing.T) *duplicatesTestServer {
+	t.Helper()
+
+	s := &duplicatesTestServer{
+		patched: map[string][]string{},
+		rrSetsOf: map[string][]stackitdnsclient.RecordSet{
+			"txt": {
+				{Id: "b", Name: "_acme-challenge.test.com.", Type: "TXT", Records: []stackitdnsclient.Record{
+					{Content: "key-2"}, {Content: "key-3"},
+				}},
+				{Id: "a", Name: "_acme-challenge.test.com.", Type: "TXT", Records: []stackitdnsclient.Record{
+					{Content: "key-1"}, {Content: "key-2"},
+				}},
+			},
+			"cname": {
+				{Id: "c1", Name: "www.test.com.", Type: "CNAME"},
+				{Id: "c2", Name: "www.test.com.", Type: "CNAME"},
+			},
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/projects/1234/zones", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(t, w, http.StatusOK, stackitdnsclient.ListZonesResponse{TotalPages: 1, Zones: []stackitdnsclient.Zone{
+			{Id: "zone-1", DnsName: "test.com"},
+			{Id: "zone-2", DnsName: "Test.com"},
+		}})
+	})
+	mux.HandleFunc("GET /v1/projects/1234/zones/{zone}/rrsets", func(w http.ResponseWriter, r *http.Request) {
+		rrSets := s.rrSetsOf[r.PathValue("zone")]
+		writeJSON(t, w, http.StatusOK, stackitdnsclient.ListRecordSetsResponse{TotalPages: 1, RrSets: rrSets})
+	})
+	mux.HandleFunc("PATCH /v1/projects/1234/zones/{zone}/rrsets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var payload stackitdnsclient.PartialUpdateRecordSetPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+
+		s.mu.Lock()
+		for _, record := range payload.Records {
+			s.patched[r.PathValue("id")] = append(s.patched[r.PathValue("id")], record.Content)
+		}
+		s.mu.Unlock()
+
+		writeJSON(t, w, http.StatusAccepted, stackitdnsclient.Message{})
+	})
+	mux.HandleFunc("DELETE /v1/projects/1234/zones/{zone}/rrsets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.deleted = append(s.deleted, r.PathValue("id"))
+		s.mu.Unlock()
+
+		writeJSON(t, w, http.StatusAccepted, stackitdnsclient.Message{})
+	})
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, statusCode int, response any) {
+	t.Helper()
+
+	body, err := json.Marshal(response)
+	require.NoError(t, err)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(body)
+}
+
+func (s *duplicatesTestServer) config() repository.Config {
+	return repository.Config{
+		ApiBasePath: s.URL,
+		AuthToken:   "token",
+		ProjectId:   "1234",
+		HttpClient:  s.Client(),
+	}
+}
+
+func TestZoneRepository_FetchZoneAmbiguous(t *testing.T) {
+	t.Parallel()
+
+	server := newDuplicatesTestServer(t)
+
+	zoneRepository, err := repository.NewZoneRepositoryFactory().NewZoneRepository(server.config())
+	require.NoError(t, err)
+
+	_, err = zoneRepository.FetchZone(context.TODO(), "test.com")
+	require.ErrorIs(t, err, repository.ErrAmbiguous)
+	require.ErrorContains(t, err, "2 zones named test.com in project 1234: zone-1 (test.com), zone-2 (Test.com)")
+}
+
+func TestRRSetRepository_FetchRRSetForZoneAmbiguous(t *testing.T) {
+	t.Parallel()
+
+	server := newDuplicatesTestServer(t)
+
+	for _, tt := range []struct {
+		zone, name, rrSetType, err string
+	}{
+		{"cname", "www.test.com.", "CNAME", "2 CNAME record sets named www.test.com. in zone cname: c1, c2"},
+		{"txt", "_acme-challenge.test.com.", rrSetTypeTxt,
+			"2 TXT record sets named _acme-challenge.test.com. in zone txt: b, a"},
+	} {
+		rrSetRepository, err := repository.NewRRSetRepositoryFactory().NewRRSetRepository(server.config(), tt.zone)
+		require.NoError(t, err)
+
+		_, err = rrSetRepository.FetchRRSetForZone(context.TODO(), tt.name, tt.rrSetType)
+		require.ErrorIs(t, err, repository.ErrAmbiguous)
+		require.ErrorContains(t, err, tt.err)
+	}
+
+	// Reads never write, duplicates are merged by the writer.
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	require.Empty(t, server.patched)
+	require.Empty(t, server.deleted)
+}
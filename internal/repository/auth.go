@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/metrics"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+)
+
+// CredentialRefresher returns a Config with freshly loaded credentials. It is
+// called once after the DNS API rejected a request with 401.
+type CredentialRefresher func() (Config, error)
+
+// authenticatedClient holds the API client of a repository and swaps it for
+// one built from refreshed credentials when the API answers 401.
+type authenticatedClient struct {
+	mu        sync.Mutex
+	config    Config
+	apiClient *stackitdnsclient.APIClient
+}
+
+func newAuthenticatedClient(config Config) (*authenticatedClient, error) {
+	apiClient, err := chooseNewStackitDnsClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authenticatedClient{
+		config:    config,
+		apiClient: apiClient,
+	}, nil
+}
+
+// do runs call and retries it once with refreshed credentials on a 401.
+// Failures are classified and wrapped with the operation, project and zone.
+func (c *authenticatedClient) do(
+	ctx context.Context,
+	operation, zoneId string,
+	call func(ctx context.Context, api *stackitdnsclient.APIClient) error,
+) error {
+	err := call(ctx, c.current())
+	if statusCode(err) == http.StatusUnauthorized && c.config.RefreshCredentials != nil {
+		if refreshErr := c.refresh(); refreshErr != nil {
+			metrics.CredentialRefreshes.WithLabelValues("failed").Inc()
+
+			return fmt.Errorf("%s: %w: refreshing credentials for project %s failed: %w",
+				operationContext(operation, c.config.ProjectId, zoneId), ErrUnauthorized, c.config.ProjectId, refreshErr)
+		}
+		metrics.CredentialRefreshes.WithLabelValues("refreshed").Inc()
+
+		err = call(ctx, c.current())
+	}
+
+	return classifyError(err, operation, c.config.ProjectId, zoneId)
+}
+
+func (c *authenticatedClient) current() *stackitdnsclient.APIClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.apiClient
+}
+
+func (c *authenticatedClient) refresh() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.config.ClientCache != nil {
+		c.config.ClientCache.forget(c.config)
+	}
+
+	config, err := c.config.RefreshCredentials()
+	if err != nil {
+		return err
+	}
+
+	apiClient, err := chooseNewStackitDnsClient(config)
+	if err != nil {
+		return err
+	}
+
+	c.config = config
+	c.apiClient = apiClient
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuthTestServer accepts only "Bearer valid-token" and answers 403 for
+// project "forbidden".
+func newAuthTestServer(t *testing.T, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Header.Get("Authorization") != "Bearer valid-token":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v1/projects/forbidden/zones":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			response, err := json.Marshal(stackitdnsclient.ListZonesResponse{
+				Zones: []stackitdnsclient.Zone{{Id: "1234", DnsName: "test.com"}},
+			})
+			require.NoError(t, err)
+			_, _ = w.Write(response)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestRepository_RetriesOnceOnUnauthorized(t *testing.T) {
+	t.Parallel()
+
+	var requests, refreshes atomic.Int32
+	server := newAuthTestServer(t, &requests)
+
+	config := repository.Config{
+		ApiBasePath: server.URL,
+		AuthToken:   "expired-token",
+		ProjectId:   "1234",
+		HttpClient:  server.Client(),
+		ClientCache: repository.NewClientCache(),
+	}
+	config.RefreshCredentials = func() (repository.Config, error) {
+		refreshes.Add(1)
+		refreshed := config
+		refreshed.AuthToken = "valid-token"
+
+		return refreshed, nil
+	}
+
+	zoneRepository, err := repository.NewZoneRepositoryFactory().NewZoneRepository(config)
+	require.NoError(t, err)
+
+	zone, err := zoneRepository.FetchZone(context.TODO(), "test.com")
+	require.NoError(t, err)
+	require.Equal(t, "1234", zone.Id)
+	require.Equal(t, int32(1), refreshes.Load())
+	require.Equal(t, int32(2), requests.Load())
+
+	// the refreshed client is kept for later calls
+	_, err = zoneRepository.FetchZone(context.TODO(), "test.com")
+	require.NoError(t, err)
+	require.Equal(t, int32(1), refreshes.Load())
+}
+
+func TestRepository_UnauthorizedAfterRefresh(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+	server := newAuthTestServer(t, &requests)
+
+	config := repository.Config{
+		ApiBasePath: server.URL,
+		AuthToken:   "expired-token",
+		ProjectId:   "1234",
+		HttpClient:  server.Client(),
+	}
+	config.RefreshCredentials = func() (repository.Config, error) {
+		return config, nil
+	}
+
+	rrSetRepository, err := repository.NewRRSetRepositoryFactory().NewRRSetRepository(config, "1234")
+	require.NoError(t, err)
+
+	err = rrSetRepository.DeleteRRSet(context.TODO(), "2222")
+	require.ErrorIs(t, err, repository.ErrUnauthorized)
+	require.ErrorContains(t, err, "credentials rejected by the STACKIT DNS API for project 1234")
+	require.Equal(t, int32(2), requests.Load(), "a 401 is retried exactly once")
+}
+
+func TestRepository_Forbidden(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+	server := newAuthTestServer(t, &requests)
+
+	zoneRepository, err := repository.NewZoneRepositoryFactory().NewZoneRepository(repository.Config{
+		ApiBasePath: server.URL,
+		AuthToken:   "valid-token",
+		ProjectId:   "forbidden",
+		HttpClient:  server.Client(),
+	})
+	require.NoError(t, err)
+
+	_, err = zoneRepository.FetchZone(context.TODO(), "test.com")
+	require.ErrorIs(t, err, repository.ErrForbidden)
+	require.ErrorContains(t, err, "service account lacks DNS permissions on project forbidden")
+	require.Equal(t, int32(1), requests.Load())
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+)
+
+// MaxCachedClients bounds the number of clients a ClientCache keeps, so
+// clients built from credentials that rotated out, e.g. an updated Secret,
+// are eventually dropped.
+const MaxCachedClients = 32
+
+// ClientCache keeps STACKIT DNS API clients keyed by the credentials they were
+// built from, evicting the least recently used client beyond
+// MaxCachedClients. Invalidate drops every client, e.g. after a credential
+// rotation.
+type ClientCache struct {
+	mu      sync.Mutex
+	clients map[string]*cachedClient
+	// uses counts lookups and orders clients by their last use.
+	uses uint64
+}
+
+type cachedClient struct {
+	client   *stackitdnsclient.APIClient
+	lastUsed uint64
+}
+
+func NewClientCache() *ClientCache {
+	return &ClientCache{
+		clients: map[string]*cachedClient{},
+	}
+}
+
+func (c *ClientCache) get(
+	config Config,
+	build func(Config) (*stackitdnsclient.APIClient, error),
+) (*stackitdnsclient.APIClient, error) {
+	key := clientCacheKey(config)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.uses++
+	if cached, ok := c.clients[key]; ok {
+		cached.lastUsed = c.uses
+
+		return cached.client, nil
+	}
+
+	client, err := build(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.clients) >= MaxCachedClients {
+		c.evictLeastRecentlyUsed()
+	}
+	c.clients[key] = &cachedClient{client: client, lastUsed: c.uses}
+
+	return client, nil
+}
+
+// evictLeastRecentlyUsed drops the client that was used longest ago. The
+// caller must hold mu.
+func (c *ClientCache) evictLeastRecentlyUsed() {
+	var oldestKey string
+	var oldest uint64
+	for key, cached := range c.clients {
+		if oldestKey == "" || cached.lastUsed < oldest {
+			oldestKey, oldest = key, cached.lastUsed
+		}
+	}
+	delete(c.clients, oldestKey)
+}
+
+// forget drops the client built from config.
+func (c *ClientCache) forget(config Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.clients, clientCacheKey(config))
+}
+
+// Invalidate drops all cached clients.
+func (c *ClientCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clients = map[string]*cachedClient{}
+}
+
+// Len returns the number of cached clients.
+func (c *ClientCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.clients)
+}
+
+// clientCacheKey hashes the credential material so secrets are not kept as
+// map keys in plain text.
+func clientCacheKey(config Config) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		config.ApiBasePath,
+		config.ServiceAccountBaseUrl,
+		config.AuthToken,
+		config.SaKeyPath,
+		config.SaKey,
+	}, "\x00")))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,70 @@
+package repository_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCache(t *testing.T) {
+	t.Parallel()
+
+	server := getTestServer(t)
+	t.Cleanup(server.Close)
+
+	cache := repository.NewClientCache()
+	config := repository.Config{
+		ApiBasePath: server.URL,
+		AuthToken:   "test-token",
+		ProjectId:   "1234",
+		HttpClient:  server.Client(),
+		ClientCache: cache,
+	}
+	zoneRepositoryFactory := repository.NewZoneRepositoryFactory()
+	rrSetRepositoryFactory := repository.NewRRSetRepositoryFactory()
+
+	_, err := zoneRepositoryFactory.NewZoneRepository(config)
+	require.NoError(t, err)
+	_, err = rrSetRepositoryFactory.NewRRSetRepository(config, "1234")
+	require.NoError(t, err)
+	require.Equal(t, 1, cache.Len(), "same credentials should share a client")
+
+	config.AuthToken = "rotated-token"
+	_, err = zoneRepositoryFactory.NewZoneRepository(config)
+	require.NoError(t, err)
+	require.Equal(t, 2, cache.Len())
+
+	cache.Invalidate()
+	require.Equal(t, 0, cache.Len())
+}
+
+func TestClientCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+
+	server := getTestServer(t)
+	t.Cleanup(server.Close)
+
+	cache := repository.NewClientCache()
+	config := repository.Config{
+		ApiBasePath: server.URL,
+		AuthToken:   "token-0",
+		ProjectId:   "1234",
+		HttpClient:  server.Client(),
+		ClientCache: cache,
+	}
+	zoneRepositoryFactory := repository.NewZoneRepositoryFactory()
+
+	for i := range repository.MaxCachedClients + 10 {
+		config.AuthToken = "token-" + strconv.Itoa(i)
+		_, err := zoneRepositoryFactory.NewZoneRepository(config)
+		require.NoError(t, err)
+	}
+	require.Equal(t, repository.MaxCachedClients, cache.Len(), "rotated credentials should be evicted")
+
+	config.AuthToken = "token-" + strconv.Itoa(repository.MaxCachedClients+9)
+	_, err := zoneRepositoryFactory.NewZoneRepository(config)
+	require.NoError(t, err)
+	require.Equal(t, repository.MaxCachedClients, cache.Len(), "recent credentials should stay cached")
+}
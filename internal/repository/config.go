@@ -9,5 +9,15 @@ type Config struct {
 	ProjectId             string
 	HttpClient            *http.Client
 	SaKeyPath             string
-	UseSaKey              bool
+	// SaKey holds the service account key content. When set it takes
+	// precedence over SaKeyPath so the caller controls when a rotated key
+	// is picked up.
+	SaKey    string
+	UseSaKey bool
+	// ClientCache is optional. When set, API clients are reused across
+	// repositories built from the same credentials.
+	ClientCache *ClientCache
+	// RefreshCredentials is optional. When set, a request rejected with 401
+	// is retried once with the credentials it returns.
+	RefreshCredentials CredentialRefresher
 }
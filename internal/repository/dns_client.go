@@ -20,8 +20,13 @@ func newStackitDnsClientBearerToken(config Config) (*stackitdnsclient.APIClient,
 }
 
 func newStackitDnsClientKeyPath(config Config) (*stackitdnsclient.APIClient, error) {
+	keyOption := stackitconfig.WithServiceAccountKeyPath(config.SaKeyPath)
+	if config.SaKey != "" {
+		keyOption = stackitconfig.WithServiceAccountKey(config.SaKey)
+	}
+
 	return newStackitDnsClient(
-		stackitconfig.WithServiceAccountKeyPath(config.SaKeyPath),
+		keyOption,
 		stackitconfig.WithHTTPClient(new(*config.HttpClient)),
 		stackitconfig.WithEndpoint(config.ApiBasePath),
 		stackitconfig.WithTokenEndpoint(config.ServiceAccountBaseUrl),
@@ -29,6 +34,14 @@ func newStackitDnsClientKeyPath(config Config) (*stackitdnsclient.APIClient, err
 }
 
 func chooseNewStackitDnsClient(config Config) (*stackitdnsclient.APIClient, error) {
+	if config.ClientCache != nil {
+		return config.ClientCache.get(config, buildStackitDnsClient)
+	}
+
+	return buildStackitDnsClient(config)
+}
+
+func buildStackitDnsClient(config Config) (*stackitdnsclient.APIClient, error) {
 	switch {
 	case config.UseSaKey:
 		return newStackitDnsClientKeyPath(config)
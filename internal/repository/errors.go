@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/stackitcloud/stackit-sdk-go/core/oapierror"
+)
+
+// Kinds of STACKIT DNS API failures. Every error returned by the repositories
+// for a failed API call matches one of them with errors.Is.
+var (
+	ErrUnauthorized = fmt.Errorf("unauthorized")
+	ErrForbidden    = fmt.Errorf("forbidden")
+	ErrNotFound     = fmt.Errorf("not found")
+	ErrConflict     = fmt.Errorf("conflict")
+	ErrValidation   = fmt.Errorf("validation failed")
+	ErrRateLimited  = fmt.Errorf("rate limited")
+	ErrServerError  = fmt.Errorf("server error")
+)
+
+// ErrAmbiguous is returned when a lookup expected to find one zone or record
+// set finds several.
+var ErrAmbiguous = fmt.Errorf("ambiguous match")
+
+// apiErrorMessageLimit caps how much of a non-JSON response body ends up in an
+// error message, and with it in the Challenge status.
+const apiErrorMessageLimit = 200
+
+// APIError is a failed call to the STACKIT DNS API. Its message names the
+// operation, project and zone so the Challenge status shows what went wrong.
+type APIError struct {
+	// Kind is one of the Err* kinds above.
+	Kind       error
+	Operation  string
+	ProjectId  string
+	ZoneId     string
+	StatusCode int
+	// Message is the error message from the response body, if any.
+	Message string
+	Err     error
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+
+	b.WriteString(operationContext(e.Operation, e.ProjectId, e.ZoneId))
+	b.WriteString(": ")
+	b.WriteString(e.Kind.Error())
+	if hint := e.hint(); hint != "" {
+		b.WriteString(": ")
+		b.WriteString(hint)
+	}
+	fmt.Fprintf(&b, " (HTTP %d)", e.StatusCode)
+	if e.Message != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Message)
+	}
+
+	return b.String()
+}
+
+func (e *APIError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+func (e *APIError) hint() string {
+	switch e.Kind {
+	case ErrUnauthorized:
+		return "credentials rejected by the STACKIT DNS API for project " + e.ProjectId
+	case ErrForbidden:
+		return "service account lacks DNS permissions on project " + e.ProjectId
+	case ErrConflict:
+		return "the record set was changed concurrently or already exists"
+	case ErrRateLimited:
+		return "too many requests to the STACKIT DNS API, retry later"
+	case ErrServerError:
+		return "the STACKIT DNS API is unavailable, retry later"
+	default:
+		return ""
+	}
+}
+
+// classifyError maps err to an APIError if it is an error response of the
+// API. Any other error is wrapped with the operation context.
+func classifyError(err error, operation, projectId, zoneId string) error {
+	if err == nil {
+		return nil
+	}
+
+	oapiError, ok := errors.AsType[*oapierror.GenericOpenAPIError](err)
+	if !ok {
+		return fmt.Errorf("%s: %w", operationContext(operation, projectId, zoneId), err)
+	}
+
+	message := apiErrorMessage(oapiError.Body)
+	kind := errorKind(oapiError.StatusCode, message)
+	if kind == nil {
+		return fmt.Errorf("%s: %w", operationContext(operation, projectId, zoneId), err)
+	}
+
+	return &APIError{
+		Kind:       kind,
+		Operation:  operation,
+		ProjectId:  projectId,
+		ZoneId:     zoneId,
+		StatusCode: oapiError.StatusCode,
+		Message:    message,
+		Err:        err,
+	}
+}
+
+func errorKind(statusCode int, message string) error {
+	switch {
+	case statusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case statusCode == http.StatusForbidden:
+		return ErrForbidden
+	case statusCode == http.StatusNotFound:
+		return ErrNotFound
+	case statusCode == http.StatusConflict:
+		return ErrConflict
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode == http.StatusBadRequest, statusCode == http.StatusUnprocessableEntity:
+		// The API answers some lookups of unknown or duplicate objects with
+		// 400, only the body tells them apart from invalid payloads.
+		lower := strings.ToLower(message)
+		switch {
+		case strings.Contains(lower, "not found"), strings.Contains(lower, "does not exist"):
+			return ErrNotFound
+		case strings.Contains(lower, "already exists"), strings.Contains(lower, "conflict"):
+			return ErrConflict
+		default:
+			return ErrValidation
+		}
+	case statusCode >= http.StatusInternalServerError:
+		return ErrServerError
+	default:
+		return nil
+	}
+}
+
+// apiErrorMessage extracts the message of an error response body.
+func apiErrorMessage(body []byte) string {
+	var response struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err == nil {
+		if response.Message != "" {
+			return response.Message
+		}
+
+		return response.Error
+	}
+
+	message := strings.TrimSpace(string(body))
+	if len(message) > apiErrorMessageLimit {
+		message = message[:apiErrorMessageLimit] + "..."
+	}
+
+	return message
+}
+
+func operationContext(operation, projectId, zoneId string) string {
+	if zoneId == "" {
+		return fmt.Sprintf("%s in project %s", operation, projectId)
+	}
+
+	return fmt.Sprintf("%s in zone %s of project %s", operation, zoneId, projectId)
+}
+
+func statusCode(err error) int {
+	if oapiError, ok := errors.AsType[*oapierror.GenericOpenAPIError](err); ok {
+		return oapiError.StatusCode
+	}
+
+	return 0
+}
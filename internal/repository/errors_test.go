@@ -0,0 +1,98 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_ErrorClassification(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		statusCode  int
+		body        string
+		wantErr     error
+		wantMessage string
+	}{
+		{
+			name:       "rate limited",
+			statusCode: http.StatusTooManyRequests,
+			body:       `{"message":"rate limit exceeded"}`,
+			wantErr:    repository.ErrRateLimited,
+			wantMessage: "create record set in zone zone-1 of project project-1: rate limited: " +
+				"too many requests to the STACKIT DNS API, retry later (HTTP 429): rate limit exceeded",
+		},
+		{
+			name:        "conflict",
+			statusCode:  http.StatusConflict,
+			body:        `{"message":"rrset already exists"}`,
+			wantErr:     repository.ErrConflict,
+			wantMessage: "(HTTP 409): rrset already exists",
+		},
+		{
+			name:        "conflict reported as bad request",
+			statusCode:  http.StatusBadRequest,
+			body:        `{"message":"record set with this name already exists"}`,
+			wantErr:     repository.ErrConflict,
+			wantMessage: "conflict",
+		},
+		{
+			name:        "validation",
+			statusCode:  http.StatusBadRequest,
+			body:        `{"error":"ttl must be between 60 and 99999999"}`,
+			wantErr:     repository.ErrValidation,
+			wantMessage: "validation failed (HTTP 400): ttl must be between 60 and 99999999",
+		},
+		{
+			name:        "server error with plain body",
+			statusCode:  http.StatusBadGateway,
+			body:        "upstream unavailable",
+			wantErr:     repository.ErrServerError,
+			wantMessage: "(HTTP 502): upstream unavailable",
+		},
+		{
+			name:        "not found",
+			statusCode:  http.StatusNotFound,
+			body:        `{"message":"zone not found"}`,
+			wantErr:     repository.ErrNotFound,
+			wantMessage: "create record set in zone zone-1 of project project-1: not found (HTTP 404)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.statusCode)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			t.Cleanup(server.Close)
+
+			rrSetRepository, err := repository.NewRRSetRepositoryFactory().NewRRSetRepository(repository.Config{
+				ApiBasePath: server.URL,
+				AuthToken:   "token",
+				ProjectId:   "project-1",
+				HttpClient:  server.Client(),
+			}, "zone-1")
+			require.NoError(t, err)
+
+			err = rrSetRepository.CreateRRSet(context.TODO(), stackitdnsclient.RecordSet{Name: "test.com.", Type: "TXT"})
+			require.ErrorIs(t, err, tt.wantErr)
+			require.ErrorContains(t, err, tt.wantMessage)
+
+			apiError, ok := errors.AsType[*repository.APIError](err)
+			require.True(t, ok)
+			require.Equal(t, tt.statusCode, apiError.StatusCode)
+		})
+	}
+}
@@ -11,6 +11,7 @@ package mock_repository
 
 import (
 	context "context"
+	iter "iter"
 	reflect "reflect"
 
 	repository "github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
@@ -85,6 +86,34 @@ func (mr *MockRRSetRepositoryMockRecorder) FetchRRSetForZone(ctx, rrSetName, rrS
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchRRSetForZone", reflect.TypeOf((*MockRRSetRepository)(nil).FetchRRSetForZone), ctx, rrSetName, rrSetType)
 }
 
+// ListRRSets mocks base method.
+func (m *MockRRSetRepository) ListRRSets(ctx context.Context, options repository.RRSetListOptions) iter.Seq2[v1api.RecordSet, error] {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRRSets", ctx, options)
+	ret0, _ := ret[0].(iter.Seq2[v1api.RecordSet, error])
+	return ret0
+}
+
+// ListRRSets indicates an expected call of ListRRSets.
+func (mr *MockRRSetRepositoryMockRecorder) ListRRSets(ctx, options any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRRSets", reflect.TypeOf((*MockRRSetRepository)(nil).ListRRSets), ctx, options)
+}
+
+// RestoreRRSet mocks base method.
+func (m *MockRRSetRepository) RestoreRRSet(ctx context.Context, rrSetId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreRRSet", ctx, rrSetId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreRRSet indicates an expected call of RestoreRRSet.
+func (mr *MockRRSetRepositoryMockRecorder) RestoreRRSet(ctx, rrSetId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreRRSet", reflect.TypeOf((*MockRRSetRepository)(nil).RestoreRRSet), ctx, rrSetId)
+}
+
 // UpdateRRSet mocks base method.
 func (m *MockRRSetRepository) UpdateRRSet(ctx context.Context, rrSet v1api.RecordSet) error {
 	m.ctrl.T.Helper()
@@ -11,6 +11,7 @@ package mock_repository
 
 import (
 	context "context"
+	iter "iter"
 	reflect "reflect"
 
 	repository "github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
@@ -57,6 +58,20 @@ func (mr *MockZoneRepositoryMockRecorder) FetchZone(ctx, zoneDnsName any) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchZone", reflect.TypeOf((*MockZoneRepository)(nil).FetchZone), ctx, zoneDnsName)
 }
 
+// ListZones mocks base method.
+func (m *MockZoneRepository) ListZones(ctx context.Context, options repository.ZoneListOptions) iter.Seq2[v1api.Zone, error] {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListZones", ctx, options)
+	ret0, _ := ret[0].(iter.Seq2[v1api.Zone, error])
+	return ret0
+}
+
+// ListZones indicates an expected call of ListZones.
+func (mr *MockZoneRepositoryMockRecorder) ListZones(ctx, options any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListZones", reflect.TypeOf((*MockZoneRepository)(nil).ListZones), ctx, options)
+}
+
 // MockZoneRepositoryFactory is a mock of ZoneRepositoryFactory interface.
 type MockZoneRepositoryFactory struct {
 	ctrl     *gomock.Controller
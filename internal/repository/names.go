@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrInvalidName is returned for domain names that cannot be canonicalized.
+var ErrInvalidName = fmt.Errorf("invalid domain name: %w", ErrValidation)
+
+// nameProfile maps names the way resolvers look them up: Unicode labels are
+// case folded and converted to punycode. Underscore labels such as
+// _acme-challenge are not valid host names, so STD3 rules are not enforced.
+var nameProfile = idna.New(
+	idna.MapForLookup(),
+	idna.Transitional(false),
+	idna.StrictDomainName(false),
+)
+
+// CanonicalZoneName returns the form the DNS API uses for zone names:
+// lowercase ASCII (punycode) without a trailing dot.
+func CanonicalZoneName(name string) (string, error) {
+	canonical, err := canonicalName(name)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(canonical, "."), nil
+}
+
+// AbsoluteZoneNames returns the canonical names of zones as absolute names
+// with a trailing dot, the form DNS messages carry. The root zone is rejected.
+func AbsoluteZoneNames(zones []string) ([]string, error) {
+	absolute := make([]string, len(zones))
+	for i, zone := range zones {
+		name, err := CanonicalZoneName(zone)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			return nil, fmt.Errorf("%w: empty zone", ErrInvalidName)
+		}
+		absolute[i] = name + "."
+	}
+
+	return absolute, nil
+}
+
+// CanonicalRRSetName returns the form the DNS API uses for record set names:
+// an absolute, lowercase ASCII (punycode) name with a trailing dot. A name
+// without a trailing dot that is neither the zone nor below it is taken as
+// relative to zoneDnsName; "@" names the zone apex. With an empty
+// zoneDnsName every name is taken as absolute.
+func CanonicalRRSetName(name, zoneDnsName string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+
+	zone, err := CanonicalZoneName(zoneDnsName)
+	if err != nil {
+		return "", err
+	}
+
+	if name == "@" {
+		if zone == "" {
+			return "", fmt.Errorf("%w: %q needs a zone", ErrInvalidName, name)
+		}
+
+		return zone + ".", nil
+	}
+
+	canonical, err := canonicalName(name)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasSuffix(canonical, ".") {
+		return canonical, nil
+	}
+
+	if zone != "" && canonical != zone && !strings.HasSuffix(canonical, "."+zone) {
+		canonical += "." + zone
+	}
+
+	return canonical + ".", nil
+}
+
+// canonicalName converts name to lowercase ASCII and keeps a trailing dot if
+// present.
+func canonicalName(name string) (string, error) {
+	if name == "" || name == "." {
+		return "", nil
+	}
+
+	canonical, err := nameProfile.ToASCII(name)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q: %w", ErrInvalidName, name, err)
+	}
+
+	if slices.Contains(strings.Split(strings.TrimSuffix(canonical, "."), "."), "") {
+		return "", fmt.Errorf("%w: %q has an empty label", ErrInvalidName, name)
+	}
+
+	return canonical, nil
+}
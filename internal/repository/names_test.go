@@ -0,0 +1,116 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalZoneName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "empty", input: "", expected: ""},
+		{name: "root", input: ".", expected: ""},
+		{name: "lowercase", input: "test.com", expected: "test.com"},
+		{name: "mixed case", input: "Test.COM", expected: "test.com"},
+		{name: "trailing dot", input: "test.com.", expected: "test.com"},
+		{name: "unicode", input: "Bücher.Example.", expected: "xn--bcher-kva.example"},
+		{name: "punycode", input: "XN--BCHER-KVA.example", expected: "xn--bcher-kva.example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := repository.CanonicalZoneName(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestAbsoluteZoneNames(t *testing.T) {
+	t.Parallel()
+
+	zones, err := repository.AbsoluteZoneNames([]string{"Test.COM", "example.com."})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test.com.", "example.com."}, zones)
+
+	_, err = repository.AbsoluteZoneNames([]string{"."})
+	require.ErrorIs(t, err, repository.ErrInvalidName)
+}
+
+func TestCanonicalRRSetName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		zone     string
+		expected string
+	}{
+		{name: "empty", input: "", zone: "test.com", expected: ""},
+		{name: "absolute", input: "_acme-challenge.test.com.", zone: "test.com", expected: "_acme-challenge.test.com."},
+		{name: "mixed case", input: "_ACME-Challenge.Test.com.", zone: "test.com", expected: "_acme-challenge.test.com."},
+		{
+			name:     "below zone without dot",
+			input:    "_acme-challenge.test.com",
+			zone:     "test.com.",
+			expected: "_acme-challenge.test.com.",
+		},
+		{name: "relative", input: "_acme-challenge", zone: "Test.com", expected: "_acme-challenge.test.com."},
+		{
+			name:     "relative multi label",
+			input:    "_acme-challenge.www",
+			zone:     "test.com",
+			expected: "_acme-challenge.www.test.com.",
+		},
+		{name: "zone itself", input: "test.com", zone: "test.com", expected: "test.com."},
+		{name: "apex", input: "@", zone: "test.com", expected: "test.com."},
+		{name: "no zone", input: "_acme-challenge.test.com", zone: "", expected: "_acme-challenge.test.com."},
+		{name: "wildcard", input: "*.Test.com.", zone: "test.com", expected: "*.test.com."},
+		{
+			name:     "unicode",
+			input:    "_acme-challenge.Bücher.Example.",
+			zone:     "bücher.example",
+			expected: "_acme-challenge.xn--bcher-kva.example.",
+		},
+		{
+			name:     "unicode zone with punycode name",
+			input:    "_acme-challenge.xn--bcher-kva.example",
+			zone:     "Bücher.example.",
+			expected: "_acme-challenge.xn--bcher-kva.example.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := repository.CanonicalRRSetName(tt.input, tt.zone)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestCanonicalNameInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := repository.CanonicalZoneName("a..test.com")
+	require.ErrorIs(t, err, repository.ErrInvalidName)
+	require.ErrorIs(t, err, repository.ErrValidation)
+
+	_, err = repository.CanonicalRRSetName("@", "")
+	require.ErrorIs(t, err, repository.ErrInvalidName)
+
+	_, err = repository.CanonicalRRSetName("_acme-challenge", "..")
+	require.ErrorIs(t, err, repository.ErrInvalidName)
+}
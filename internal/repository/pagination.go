@@ -0,0 +1,34 @@
+package repository
+
+import "iter"
+
+// listPageSize is the number of items requested per page when listing zones
+// or record sets.
+const listPageSize int32 = 100
+
+// paginate walks all pages returned by fetch, starting at page 1. It stops
+// after the last page reported by the API, on the first empty page or when the
+// consumer stops iterating.
+func paginate[T any](fetch func(page int32) ([]T, int32, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for page := int32(1); ; page++ {
+			items, totalPages, err := fetch(page)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+
+				return
+			}
+
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			if len(items) == 0 || page >= totalPages {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+)
+
+// newPagingTestServer serves 250 zones and 250 record sets in pages of the
+// requested size and counts the requests it receives.
+func newPagingTestServer(t *testing.T, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	const total = 250
+
+	zones := make([]stackitdnsclient.Zone, total)
+	rrSets := make([]stackitdnsclient.RecordSet, total)
+	for i := range total {
+		zones[i] = stackitdnsclient.Zone{Id: strconv.Itoa(i), DnsName: fmt.Sprintf("zone-%d.test.com", i)}
+		rrSets[i] = stackitdnsclient.RecordSet{Id: strconv.Itoa(i), Name: fmt.Sprintf("rrset-%d.test.com.", i), Type: "TXT"}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/projects/1234/zones", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		items, totalPages := pageOf(t, r, zones)
+		writePagingResponse(t, w, stackitdnsclient.ListZonesResponse{Zones: items, TotalPages: totalPages})
+	})
+	mux.HandleFunc("/v1/projects/1234/zones/1234/rrsets", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		items, totalPages := pageOf(t, r, rrSets)
+		writePagingResponse(t, w, stackitdnsclient.ListRecordSetsResponse{RrSets: items, TotalPages: totalPages})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func pageOf[T any](t *testing.T, r *http.Request, items []T) ([]T, int32) {
+	t.Helper()
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	require.NoError(t, err)
+	pageSize, err := strconv.Atoi(r.URL.Query().Get("pageSize"))
+	require.NoError(t, err)
+
+	totalPages := (len(items) + pageSize - 1) / pageSize
+	start := min((page-1)*pageSize, len(items))
+	end := min(start+pageSize, len(items))
+
+	return items[start:end], int32(totalPages)
+}
+
+func writePagingResponse(t *testing.T, w http.ResponseWriter, response any) {
+	t.Helper()
+
+	body, err := json.Marshal(response)
+	require.NoError(t, err)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+func TestZoneRepository_ListZones(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+	server := newPagingTestServer(t, &requests)
+
+	zoneRepository, err := repository.NewZoneRepositoryFactory().NewZoneRepository(repository.Config{
+		ApiBasePath: server.URL,
+		AuthToken:   "token",
+		ProjectId:   "1234",
+		HttpClient:  server.Client(),
+	})
+	require.NoError(t, err)
+
+	var ids []string
+	for zone, err := range zoneRepository.ListZones(context.TODO(), repository.ZoneListOptions{}) {
+		require.NoError(t, err)
+		ids = append(ids, zone.Id)
+	}
+	require.Len(t, ids, 250)
+	require.Equal(t, "249", ids[249])
+	require.Equal(t, int32(3), requests.Load())
+}
+
+func TestRRSetRepository_ListRRSets(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+	server := newPagingTestServer(t, &requests)
+
+	rrSetRepository, err := repository.NewRRSetRepositoryFactory().NewRRSetRepository(repository.Config{
+		ApiBasePath: server.URL,
+		AuthToken:   "token",
+		ProjectId:   "1234",
+		HttpClient:  server.Client(),
+	}, "1234")
+	require.NoError(t, err)
+
+	t.Run("all pages", func(t *testing.T) {
+		var ids []string
+		for rrSet, err := range rrSetRepository.ListRRSets(context.TODO(), repository.RRSetListOptions{}) {
+			require.NoError(t, err)
+			ids = append(ids, rrSet.Id)
+		}
+		require.Len(t, ids, 250)
+		require.Equal(t, "0", ids[0])
+		require.Equal(t, "249", ids[249])
+	})
+
+	t.Run("stops fetching when the consumer stops", func(t *testing.T) {
+		requests.Store(0)
+		for rrSet, err := range rrSetRepository.ListRRSets(context.TODO(), repository.RRSetListOptions{}) {
+			require.NoError(t, err)
+			if rrSet.Id == "150" {
+				break
+			}
+		}
+		require.Equal(t, int32(2), requests.Load())
+	})
+}
+
+func TestRRSetRepository_ListRRSetsError(t *testing.T) {
+	t.Parallel()
+
+	server := getTestServer(t)
+	t.Cleanup(server.Close)
+
+	rrSetRepository, err := repository.NewRRSetRepositoryFactory().NewRRSetRepository(repository.Config{
+		ApiBasePath: server.URL,
+		AuthToken:   "token",
+		ProjectId:   "1234",
+		HttpClient:  server.Client(),
+	}, "5678")
+	require.NoError(t, err)
+
+	var errs []error
+	for _, err := range rrSetRepository.ListRRSets(context.TODO(), repository.RRSetListOptions{}) {
+		errs = append(errs, err)
+	}
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs[0], repository.ErrServerError)
+}
@@ -4,22 +4,26 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"iter"
+	"strings"
+	"sync"
 
-	"github.com/stackitcloud/stackit-sdk-go/core/oapierror"
 	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
 )
 
 var (
-	ErrRRSetNotFound = fmt.Errorf("rrset not found")
+	ErrRRSetNotFound = fmt.Errorf("rrset %w", ErrNotFound)
 	ErrEmptyRRSet    = fmt.Errorf("empty rrset")
 )
 
 //go:generate mockgen -destination=./mock/rrset_repository.go -source=./rrset_repository.go RRSetRepository
 type RRSetRepository interface {
 	FetchRRSetForZone(ctx context.Context, rrSetName string, rrSetType string) (*stackitdnsclient.RecordSet, error)
+	ListRRSets(ctx context.Context, options RRSetListOptions) iter.Seq2[stackitdnsclient.RecordSet, error]
 	CreateRRSet(ctx context.Context, rrSet stackitdnsclient.RecordSet) error
 	UpdateRRSet(ctx context.Context, rrSet stackitdnsclient.RecordSet) error
 	DeleteRRSet(ctx context.Context, rrSetId string) error
+	RestoreRRSet(ctx context.Context, rrSetId string) error
 }
 
 //go:generate mockgen -destination=./mock/rrset_repository.go -source=./rrset_repository.go RRSetRepositoryFactory
@@ -27,10 +31,23 @@ type RRSetRepositoryFactory interface {
 	NewRRSetRepository(config Config, zoneId string) (RRSetRepository, error)
 }
 
+// RRSetListOptions filters ListRRSets. Empty fields match every record set.
+type RRSetListOptions struct {
+	Name string
+	Type string
+	// ActiveOnly skips record sets that are not active.
+	ActiveOnly bool
+}
+
 type rrSetRepository struct {
-	apiClient *stackitdnsclient.APIClient
+	client    *authenticatedClient
 	projectId string
 	zoneId    string
+
+	// zoneDnsName is fetched on first use, for record set names relative to
+	// the zone.
+	mu          sync.Mutex
+	zoneDnsName string
 }
 
 type rrSetRepositoryFactory struct{}
@@ -39,13 +56,13 @@ func (r rrSetRepositoryFactory) NewRRSetRepository(
 	config Config,
 	zoneId string,
 ) (RRSetRepository, error) {
-	apiClient, err := chooseNewStackitDnsClient(config)
+	client, err := newAuthenticatedClient(config)
 	if err != nil {
 		return nil, err
 	}
 
 	return &rrSetRepository{
-		apiClient: apiClient,
+		client:    client,
 		projectId: config.ProjectId,
 		zoneId:    zoneId,
 	}, nil
@@ -55,33 +72,131 @@ func NewRRSetRepositoryFactory() RRSetRepositoryFactory {
 	return rrSetRepositoryFactory{}
 }
 
-// FetchRRSetForZone fetch specific rr set for a zone.
+// FetchRRSetForZone fetch specific rr set for a zone. Several active record
+// sets of the name and type, e.g. left behind by concurrent creates, fail with
+// ErrAmbiguous and are left as they are.
 func (r *rrSetRepository) FetchRRSetForZone(
 	ctx context.Context,
 	rrSetName string,
 	rrSetType string,
 ) (*stackitdnsclient.RecordSet, error) {
-	var pager int32 = 1
-	listRequest := r.apiClient.DefaultAPI.ListRecordSets(ctx, r.projectId, r.zoneId).
-		Page(pager).PageSize(10000).
-		ActiveEq(true).NameEq(rrSetName).TypeEq(stackitdnsclient.ListRecordSetsTypeEqParameter(rrSetType))
+	var rrSets []stackitdnsclient.RecordSet
+	for rrSet, err := range r.ListRRSets(ctx, RRSetListOptions{Name: rrSetName, Type: rrSetType, ActiveOnly: true}) {
+		if err != nil {
+			return nil, err
+		}
+		rrSets = append(rrSets, rrSet)
+	}
+
+	switch len(rrSets) {
+	case 0:
+		return nil, ErrRRSetNotFound
+	case 1:
+		return &rrSets[0], nil
+	default:
+		return nil, fmt.Errorf("%w: %d %s record sets named %s in zone %s: %s",
+			ErrAmbiguous, len(rrSets), rrSetType, rrSetName, r.zoneId, rrSetIds(rrSets))
+	}
+}
+
+func rrSetIds(rrSets []stackitdnsclient.RecordSet) string {
+	ids := make([]string, len(rrSets))
+	for i, rrSet := range rrSets {
+		ids[i] = rrSet.Id
+	}
+
+	return strings.Join(ids, ", ")
+}
+
+// ListRRSets iterates over all record sets of the zone matching options,
+// fetching further pages as needed.
+func (r *rrSetRepository) ListRRSets(
+	ctx context.Context,
+	options RRSetListOptions,
+) iter.Seq2[stackitdnsclient.RecordSet, error] {
+	return paginate(func(page int32) ([]stackitdnsclient.RecordSet, int32, error) {
+		name, err := r.canonicalName(ctx, options.Name)
+		if err != nil {
+			return nil, 0, err
+		}
 
-	rrSetResponse, err := listRequest.Execute()
+		var rrSetResponse *stackitdnsclient.ListRecordSetsResponse
+		err = r.do(ctx, "list record sets", func(ctx context.Context, api *stackitdnsclient.APIClient) error {
+			request := api.DefaultAPI.ListRecordSets(ctx, r.projectId, r.zoneId).Page(page).PageSize(listPageSize)
+			if name != "" {
+				request = request.NameEq(name)
+			}
+			if options.Type != "" {
+				request = request.TypeEq(stackitdnsclient.ListRecordSetsTypeEqParameter(options.Type))
+			}
+			if options.ActiveOnly {
+				request = request.ActiveEq(true)
+			}
+
+			var err error
+			rrSetResponse, err = request.Execute()
+
+			return err
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return rrSetResponse.RrSets, rrSetResponse.TotalPages, nil
+	})
+}
+
+// canonicalName returns the absolute form of a record set name. Names without
+// a trailing dot that are not within the zone are relative to it.
+func (r *rrSetRepository) canonicalName(ctx context.Context, name string) (string, error) {
+	if name == "" || strings.HasSuffix(name, ".") {
+		return CanonicalRRSetName(name, "")
+	}
+
+	zoneDnsName, err := r.fetchZoneDnsName(ctx)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	if len(rrSetResponse.RrSets) == 0 {
-		return nil, ErrRRSetNotFound
+	return CanonicalRRSetName(name, zoneDnsName)
+}
+
+func (r *rrSetRepository) fetchZoneDnsName(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.zoneDnsName != "" {
+		return r.zoneDnsName, nil
 	}
 
-	return &rrSetResponse.RrSets[0], nil
+	var zoneResponse *stackitdnsclient.ZoneResponse
+	err := r.do(ctx, "get zone", func(ctx context.Context, api *stackitdnsclient.APIClient) error {
+		var err error
+		zoneResponse, err = api.DefaultAPI.GetZone(ctx, r.projectId, r.zoneId).Execute()
+
+		return err
+	})
+	if errors.Is(err, ErrNotFound) {
+		return "", fmt.Errorf("%w: %w", ErrZoneNotFound, err)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	r.zoneDnsName = zoneResponse.Zone.DnsName
+
+	return r.zoneDnsName, nil
 }
 
 func (r *rrSetRepository) CreateRRSet(
 	ctx context.Context,
 	rrSet stackitdnsclient.RecordSet,
 ) error {
+	name, err := r.canonicalName(ctx, rrSet.Name)
+	if err != nil {
+		return err
+	}
+
 	var records []stackitdnsclient.RecordPayload
 	if rrSet.Records != nil {
 		records = make([]stackitdnsclient.RecordPayload, len(rrSet.Records))
@@ -94,12 +209,16 @@ func (r *rrSetRepository) CreateRRSet(
 	ttl := rrSet.Ttl
 	payload := stackitdnsclient.CreateRecordSetPayload{
 		Comment: rrSet.Comment,
-		Name:    rrSet.Name,
+		Name:    name,
 		Ttl:     &ttl,
 		Type:    stackitdnsclient.CreateRecordSetPayloadType(string(rrSet.Type)),
 		Records: records,
 	}
-	_, err := r.apiClient.DefaultAPI.CreateRecordSet(ctx, r.projectId, r.zoneId).CreateRecordSetPayload(payload).Execute()
+	err = r.do(ctx, "create record set", func(ctx context.Context, api *stackitdnsclient.APIClient) error {
+		_, err := api.DefaultAPI.CreateRecordSet(ctx, r.projectId, r.zoneId).CreateRecordSetPayload(payload).Execute()
+
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -111,6 +230,11 @@ func (r *rrSetRepository) UpdateRRSet(
 	ctx context.Context,
 	rrSet stackitdnsclient.RecordSet,
 ) error {
+	name, err := r.canonicalName(ctx, rrSet.Name)
+	if err != nil {
+		return err
+	}
+
 	records := make([]stackitdnsclient.RecordPayload, len(rrSet.Records))
 	for i, record := range rrSet.Records {
 		records[i] = stackitdnsclient.RecordPayload{
@@ -120,13 +244,17 @@ func (r *rrSetRepository) UpdateRRSet(
 	ttl := rrSet.Ttl
 	payload := stackitdnsclient.PartialUpdateRecordSetPayload{
 		Comment: rrSet.Comment,
-		Name:    &rrSet.Name,
+		Name:    &name,
 		Records: records,
 		Ttl:     &ttl,
 	}
 
-	_, err := r.apiClient.DefaultAPI.PartialUpdateRecordSet(ctx, r.projectId, r.zoneId, rrSet.Id).
-		PartialUpdateRecordSetPayload(payload).Execute()
+	err = r.do(ctx, "update record set "+rrSet.Id, func(ctx context.Context, api *stackitdnsclient.APIClient) error {
+		_, err := api.DefaultAPI.PartialUpdateRecordSet(ctx, r.projectId, r.zoneId, rrSet.Id).
+			PartialUpdateRecordSetPayload(payload).Execute()
+
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -135,14 +263,31 @@ func (r *rrSetRepository) UpdateRRSet(
 }
 
 func (r *rrSetRepository) DeleteRRSet(ctx context.Context, rrSetId string) error {
-	_, err := r.apiClient.DefaultAPI.DeleteRecordSet(ctx, r.projectId, r.zoneId, rrSetId).Execute()
-	if err != nil {
-		if oapiError, ok := errors.AsType[*oapierror.GenericOpenAPIError](err); ok {
-			if oapiError.StatusCode == 404 || oapiError.StatusCode == 400 {
-				return ErrRRSetNotFound
-			}
-		}
+	err := r.do(ctx, "delete record set "+rrSetId, func(ctx context.Context, api *stackitdnsclient.APIClient) error {
+		_, err := api.DefaultAPI.DeleteRecordSet(ctx, r.projectId, r.zoneId, rrSetId).Execute()
+
+		return err
+	})
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: %w", ErrRRSetNotFound, err)
 	}
 
 	return err
 }
+
+// RestoreRRSet reactivates an inactive or deleted record set.
+func (r *rrSetRepository) RestoreRRSet(ctx context.Context, rrSetId string) error {
+	return r.do(ctx, "restore record set "+rrSetId, func(ctx context.Context, api *stackitdnsclient.APIClient) error {
+		_, err := api.DefaultAPI.RestoreRecordSet(ctx, r.projectId, r.zoneId, rrSetId).Execute()
+
+		return err
+	})
+}
+
+func (r *rrSetRepository) do(
+	ctx context.Context,
+	operation string,
+	call func(ctx context.Context, api *stackitdnsclient.APIClient) error,
+) error {
+	return r.client.do(ctx, operation, r.zoneId, call)
+}
@@ -2,6 +2,9 @@ package repository_test
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
@@ -132,6 +135,16 @@ func TestRrSetRepository_DeleteRRSet(t *testing.T) {
 		require.NoError(t, err)
 		err = rrSetRepository.DeleteRRSet(ctx, "4444")
 		require.Error(t, err)
+		require.ErrorIs(t, err, repository.ErrValidation)
+		require.NotErrorIs(t, err, repository.ErrRRSetNotFound)
+	})
+
+	t.Run("DeleteRRSet 400 not found return", func(t *testing.T) {
+		t.Parallel()
+		rrSetRepository, err := rrSetRepositoryFactory.NewRRSetRepository(config, "1234")
+		require.NoError(t, err)
+		err = rrSetRepository.DeleteRRSet(ctx, "6666")
+		require.Error(t, err)
 		require.ErrorIs(t, err, repository.ErrRRSetNotFound)
 	})
 
@@ -145,6 +158,61 @@ func TestRrSetRepository_DeleteRRSet(t *testing.T) {
 	})
 }
 
+func TestRrSetRepository_RestoreRRSet(t *testing.T) {
+	t.Parallel()
+
+	ctx, config, rrSetRepositoryFactory := setupRRSetRepositoryTests(t)
+
+	t.Run("RestoreRRSet success", func(t *testing.T) {
+		t.Parallel()
+		rrSetRepository, err := rrSetRepositoryFactory.NewRRSetRepository(config, "1234")
+		require.NoError(t, err)
+		err = rrSetRepository.RestoreRRSet(ctx, "2222")
+		require.NoError(t, err)
+	})
+
+	t.Run("RestoreRRSet not found", func(t *testing.T) {
+		t.Parallel()
+		rrSetRepository, err := rrSetRepositoryFactory.NewRRSetRepository(config, "1234")
+		require.NoError(t, err)
+		err = rrSetRepository.RestoreRRSet(ctx, "7777")
+		require.ErrorIs(t, err, repository.ErrNotFound)
+	})
+}
+
+func TestRrSetRepository_RelativeNames(t *testing.T) {
+	t.Parallel()
+
+	var zoneFetches atomic.Int32
+	var names []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/projects/1234/zones/zone-1", func(w http.ResponseWriter, _ *http.Request) {
+		zoneFetches.Add(1)
+		writeJSON(t, w, http.StatusOK, stackitdnsclient.ZoneResponse{Zone: stackitdnsclient.Zone{DnsName: "test.com"}})
+	})
+	mux.HandleFunc("GET /v1/projects/1234/zones/zone-1/rrsets", func(w http.ResponseWriter, r *http.Request) {
+		names = append(names, r.URL.Query().Get("name[eq]"))
+		writeJSON(t, w, http.StatusOK, stackitdnsclient.ListRecordSetsResponse{TotalPages: 1})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	rrSetRepository, err := repository.NewRRSetRepositoryFactory().NewRRSetRepository(repository.Config{
+		ApiBasePath: server.URL,
+		AuthToken:   "test-token",
+		ProjectId:   "1234",
+		HttpClient:  server.Client(),
+	}, "zone-1")
+	require.NoError(t, err)
+
+	for _, name := range []string{"foo", "_acme-challenge.Test.com", "@", "bar."} {
+		_, err := rrSetRepository.FetchRRSetForZone(context.TODO(), name, rrSetTypeTxt)
+		require.ErrorIs(t, err, repository.ErrRRSetNotFound)
+	}
+	require.Equal(t, []string{"foo.test.com.", "_acme-challenge.test.com.", "test.com.", "bar."}, names)
+	require.Equal(t, int32(1), zoneFetches.Load())
+}
+
 func setupRRSetRepositoryTests(t *testing.T) (context.Context, repository.Config, repository.RRSetRepositoryFactory) {
 	t.Helper()
 
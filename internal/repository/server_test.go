@@ -92,6 +92,13 @@ func getTestServer(t *testing.T) *httptest.Server { //nolint:funlen // This is a
 			failureResponse(t, w)
 		},
 	)
+	// Case RestoreRRSet success
+	mux.HandleFunc(
+		"/v1/projects/1234/zones/1234/rrsets/2222/restores",
+		func(w http.ResponseWriter, r *http.Request) {
+			patchRRSetResponseSuccess(t, w)
+		},
+	)
 	// Case DeleteRRSet 400 return
 	mux.HandleFunc(
 		"/v1/projects/1234/zones/1234/rrsets/4444",
@@ -99,6 +106,13 @@ func getTestServer(t *testing.T) *httptest.Server { //nolint:funlen // This is a
 			deleteRRSetResponse400(t, w)
 		},
 	)
+	// Case DeleteRRSet 400 not found return
+	mux.HandleFunc(
+		"/v1/projects/1234/zones/1234/rrsets/6666",
+		func(w http.ResponseWriter, r *http.Request) {
+			deleteRRSetResponse400NotFound(t, w)
+		},
+	)
 	// Case DeleteRRSet 404 return
 	mux.HandleFunc(
 		"/v1/projects/1234/zones/1234/rrsets/5555",
@@ -243,6 +257,18 @@ func deleteRRSetResponse400(t *testing.T, w http.ResponseWriter) {
 	writeResponseMessageSuccess(t, w, http.StatusBadRequest)
 }
 
+func deleteRRSetResponse400NotFound(t *testing.T, w http.ResponseWriter) {
+	t.Helper()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response, err := json.Marshal(stackitdnsclient.Message{Message: ptr.To("record set not found")})
+	assert.NoError(t, err)
+
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(response)
+}
+
 func deleteRRSetResponse404(t *testing.T, w http.ResponseWriter) {
 	t.Helper()
 
@@ -3,16 +3,18 @@ package repository
 import (
 	"context"
 	"fmt"
+	"iter"
 	"strings"
 
 	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
 )
 
-var ErrZoneNotFound = fmt.Errorf("zone not found")
+var ErrZoneNotFound = fmt.Errorf("zone %w", ErrNotFound)
 
 //go:generate mockgen -destination=./mock/zone_repository.go -source=./zone_repository.go ZoneRepository
 type ZoneRepository interface {
 	FetchZone(ctx context.Context, zoneDnsName string) (*stackitdnsclient.Zone, error)
+	ListZones(ctx context.Context, options ZoneListOptions) iter.Seq2[stackitdnsclient.Zone, error]
 }
 
 //go:generate mockgen -destination=./mock/zone_repository.go -source=./zone_repository.go ZoneRepositoryFactory
@@ -20,8 +22,15 @@ type ZoneRepositoryFactory interface {
 	NewZoneRepository(config Config) (ZoneRepository, error)
 }
 
+// ZoneListOptions filters ListZones. Empty fields match every zone.
+type ZoneListOptions struct {
+	DnsName string
+	// ActiveOnly skips zones that are not active.
+	ActiveOnly bool
+}
+
 type zoneRepository struct {
-	apiClient *stackitdnsclient.APIClient
+	client    *authenticatedClient
 	projectId string
 }
 
@@ -30,13 +39,13 @@ type zoneRepositoryFactory struct{}
 func (z zoneRepositoryFactory) NewZoneRepository(
 	config Config,
 ) (ZoneRepository, error) {
-	apiClient, err := chooseNewStackitDnsClient(config)
+	client, err := newAuthenticatedClient(config)
 	if err != nil {
 		return nil, err
 	}
 
 	return &zoneRepository{
-		apiClient: apiClient,
+		client:    client,
 		projectId: config.ProjectId,
 	}, nil
 }
@@ -45,18 +54,76 @@ func NewZoneRepositoryFactory() ZoneRepositoryFactory {
 	return zoneRepositoryFactory{}
 }
 
+// FetchZone returns the active zone with the given DNS name. More than one
+// match, e.g. zones differing only in case or trailing dot, fails with
+// ErrAmbiguous.
 func (z *zoneRepository) FetchZone(
 	ctx context.Context,
 	zoneDnsName string,
 ) (*stackitdnsclient.Zone, error) {
-	zoneResponse, err := z.apiClient.DefaultAPI.ListZones(ctx, z.projectId).ActiveEq(true).DnsNameEq(strings.ToLower(zoneDnsName)).Execute()
-	if err != nil {
-		return nil, err
+	var zones []stackitdnsclient.Zone
+	for zone, err := range z.ListZones(ctx, ZoneListOptions{DnsName: zoneDnsName, ActiveOnly: true}) {
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, zone)
 	}
 
-	if len(zoneResponse.Zones) == 0 {
+	switch len(zones) {
+	case 0:
 		return nil, ErrZoneNotFound
+	case 1:
+		return &zones[0], nil
+	default:
+		ids := make([]string, len(zones))
+		for i, zone := range zones {
+			ids[i] = zone.Id + " (" + zone.DnsName + ")"
+		}
+
+		return nil, fmt.Errorf("%w: %d zones named %s in project %s: %s",
+			ErrAmbiguous, len(zones), zoneDnsName, z.projectId, strings.Join(ids, ", "))
 	}
+}
+
+// ListZones iterates over all zones of the project matching options, fetching
+// further pages as needed.
+func (z *zoneRepository) ListZones(
+	ctx context.Context,
+	options ZoneListOptions,
+) iter.Seq2[stackitdnsclient.Zone, error] {
+	return paginate(func(page int32) ([]stackitdnsclient.Zone, int32, error) {
+		dnsName, err := CanonicalZoneName(options.DnsName)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var zoneResponse *stackitdnsclient.ListZonesResponse
+		err = z.do(ctx, "list zones", func(ctx context.Context, api *stackitdnsclient.APIClient) error {
+			request := api.DefaultAPI.ListZones(ctx, z.projectId).Page(page).PageSize(listPageSize)
+			if dnsName != "" {
+				request = request.DnsNameEq(dnsName)
+			}
+			if options.ActiveOnly {
+				request = request.ActiveEq(true)
+			}
+
+			var err error
+			zoneResponse, err = request.Execute()
+
+			return err
+		})
+		if err != nil {
+			return nil, 0, err
+		}
 
-	return &zoneResponse.Zones[0], nil
+		return zoneResponse.Zones, zoneResponse.TotalPages, nil
+	})
+}
+
+func (z *zoneRepository) do(
+	ctx context.Context,
+	operation string,
+	call func(ctx context.Context, api *stackitdnsclient.APIClient) error,
+) error {
+	return z.client.do(ctx, operation, "", call)
 }
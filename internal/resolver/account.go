@@ -0,0 +1,82 @@
+package resolver
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// accountLabelHashLength is the number of SHA-256 bytes in the account
+	// label of dns-account-01, encoding to 16 base32 characters without
+	// padding.
+	accountLabelHashLength = 10
+	// challengeTypeDNSAccount01 is the challenge type whose record names
+	// carry an account label.
+	challengeTypeDNSAccount01 = "dns-account-01"
+)
+
+var ErrAccountLabelMismatch = errors.New("challenge record name is scoped to another ACME account")
+
+var accountLabelEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// accountLabel returns the dns-account-01 label of an ACME account, "_"
+// followed by the lowercase base32 encoding of the first 10 bytes of the
+// SHA-256 digest of the account URL.
+func accountLabel(accountURI string) string {
+	digest := sha256.Sum256([]byte(accountURI))
+
+	return "_" + strings.ToLower(accountLabelEncoding.EncodeToString(digest[:accountLabelHashLength]))
+}
+
+func isAccountLabel(label string) bool {
+	hash, ok := strings.CutPrefix(label, "_")
+	if !ok || len(hash) != accountLabelEncoding.EncodedLen(accountLabelHashLength) {
+		return false
+	}
+
+	_, err := accountLabelEncoding.DecodeString(strings.ToUpper(hash))
+
+	return err == nil
+}
+
+// splitChallengeName splits a canonical challenge record name into the
+// account label, if any, and the domain below the _acme-challenge label. ok
+// is false for names without the _acme-challenge label.
+func splitChallengeName(rrSetName string) (label, domain string, ok bool) {
+	first, rest, _ := strings.Cut(rrSetName, ".")
+	if isAccountLabel(first) {
+		label = first
+		rrSetName = rest
+	}
+
+	domain, ok = strings.CutPrefix(rrSetName, acmeChallengeLabel+".")
+
+	return label, domain, ok
+}
+
+// accountScopedName returns the dns-account-01 record name
+// _<label>._acme-challenge.<domain> for the dns-01 name
+// _acme-challenge.<domain>, so every ACME account uses a record set of its
+// own. Names that are already account scoped are kept if they match
+// accountURI; names without the _acme-challenge label, e.g. after cert-manager
+// followed a CNAME, are kept as they are.
+func accountScopedName(rrSetName, accountURI string) (string, error) {
+	label, _, ok := splitChallengeName(rrSetName)
+	if accountURI == "" || !ok {
+		return rrSetName, nil
+	}
+
+	expected := accountLabel(accountURI)
+	switch label {
+	case "":
+		return expected + "." + rrSetName, nil
+	case expected:
+		return rrSetName, nil
+	default:
+		return "", fmt.Errorf("%w: %s has label %s, expected %s for account %s",
+			ErrAccountLabelMismatch, rrSetName, label, expected, accountURI)
+	}
+}
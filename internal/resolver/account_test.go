@@ -0,0 +1,277 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	repository_mock "github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository/mock"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const (
+	accountA = "https://acme.test/acme/acct/1"
+	accountB = "https://acme.test/acme/acct/2"
+)
+
+func TestAccountLabel(t *testing.T) {
+	t.Parallel()
+
+	// The example of draft-ietf-acme-dns-account-label.
+	require.Equal(t, "_ujmmovf2vn55tgye", accountLabel("https://example.com/acme/acct/ExampleAccount"))
+	require.NotEqual(t, accountLabel(accountA), accountLabel(accountB))
+	require.True(t, isAccountLabel(accountLabel(accountA)))
+	require.False(t, isAccountLabel(acmeChallengeLabel))
+	require.False(t, isAccountLabel("_ujmmovf2vn55tgy1"))
+}
+
+func TestAccountScopedName(t *testing.T) {
+	t.Parallel()
+
+	labelA := accountLabel(accountA)
+
+	tests := []struct {
+		name       string
+		rrSetName  string
+		accountURI string
+		expected   string
+		err        error
+	}{
+		{name: "no account", rrSetName: "_acme-challenge.test.com.", expected: "_acme-challenge.test.com."},
+		{
+			name:       "dns-01 name",
+			rrSetName:  "_acme-challenge.www.test.com.",
+			accountURI: accountA,
+			expected:   labelA + "._acme-challenge.www.test.com.",
+		},
+		{
+			name:       "already scoped",
+			rrSetName:  labelA + "._acme-challenge.test.com.",
+			accountURI: accountA,
+			expected:   labelA + "._acme-challenge.test.com.",
+		},
+		{
+			name:      "scoped by the request",
+			rrSetName: labelA + "._acme-challenge.test.com.",
+			expected:  labelA + "._acme-challenge.test.com.",
+		},
+		{
+			name:       "scoped to another account",
+			rrSetName:  labelA + "._acme-challenge.test.com.",
+			accountURI: accountB,
+			err:        ErrAccountLabelMismatch,
+		},
+		{
+			name:       "without challenge label",
+			rrSetName:  "acme.delegated.test.com.",
+			accountURI: accountA,
+			expected:   "acme.delegated.test.com.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			name, err := accountScopedName(tt.rrSetName, tt.accountURI)
+			require.ErrorIs(t, err, tt.err)
+			require.Equal(t, tt.expected, name)
+		})
+	}
+}
+
+func TestValidateChallengeAcceptsAccountLabel(t *testing.T) {
+	t.Parallel()
+
+	require.Empty(t, validateChallenge("test.com", accountLabel(accountA)+"._acme-challenge.test.com.", validChallengeKey))
+}
+
+// memoryRRSetRepository keeps record sets in memory, like the DNS API would.
+type memoryRRSetRepository struct {
+	mu     sync.Mutex
+	rrSets map[string]stackitdnsclient.RecordSet
+	nextId int
+}
+
+func newMemoryRRSetRepository() *memoryRRSetRepository {
+	return &memoryRRSetRepository{rrSets: map[string]stackitdnsclient.RecordSet{}}
+}
+
+func (r *memoryRRSetRepository) FetchRRSetForZone(
+	_ context.Context,
+	rrSetName, rrSetType string,
+) (*stackitdnsclient.RecordSet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rrSet := range r.rrSets {
+		if rrSet.Name == rrSetName && rrSet.Type == stackitdnsclient.RecordSetType(rrSetType) {
+			rrSet.Records = slices.Clone(rrSet.Records)
+
+			return &rrSet, nil
+		}
+	}
+
+	return nil, repository.ErrRRSetNotFound
+}
+
+func (r *memoryRRSetRepository) ListRRSets(
+	_ context.Context,
+	options repository.RRSetListOptions,
+) iter.Seq2[stackitdnsclient.RecordSet, error] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var rrSets []stackitdnsclient.RecordSet
+	for _, rrSet := range r.rrSets {
+		if (options.Name == "" || rrSet.Name == options.Name) &&
+			(options.Type == "" || string(rrSet.Type) == options.Type) {
+			rrSets = append(rrSets, rrSet)
+		}
+	}
+
+	return rrSetSeq(rrSets...)
+}
+
+func (r *memoryRRSetRepository) CreateRRSet(_ context.Context, rrSet stackitdnsclient.RecordSet) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.rrSets {
+		if existing.Name == rrSet.Name && existing.Type == rrSet.Type {
+			return repository.ErrConflict
+		}
+	}
+
+	r.nextId++
+	rrSet.Id = fmt.Sprint(r.nextId)
+	r.rrSets[rrSet.Id] = rrSet
+
+	return nil
+}
+
+func (r *memoryRRSetRepository) UpdateRRSet(_ context.Context, rrSet stackitdnsclient.RecordSet) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rrSets[rrSet.Id] = rrSet
+
+	return nil
+}
+
+func (r *memoryRRSetRepository) DeleteRRSet(_ context.Context, rrSetId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.rrSets, rrSetId)
+
+	return nil
+}
+
+func (r *memoryRRSetRepository) RestoreRRSet(context.Context, string) error {
+	return nil
+}
+
+// records returns the record contents by record set name.
+func (r *memoryRRSetRepository) records() map[string][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	records := map[string][]string{}
+	for _, rrSet := range r.rrSets {
+		for _, record := range rrSet.Records {
+			records[rrSet.Name] = append(records[rrSet.Name], record.Content)
+		}
+	}
+
+	return records
+}
+
+// unvalidatedConfigProvider decodes the solver config without validating it,
+// since accountURI is rejected until cert-manager sends dns-account-01
+// challenges.
+type unvalidatedConfigProvider struct{}
+
+func (unvalidatedConfigProvider) LoadConfig(cfgJSON *extapi.JSON) (StackitDnsProviderConfig, error) {
+	var cfg StackitDnsProviderConfig
+	if err := unmarshalConfig(cfgJSON, &cfg); err != nil {
+		return cfg, err
+	}
+	setDefaultValues(&cfg)
+
+	return cfg, nil
+}
+
+func TestConcurrentAccounts(t *testing.T) {
+	t.Parallel()
+
+	rrSets := newMemoryRRSetRepository()
+	ctrl := gomock.NewController(t)
+	zoneRepository := repository_mock.NewMockZoneRepository(ctrl)
+	zoneRepository.EXPECT().
+		FetchZone(gomock.Any(), "test.com").
+		Return(&stackitdnsclient.Zone{Id: "zone", DnsName: "test.com"}, nil).
+		AnyTimes()
+	zoneRepositoryFactory := repository_mock.NewMockZoneRepositoryFactory(ctrl)
+	zoneRepositoryFactory.EXPECT().NewZoneRepository(gomock.Any()).Return(zoneRepository, nil).AnyTimes()
+	rrSetRepositoryFactory := repository_mock.NewMockRRSetRepositoryFactory(ctrl)
+	rrSetRepositoryFactory.EXPECT().NewRRSetRepository(gomock.Any(), "zone").Return(rrSets, nil).AnyTimes()
+
+	solver := NewResolver(
+		&http.Client{},
+		zap.NewNop(),
+		zoneRepositoryFactory,
+		rrSetRepositoryFactory,
+		staticSecretFetcher{"certs/stackit-cert-manager-webhook/auth-token": "token"},
+		unvalidatedConfigProvider{},
+	)
+
+	challenge := func(accountURI, key string) *v1alpha1.ChallengeRequest {
+		return &v1alpha1.ChallengeRequest{
+			Type:         challengeTypeDNSAccount01,
+			Key:          key,
+			ResolvedZone: "test.com.",
+			ResolvedFQDN: "_acme-challenge.test.com.",
+			Config: &extapi.JSON{Raw: fmt.Appendf(nil,
+				`{"projectId":"project","authTokenSecretNamespace":"certs","accountURI":%q}`, accountURI)},
+		}
+	}
+	challengeA := challenge(accountA, "key-a")
+	challengeB := challenge(accountB, "key-b")
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, ch := range []*v1alpha1.ChallengeRequest{challengeA, challengeB} {
+		wg.Go(func() {
+			errs[i] = solver.Present(ch)
+		})
+	}
+	wg.Wait()
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1])
+
+	nameA := accountLabel(accountA) + "._acme-challenge.test.com."
+	nameB := accountLabel(accountB) + "._acme-challenge.test.com."
+	require.Equal(t, map[string][]string{nameA: {"key-a"}, nameB: {"key-b"}}, rrSets.records())
+
+	// Cleaning up one account leaves the record set of the other untouched.
+	require.NoError(t, solver.CleanUp(challengeA))
+	require.Equal(t, map[string][]string{nameB: {"key-b"}}, rrSets.records())
+
+	// dns-01 challenges of the same issuer keep the name the ACME server
+	// validates.
+	dns01 := challenge(accountA, "key-c")
+	dns01.Type = "dns-01"
+	require.NoError(t, solver.Present(dns01))
+	require.Equal(t, map[string][]string{nameB: {"key-b"}, "_acme-challenge.test.com.": {"key-c"}}, rrSets.records())
+}
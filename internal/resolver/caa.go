@@ -0,0 +1,225 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/miekg/dns"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	typeCAARecord = "CAA"
+
+	caaTagIssue     = "issue"
+	caaTagIssueWild = "issuewild"
+	caaFlagCritical = 128
+)
+
+var (
+	ErrCAANotPermitted = errors.New("CAA records do not permit the configured CA")
+	ErrInvalidCAA      = errors.New("invalid CAA record")
+)
+
+// CAACheckConfig makes Present check the CAA records of the domain against
+// IssuerDomain, the domain the ACME CA uses in CAA issue properties, e.g.
+// letsencrypt.org.
+type CAACheckConfig struct {
+	IssuerDomain string `json:"issuerDomain"`
+}
+
+func validateCAACheckConfig(check *CAACheckConfig) error {
+	if check != nil && check.IssuerDomain == "" {
+		return fmt.Errorf("caaCheck.issuerDomain must be specified")
+	}
+
+	return nil
+}
+
+type caaRecord struct {
+	flags uint64
+	tag   string
+	value string
+}
+
+// parseCAARecord parses record content in presentation format, e.g.
+// `0 issue "letsencrypt.org"`.
+func parseCAARecord(content string) (caaRecord, error) {
+	fields := strings.SplitN(strings.TrimSpace(content), " ", 3)
+	if len(fields) != 3 {
+		return caaRecord{}, fmt.Errorf("%w: %q", ErrInvalidCAA, content)
+	}
+
+	flags, err := strconv.ParseUint(fields[0], 10, 8)
+	if err != nil {
+		return caaRecord{}, fmt.Errorf("%w: flags of %q: %w", ErrInvalidCAA, content, err)
+	}
+
+	value := strings.TrimSpace(fields[2])
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		value = value[1 : len(value)-1]
+	}
+
+	return caaRecord{flags: flags, tag: strings.ToLower(fields[1]), value: value}, nil
+}
+
+// caaPermits evaluates the relevant CAA record set of a domain as described
+// in RFC 8659, section 4, and explains why issuerDomain may not issue.
+func caaPermits(records []caaRecord, issuerDomain string, wildcard bool) error {
+	tag := caaTagIssue
+	for _, record := range records {
+		switch record.tag {
+		case caaTagIssue, "iodef", "contactemail", "contactphone", "issuemail", "issuevmc":
+		case caaTagIssueWild:
+			if wildcard {
+				tag = caaTagIssueWild
+			}
+		default:
+			if record.flags&caaFlagCritical != 0 {
+				return fmt.Errorf("the critical property %q is not understood", record.tag)
+			}
+		}
+	}
+
+	restricted := false
+	var issuers []string
+	for _, record := range records {
+		if record.tag != tag {
+			continue
+		}
+		restricted = true
+
+		domain, _, _ := strings.Cut(record.value, ";")
+		domain = strings.TrimSuffix(strings.TrimSpace(domain), ".")
+		if strings.EqualFold(domain, issuerDomain) {
+			return nil
+		}
+		if domain != "" {
+			issuers = append(issuers, domain)
+		}
+	}
+
+	switch {
+	case !restricted:
+		return nil
+	case len(issuers) == 0:
+		return fmt.Errorf("%s forbids every CA", tag)
+	default:
+		return fmt.Errorf("%s only allows %s", tag, strings.Join(issuers, ", "))
+	}
+}
+
+// checkCAA fails Present if the CAA records of the domain forbid the
+// configured CA to issue for it, since issuance would fail only after the
+// challenge succeeded. The relevant record set is the one closest to the
+// domain; record sets above the zone apex are not visible to the webhook.
+func (s *stackitDnsProviderResolver) checkCAA(
+	ch *v1alpha1.ChallengeRequest,
+	initResolverRes *initResolverContextResult,
+) error {
+	check := initResolverRes.caaCheck
+	zone := initResolverRes.zone.DnsName
+	domain, wildcard, err := caaDomain(ch, initResolverRes)
+	if err != nil {
+		return err
+	}
+	apex := zone + "."
+	if domain != apex && !strings.HasSuffix(domain, "."+apex) {
+		// The challenge was delegated to this zone, e.g. by a CNAME. The CAA
+		// records of the domain live elsewhere.
+		s.logger.Info("Skipping CAA check for domain outside of the zone",
+			zap.String("domain", domain), zap.String("zoneDnsName", zone))
+
+		return nil
+	}
+
+	caaRRSets, err := s.listCAARecords(initResolverRes)
+	if err != nil {
+		return err
+	}
+
+	name, records := relevantCAA(caaRRSets, domain, apex)
+	if len(records) == 0 {
+		return nil
+	}
+
+	if err := caaPermits(records, check.IssuerDomain, wildcard); err != nil {
+		// cert-manager presents the challenge of *.example.com for
+		// example.com, so the request does not tell whether issuewild applies.
+		if wildcard || caaPermits(records, check.IssuerDomain, true) != nil {
+			return fmt.Errorf("%w %s to issue for %s: %w (CAA record set %s)",
+				ErrCAANotPermitted, check.IssuerDomain, domain, err, name)
+		}
+
+		s.logger.Info("CAA records permit the CA for wildcard certificates only",
+			zap.String("domain", domain), zap.String("caaName", name))
+
+		return nil
+	}
+
+	s.logger.Info("CAA records permit the CA", zap.String("domain", domain), zap.String("caaName", name))
+
+	return nil
+}
+
+// listCAARecords returns the CAA records of the zone by record set name.
+func (s *stackitDnsProviderResolver) listCAARecords(
+	initResolverRes *initResolverContextResult,
+) (map[string][]caaRecord, error) {
+	caaRRSets := map[string][]caaRecord{}
+	rrSets := initResolverRes.rrSetRepository.ListRRSets(s.ctx, repository.RRSetListOptions{
+		Type:       typeCAARecord,
+		ActiveOnly: true,
+	})
+	for rrSet, err := range rrSets {
+		if err != nil {
+			return nil, fmt.Errorf("error listing CAA records of zone %s: %w", initResolverRes.zone.DnsName, err)
+		}
+
+		name := strings.ToLower(rrSet.Name)
+		for _, record := range rrSet.Records {
+			caa, err := parseCAARecord(record.Content)
+			if err != nil {
+				return nil, fmt.Errorf("CAA record set %s: %w", rrSet.Name, err)
+			}
+			caaRRSets[name] = append(caaRRSets[name], caa)
+		}
+	}
+
+	return caaRRSets, nil
+}
+
+// relevantCAA climbs from domain up to the zone apex and returns the first
+// non-empty CAA record set.
+func relevantCAA(caaRRSets map[string][]caaRecord, domain, apex string) (string, []caaRecord) {
+	for name := domain; ; name = parentName(name) {
+		if records := caaRRSets[name]; len(records) > 0 {
+			return name, records
+		}
+		if name == apex || name == "." {
+			return "", nil
+		}
+	}
+}
+
+// caaDomain returns the canonical domain the certificate is requested for and
+// whether it is a wildcard. Without the domain in the request it is derived
+// from the challenge record name.
+func caaDomain(ch *v1alpha1.ChallengeRequest, initResolverRes *initResolverContextResult) (string, bool, error) {
+	name := ch.DNSName
+	if name == "" {
+		name = initResolverRes.rrSetName
+		if _, domain, ok := splitChallengeName(name); ok {
+			name = domain
+		}
+	}
+	name, wildcard := strings.CutPrefix(name, "*.")
+
+	domain, err := repository.CanonicalRRSetName(dns.Fqdn(name), initResolverRes.zone.DnsName)
+
+	return domain, wildcard, err
+}
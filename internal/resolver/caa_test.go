@@ -0,0 +1,184 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	repository_mock "github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository/mock"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestParseCAARecord(t *testing.T) {
+	t.Parallel()
+
+	record, err := parseCAARecord(`0 issue "letsencrypt.org; validationmethods=dns-01"`)
+	require.NoError(t, err)
+	require.Equal(t, caaRecord{tag: "issue", value: "letsencrypt.org; validationmethods=dns-01"}, record)
+
+	record, err = parseCAARecord(`128 TBS "x"`)
+	require.NoError(t, err)
+	require.Equal(t, caaRecord{flags: 128, tag: "tbs", value: "x"}, record)
+
+	_, err = parseCAARecord(`0 issue`)
+	require.ErrorIs(t, err, ErrInvalidCAA)
+
+	_, err = parseCAARecord(`256 issue "x"`)
+	require.ErrorIs(t, err, ErrInvalidCAA)
+}
+
+func TestCAAPermits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		records  []string
+		wildcard bool
+		reason   string
+	}{
+		{name: "permitted", records: []string{`0 issue "sectigo.com"`, `0 issue "LetsEncrypt.org."`}},
+		{name: "with parameters", records: []string{`0 issue "letsencrypt.org; accounturi=https://acme/1"`}},
+		{name: "no issue property", records: []string{`0 iodef "mailto:security@test.com"`}},
+		{name: "other CA", records: []string{`0 issue "sectigo.com"`}, reason: "issue only allows sectigo.com"},
+		{name: "no CA", records: []string{`0 issue ";"`}, reason: "issue forbids every CA"},
+		{
+			name:     "wildcard uses issuewild",
+			records:  []string{`0 issue "letsencrypt.org"`, `0 issuewild "sectigo.com"`},
+			wildcard: true,
+			reason:   "issuewild only allows sectigo.com",
+		},
+		{
+			name:    "issuewild is ignored for other names",
+			records: []string{`0 issue "letsencrypt.org"`, `0 issuewild ";"`},
+		},
+		{name: "wildcard falls back to issue", records: []string{`0 issue "letsencrypt.org"`}, wildcard: true},
+		{
+			name:    "unknown critical property",
+			records: []string{`0 issue "letsencrypt.org"`, `128 tbs "x"`},
+			reason:  `the critical property "tbs" is not understood`,
+		},
+		{name: "unknown property", records: []string{`0 issue "letsencrypt.org"`, `0 tbs "x"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			records := make([]caaRecord, len(tt.records))
+			for i, content := range tt.records {
+				var err error
+				records[i], err = parseCAARecord(content)
+				require.NoError(t, err)
+			}
+
+			err := caaPermits(records, "letsencrypt.org", tt.wildcard)
+			if tt.reason == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.reason)
+			}
+		})
+	}
+}
+
+func caaRRSet(name string, contents ...string) stackitdnsclient.RecordSet {
+	records := make([]stackitdnsclient.Record, len(contents))
+	for i, content := range contents {
+		records[i] = stackitdnsclient.Record{Content: content}
+	}
+
+	return stackitdnsclient.RecordSet{Name: name, Type: "CAA", Records: records}
+}
+
+func TestCheckCAA(t *testing.T) {
+	t.Parallel()
+
+	rrSets := []stackitdnsclient.RecordSet{
+		caaRRSet("test.com.", `0 issue "letsencrypt.org"`),
+		caaRRSet("shop.test.com.", `0 issue "sectigo.com"`),
+		caaRRSet("wild.test.com.", `0 issue "sectigo.com"`, `0 issuewild "letsencrypt.org"`),
+	}
+
+	tests := []struct {
+		name      string
+		dnsName   string
+		rrSetName string
+		err       string
+	}{
+		{name: "apex record set applies to subdomains", dnsName: "www.test.com"},
+		{
+			name:    "closest record set applies",
+			dnsName: "a.shop.test.com",
+			err: "CAA records do not permit the configured CA letsencrypt.org to issue for a.shop.test.com.: " +
+				"issue only allows sectigo.com (CAA record set shop.test.com.)",
+		},
+		{name: "domain from record name", rrSetName: "_acme-challenge.shop.test.com.", err: "sectigo.com"},
+		{name: "wildcard", dnsName: "*.shop.test.com", err: "sectigo.com"},
+		{name: "domain outside of the zone", dnsName: "shop.other.org"},
+		// cert-manager requests the challenge of *.wild.test.com for
+		// wild.test.com, so issuewild may apply.
+		{name: "permitted by issuewild", dnsName: "wild.test.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+			rrSetRepository.EXPECT().
+				ListRRSets(gomock.Any(), repository.RRSetListOptions{Type: "CAA", ActiveOnly: true}).
+				Return(rrSetSeq(rrSets...)).
+				MaxTimes(1)
+
+			s := &stackitDnsProviderResolver{ctx: context.Background(), logger: zap.NewNop()}
+			err := s.checkCAA(&v1alpha1.ChallengeRequest{DNSName: tt.dnsName}, &initResolverContextResult{
+				rrSetRepository: rrSetRepository,
+				zone:            &stackitdnsclient.Zone{DnsName: "test.com"},
+				rrSetName:       tt.rrSetName,
+				caaCheck:        &CAACheckConfig{IssuerDomain: "letsencrypt.org"},
+			})
+			if tt.err == "" {
+				require.NoError(t, err)
+
+				return
+			}
+			require.ErrorIs(t, err, ErrCAANotPermitted)
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestCheckCAA_WildcardChallengeRequest(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+	rrSetRepository.EXPECT().
+		ListRRSets(gomock.Any(), repository.RRSetListOptions{Type: "CAA", ActiveOnly: true}).
+		Return(rrSetSeq(caaRRSet("test.com.", `0 issue ";"`, `0 issuewild "letsencrypt.org"`)))
+
+	// The request cert-manager sends for a Certificate of *.test.com: the
+	// wildcard label is stripped from DNSName and nothing else marks it.
+	ch := &v1alpha1.ChallengeRequest{
+		Action:       v1alpha1.ChallengeActionPresent,
+		Type:         "dns-01",
+		DNSName:      "test.com",
+		Key:          validChallengeKey,
+		ResolvedFQDN: "_acme-challenge.test.com.",
+		ResolvedZone: "test.com.",
+	}
+
+	s := &stackitDnsProviderResolver{ctx: context.Background(), logger: zap.NewNop()}
+	err := s.checkCAA(ch, &initResolverContextResult{
+		rrSetRepository: rrSetRepository,
+		zone:            &stackitdnsclient.Zone{DnsName: "test.com"},
+		rrSetName:       "_acme-challenge.test.com.",
+		caaCheck:        &CAACheckConfig{IssuerDomain: "letsencrypt.org"},
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,109 @@
+package resolver
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"go.uber.org/zap"
+)
+
+// Levels of `challengeValidation` in the issuer config. With
+// ChallengeValidationWarn malformed challenge requests are logged and still
+// processed, with ChallengeValidationStrict they are rejected before any call
+// to the DNS API.
+const (
+	ChallengeValidationOff    = "off"
+	ChallengeValidationWarn   = "warn"
+	ChallengeValidationStrict = "strict"
+)
+
+const (
+	acmeChallengeLabel = "_acme-challenge"
+	// challengeKeyLength is the length of an unpadded base64url SHA-256 digest,
+	// the key of a dns-01 challenge (RFC 8555, section 8.4).
+	challengeKeyLength = 43
+)
+
+var ErrInvalidChallenge = errors.New("invalid challenge request")
+
+func isKnownChallengeValidation(level string) bool {
+	switch level {
+	case ChallengeValidationOff, ChallengeValidationWarn, ChallengeValidationStrict:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkChallenge validates the canonical names and key of a challenge
+// request according to level. Only ChallengeValidationStrict returns an
+// error, and only for Present: CleanUp must still remove what was presented
+// before validation became strict. An empty level behaves like
+// ChallengeValidationWarn.
+func (s *stackitDnsProviderResolver) checkChallenge(
+	level string,
+	operation string,
+	ch *v1alpha1.ChallengeRequest,
+	zoneDnsName string,
+	rrSetName string,
+) error {
+	if level == ChallengeValidationOff {
+		return nil
+	}
+
+	problems := validateChallenge(zoneDnsName, rrSetName, ch.Key)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	err := fmt.Errorf("%w for %s in zone %s: %w", ErrInvalidChallenge, rrSetName, zoneDnsName, errors.Join(problems...))
+	if level == ChallengeValidationStrict && operation == operationPresent {
+		s.logger.Error("Rejecting challenge request", zap.Error(err))
+
+		return err
+	}
+
+	s.logger.Warn("Challenge request failed validation", zap.Error(err))
+
+	return nil
+}
+
+// validateChallenge returns every problem found with a challenge request: the
+// record set must be inside the zone, carry the _acme-challenge label, possibly
+// below a dns-account-01 account label, and the key must be a base64url
+// encoded SHA-256 digest.
+func validateChallenge(zoneDnsName, rrSetName, key string) []error {
+	var problems []error
+
+	switch {
+	case zoneDnsName == "":
+		problems = append(problems, errors.New("no zone resolved"))
+	case rrSetName != zoneDnsName+"." && !strings.HasSuffix(rrSetName, "."+zoneDnsName+"."):
+		problems = append(problems, fmt.Errorf("%s is outside of zone %s", rrSetName, zoneDnsName))
+	}
+
+	if _, _, ok := splitChallengeName(rrSetName); !ok {
+		problems = append(problems, fmt.Errorf("%s does not start with %s", rrSetName, acmeChallengeLabel))
+	}
+
+	if err := validateChallengeKey(key); err != nil {
+		problems = append(problems, err)
+	}
+
+	return problems
+}
+
+func validateChallengeKey(key string) error {
+	if len(key) != challengeKeyLength {
+		return fmt.Errorf("key has %d characters, expected %d", len(key), challengeKeyLength)
+	}
+
+	if _, err := base64.RawURLEncoding.Strict().DecodeString(key); err != nil {
+		return fmt.Errorf("key is not base64url encoded: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// validChallengeKey is shaped like a dns-01 key: an unpadded base64url
+// SHA-256 digest.
+const validChallengeKey = "LoqXcYV8q5ONbJQxbmR7SCTNo3tiAXDfowyjxAjEuX0"
+
+func TestValidateChallenge(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		zone      string
+		rrSetName string
+		key       string
+		problems  []string
+	}{
+		{
+			name:      "valid",
+			zone:      "test.com",
+			rrSetName: "_acme-challenge.test.com.",
+			key:       validChallengeKey,
+		},
+		{
+			name:      "valid subdomain",
+			zone:      "test.com",
+			rrSetName: "_acme-challenge.www.test.com.",
+			key:       validChallengeKey,
+		},
+		{
+			name:      "zone apex of delegated challenge zone",
+			zone:      "_acme-challenge.test.com",
+			rrSetName: "_acme-challenge.test.com.",
+			key:       validChallengeKey,
+		},
+		{
+			name:      "outside of zone",
+			zone:      "test.com",
+			rrSetName: "_acme-challenge.othertest.com.",
+			key:       validChallengeKey,
+			problems:  []string{"_acme-challenge.othertest.com. is outside of zone test.com"},
+		},
+		{
+			name:      "no zone",
+			rrSetName: "_acme-challenge.test.com.",
+			key:       validChallengeKey,
+			problems:  []string{"no zone resolved"},
+		},
+		{
+			name:      "missing label",
+			zone:      "test.com",
+			rrSetName: "www.test.com.",
+			key:       validChallengeKey,
+			problems:  []string{"www.test.com. does not start with _acme-challenge"},
+		},
+		{
+			name:      "short key",
+			zone:      "test.com",
+			rrSetName: "_acme-challenge.test.com.",
+			key:       "abc",
+			problems:  []string{"key has 3 characters, expected 43"},
+		},
+		{
+			name:      "key with padding alphabet",
+			zone:      "test.com",
+			rrSetName: "_acme-challenge.test.com.",
+			key:       strings.Replace(validChallengeKey, "L", "+", 1),
+			problems:  []string{"key is not base64url encoded"},
+		},
+		{
+			name:      "everything wrong",
+			zone:      "test.com",
+			rrSetName: "evil.com.",
+			problems: []string{
+				"evil.com. is outside of zone test.com",
+				"evil.com. does not start with _acme-challenge",
+				"key has 0 characters, expected 43",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			problems := validateChallenge(tt.zone, tt.rrSetName, tt.key)
+			require.Len(t, problems, len(tt.problems))
+			for i, problem := range problems {
+				require.ErrorContains(t, problem, tt.problems[i])
+			}
+		})
+	}
+}
+
+func TestCheckChallenge(t *testing.T) {
+	t.Parallel()
+
+	s := &stackitDnsProviderResolver{logger: zap.NewNop()}
+	invalid := &v1alpha1.ChallengeRequest{Key: "not-a-digest"}
+	valid := &v1alpha1.ChallengeRequest{Key: validChallengeKey}
+	check := func(level, operation string, ch *v1alpha1.ChallengeRequest, rrSetName string) error {
+		return s.checkChallenge(level, operation, ch, "test.com", rrSetName)
+	}
+
+	err := check(ChallengeValidationStrict, operationPresent, invalid, "_acme-challenge.test.com.")
+	require.ErrorIs(t, err, ErrInvalidChallenge)
+	require.ErrorContains(t, err, "key has 12 characters")
+
+	require.NoError(t, check(ChallengeValidationStrict, operationPresent, valid, "_acme-challenge.test.com."))
+	require.NoError(t, check(ChallengeValidationWarn, operationPresent, invalid, "_acme-challenge.test.com."))
+	require.NoError(t, check("", operationPresent, invalid, "_acme-challenge.test.com."))
+	require.NoError(t, check(ChallengeValidationOff, operationPresent, invalid, "www.othertest.com."))
+	// CleanUp removes what was presented before validation became strict.
+	require.NoError(t, check(ChallengeValidationStrict, operationCleanUp, invalid, "www.othertest.com."))
+}
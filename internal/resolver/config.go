@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 //go:generate mockgen -destination=./mock/config.go -source=./config.go ConfigProvider
@@ -27,6 +28,43 @@ type StackitDnsProviderConfig struct {
 	ServiceAccountKeyPath    string `json:"serviceAccountKeyPath"`
 	ServiceAccountBaseUrl    string `json:"serviceAccountBaseUrl"`
 	AcmeTxtRecordTTL         int32  `json:"acmeTxtRecordTTL"`
+	// AuthMethod pins a single credential provider. AuthMethods sets an
+	// ordered chain instead. Both empty means the default chain.
+	AuthMethod                 string       `json:"authMethod"`
+	AuthMethods                []string     `json:"authMethods"`
+	AuthTokenPath              string       `json:"authTokenPath"`
+	ServiceAccountKeySecretRef string       `json:"serviceAccountKeySecretRef"`
+	ServiceAccountKeySecretKey string       `json:"serviceAccountKeySecretKey"`
+	Vault                      *VaultConfig `json:"vault"`
+	// VerifyWrites re-fetches the record set after Present and CleanUp and
+	// re-applies the change if another writer clobbered it.
+	VerifyWrites bool `json:"verifyWrites"`
+	// ChallengeValidation sets how malformed challenge requests are handled:
+	// "off", "warn" (default) or "strict".
+	ChallengeValidation string `json:"challengeValidation"`
+	// TTLPolicy selects where the TTL of the TXT record set comes from:
+	// "issuer" (default), "keep" or "zone".
+	TTLPolicy string `json:"ttlPolicy"`
+	// PropagationCheck makes Present wait until the authoritative nameservers
+	// of the zone serve the challenge record.
+	PropagationCheck *PropagationCheckConfig `json:"propagationCheck"`
+	// DelegationCheck makes Present check the NS delegation of the zone by
+	// its parent before writing the challenge record.
+	DelegationCheck *DelegationCheckConfig `json:"delegationCheck"`
+	// PropagationDelayBudget makes Present fail if resolvers may cache the
+	// absence of the challenge record for longer, see negativeCacheDelay.
+	PropagationDelayBudget metav1.Duration `json:"propagationDelayBudget"`
+	// ZoneUpdateTimeout makes Present wait up to this long for a zone in
+	// state UPDATING. CleanUp does not wait.
+	ZoneUpdateTimeout metav1.Duration `json:"zoneUpdateTimeout"`
+	// CAACheck makes Present check that the CAA records of the domain permit
+	// the ACME CA to issue.
+	CAACheck *CAACheckConfig `json:"caaCheck"`
+	// AccountURI switches dns-account-01 challenges to the record names of
+	// this ACME account, see accountScopedName. cert-manager only sends dns-01
+	// challenges to webhooks, so it is rejected until a cert-manager release
+	// sends dns-account-01.
+	AccountURI string `json:"accountURI"`
 }
 
 func (d defaultConfigProvider) LoadConfig(cfgJSON *extapi.JSON) (StackitDnsProviderConfig, error) {
@@ -68,6 +106,74 @@ func validateConfig(cfg *StackitDnsProviderConfig) error {
 		return fmt.Errorf("projectId must be specified")
 	}
 
+	if err := validateChallengeValidation(cfg); err != nil {
+		return err
+	}
+
+	if err := validateTTLConfig(cfg); err != nil {
+		return err
+	}
+
+	if err := validatePropagationCheckConfig(cfg.PropagationCheck); err != nil {
+		return err
+	}
+
+	if err := validateCAACheckConfig(cfg.CAACheck); err != nil {
+		return err
+	}
+
+	if cfg.PropagationDelayBudget.Duration < 0 || cfg.ZoneUpdateTimeout.Duration < 0 {
+		return fmt.Errorf("propagationDelayBudget and zoneUpdateTimeout must not be negative")
+	}
+
+	if cfg.AccountURI != "" {
+		return fmt.Errorf("accountURI is not supported yet, cert-manager only sends dns-01 challenges to webhooks")
+	}
+
+	return validateAuthConfig(cfg)
+}
+
+func validateAuthConfig(cfg *StackitDnsProviderConfig) error {
+	if cfg.AuthMethod != "" && len(cfg.AuthMethods) > 0 {
+		return fmt.Errorf("only one of authMethod and authMethods may be specified")
+	}
+
+	for _, method := range authMethodChain(cfg) {
+		if !isKnownAuthMethod(method) {
+			return fmt.Errorf("unknown auth method %q", method)
+		}
+	}
+
+	if cfg.Vault == nil {
+		return nil
+	}
+
+	if cfg.Vault.Address != "" || cfg.Vault.TokenPath != "" {
+		return fmt.Errorf("vault.address and vault.tokenPath are set by the deployment, use VAULT_ADDR and VAULT_TOKEN_PATH")
+	}
+
+	if cfg.Vault.Path == "" {
+		return fmt.Errorf("vault.path must be specified")
+	}
+
+	return nil
+}
+
+// validateTTLConfig only checks the policy. The TTL itself is validated when
+// Present resolves it, so CleanUp is not blocked by it.
+func validateTTLConfig(cfg *StackitDnsProviderConfig) error {
+	if cfg.TTLPolicy != "" && !isKnownTTLPolicy(cfg.TTLPolicy) {
+		return fmt.Errorf("unknown ttlPolicy %q", cfg.TTLPolicy)
+	}
+
+	return nil
+}
+
+func validateChallengeValidation(cfg *StackitDnsProviderConfig) error {
+	if cfg.ChallengeValidation != "" && !isKnownChallengeValidation(cfg.ChallengeValidation) {
+		return fmt.Errorf("unknown challengeValidation %q", cfg.ChallengeValidation)
+	}
+
 	return nil
 }
 
@@ -84,6 +190,33 @@ func setDefaultValues(cfg *StackitDnsProviderConfig) {
 	if cfg.AcmeTxtRecordTTL == 0 {
 		cfg.AcmeTxtRecordTTL = 600
 	}
+	if cfg.TTLPolicy == "" {
+		cfg.TTLPolicy = TTLPolicyIssuer
+	}
+	if cfg.ChallengeValidation == "" {
+		cfg.ChallengeValidation = ChallengeValidationWarn
+	}
+	if cfg.ServiceAccountKeySecretKey == "" {
+		cfg.ServiceAccountKeySecretKey = "sa.json"
+	}
+	if cfg.Vault != nil {
+		setVaultDefaultValues(cfg.Vault)
+	}
+	if cfg.PropagationCheck != nil {
+		setPropagationCheckDefaultValues(cfg.PropagationCheck)
+	}
+}
+
+func setVaultDefaultValues(vault *VaultConfig) {
+	if vault.Mount == "" {
+		vault.Mount = "secret"
+	}
+	if vault.KvVersion == 0 {
+		vault.KvVersion = vaultKvVersion2
+	}
+	if vault.TokenKey == "" && vault.ServiceAccountKeyKey == "" {
+		vault.TokenKey = "auth-token"
+	}
 }
 
 func determineNamespace(currentNamespace string, fileNamespaceName string) (string, error) {
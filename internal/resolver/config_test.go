@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
@@ -78,6 +79,8 @@ func TestLoadConfig(t *testing.T) {
 		require.Equal(t, "stackit-cert-manager-webhook", cfg.AuthTokenSecretRef)
 		require.Equal(t, "auth-token", cfg.AuthTokenSecretKey)
 		require.Equal(t, int32(600), cfg.AcmeTxtRecordTTL)
+		require.Equal(t, ChallengeValidationWarn, cfg.ChallengeValidation)
+		require.Equal(t, TTLPolicyIssuer, cfg.TTLPolicy)
 	})
 
 	t.Run("custom service account base url", func(t *testing.T) {
@@ -89,6 +92,108 @@ func TestLoadConfig(t *testing.T) {
 		require.Equal(t, "test", cfg.ProjectId)
 		require.Equal(t, "https://custom.stackit.cloud/dns", cfg.ServiceAccountBaseUrl)
 	})
+
+	t.Run("auth method and chain are exclusive", func(t *testing.T) {
+		t.Parallel()
+
+		rawCfg := &v1.JSON{Raw: []byte(`{"projectId":"test", "authMethod": "vault", "authMethods": ["authTokenEnv"]}`)}
+		_, err := d.LoadConfig(rawCfg)
+		require.EqualError(t, err, "only one of authMethod and authMethods may be specified")
+	})
+
+	t.Run("unknown auth method", func(t *testing.T) {
+		t.Parallel()
+
+		rawCfg := &v1.JSON{Raw: []byte(`{"projectId":"test", "authMethods": ["authTokenEnv", "password"]}`)}
+		_, err := d.LoadConfig(rawCfg)
+		require.EqualError(t, err, `unknown auth method "password"`)
+	})
+
+	t.Run("vault defaults", func(t *testing.T) {
+		t.Parallel()
+
+		rawCfg := &v1.JSON{Raw: []byte(`{"projectId":"test", "authTokenSecretNamespace": "test", "vault": {"path": "stackit"}}`)}
+		cfg, err := d.LoadConfig(rawCfg)
+		require.NoError(t, err)
+		require.Equal(t, "secret", cfg.Vault.Mount)
+		require.Equal(t, 2, cfg.Vault.KvVersion)
+		require.Equal(t, "auth-token", cfg.Vault.TokenKey)
+		require.Equal(t, "sa.json", cfg.ServiceAccountKeySecretKey)
+	})
+
+	t.Run("vault without path", func(t *testing.T) {
+		t.Parallel()
+
+		rawCfg := &v1.JSON{Raw: []byte(`{"projectId":"test", "vault": {"mount": "kv"}}`)}
+		_, err := d.LoadConfig(rawCfg)
+		require.EqualError(t, err, "vault.path must be specified")
+	})
+
+	t.Run("account URI", func(t *testing.T) {
+		t.Parallel()
+
+		rawCfg := &v1.JSON{Raw: []byte(`{"projectId":"test", "accountURI": "https://acme.test/acct/1"}`)}
+		_, err := d.LoadConfig(rawCfg)
+		require.ErrorContains(t, err, "accountURI is not supported yet")
+	})
+
+	t.Run("vault address from the issuer", func(t *testing.T) {
+		t.Parallel()
+
+		rawCfg := &v1.JSON{Raw: []byte(`{"projectId":"test", "vault": {"address": "http://attacker:8200", "path": "stackit"}}`)}
+		_, err := d.LoadConfig(rawCfg)
+		require.ErrorContains(t, err, "vault.address and vault.tokenPath are set by the deployment")
+	})
+
+	t.Run("unknown challenge validation", func(t *testing.T) {
+		t.Parallel()
+
+		rawCfg := &v1.JSON{Raw: []byte(`{"projectId":"test", "challengeValidation": "paranoid"}`)}
+		_, err := d.LoadConfig(rawCfg)
+		require.EqualError(t, err, `unknown challengeValidation "paranoid"`)
+	})
+
+	t.Run("unknown ttl policy", func(t *testing.T) {
+		t.Parallel()
+
+		rawCfg := &v1.JSON{Raw: []byte(`{"projectId":"test", "ttlPolicy": "shortest"}`)}
+		_, err := d.LoadConfig(rawCfg)
+		require.EqualError(t, err, `unknown ttlPolicy "shortest"`)
+	})
+
+	t.Run("acme txt record ttl out of range", func(t *testing.T) {
+		t.Parallel()
+
+		// Only Present validates the TTL, so CleanUp still works.
+		rawCfg := &v1.JSON{Raw: []byte(`{"projectId":"test", "authTokenSecretNamespace": "test", "acmeTxtRecordTTL": 10}`)}
+		cfg, err := d.LoadConfig(rawCfg)
+		require.NoError(t, err)
+
+		_, err = rrSetTTL(&cfg, nil)
+		require.ErrorIs(t, err, ErrInvalidTTL)
+		require.EqualError(t, err, "acmeTxtRecordTTL: invalid ttl: 10 is outside of 60..99999999")
+	})
+
+	t.Run("caa check without issuer domain", func(t *testing.T) {
+		t.Parallel()
+
+		rawCfg := &v1.JSON{Raw: []byte(`{"projectId":"test", "caaCheck": {}}`)}
+		_, err := d.LoadConfig(rawCfg)
+		require.EqualError(t, err, "caaCheck.issuerDomain must be specified")
+	})
+
+	t.Run("propagation delay budget", func(t *testing.T) {
+		t.Parallel()
+
+		rawCfg := &v1.JSON{Raw: []byte(`{"projectId":"test", "authTokenSecretNamespace": "test", "propagationDelayBudget": "5m"}`)}
+		cfg, err := d.LoadConfig(rawCfg)
+		require.NoError(t, err)
+		require.Equal(t, 5*time.Minute, cfg.PropagationDelayBudget.Duration)
+
+		rawCfg = &v1.JSON{Raw: []byte(`{"projectId":"test", "propagationDelayBudget": "-1m"}`)}
+		_, err = d.LoadConfig(rawCfg)
+		require.EqualError(t, err, "propagationDelayBudget and zoneUpdateTimeout must not be negative")
+	})
 }
 
 func TestDefaultConfigProvider_LoadConfigNamespaceFile(t *testing.T) {
@@ -157,8 +262,9 @@ func TestGetRepositoryConfig_WithSaKeyPath(t *testing.T) {
 	}()
 
 	r := &stackitDnsProviderResolver{
-		httpClient: &http.Client{},
-		logger:     zap.NewNop(),
+		httpClient:  &http.Client{},
+		logger:      zap.NewNop(),
+		credentials: newCredentialWatcher(zap.NewNop(), nil),
 	}
 
 	cfg := &StackitDnsProviderConfig{
@@ -175,19 +281,15 @@ func TestGetRepositoryConfig_WithSaKeyPath(t *testing.T) {
 }
 
 func TestGetRepositoryConfig_NoEnvSet(t *testing.T) {
-	oldAuthToken := stackitAuthToken
-	stackitAuthToken = "token" // global variable from resolver.go
-
 	t.Setenv("STACKIT_SERVICE_ACCOUNT_KEY_PATH", "")
-	defer func() {
-		stackitAuthToken = oldAuthToken
-	}()
 
 	s := NewSecretFetcher()
 	r := &stackitDnsProviderResolver{
 		httpClient:    &http.Client{},
 		secretFetcher: s,
 		logger:        zap.NewNop(),
+		authToken:     "token",
+		credentials:   newCredentialWatcher(zap.NewNop(), nil),
 	}
 
 	cfg := &StackitDnsProviderConfig{
@@ -199,5 +301,5 @@ func TestGetRepositoryConfig_NoEnvSet(t *testing.T) {
 
 	require.NoError(t, err)
 	require.False(t, config.UseSaKey)
-	require.Equal(t, stackitAuthToken, config.AuthToken)
+	require.Equal(t, "token", config.AuthToken)
 }
@@ -0,0 +1,59 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"go.uber.org/zap"
+)
+
+// maxCreateConflictAttempts bounds how often the record set created by
+// someone else is re-fetched and updated after a create conflict.
+const maxCreateConflictAttempts = 3
+
+// recoverFromCreateConflict handles a conflict on create, typically another
+// replica creating the same record set at the same time. The record set that
+// now exists is re-fetched and the challenge key is merged into it through the
+// update path.
+func (s *stackitDnsProviderResolver) recoverFromCreateConflict(
+	initResolverRes *initResolverContextResult,
+	challengeKey string,
+) error {
+	var err error
+
+	for attempt := 1; attempt <= maxCreateConflictAttempts; attempt++ {
+		s.logger.Info(
+			"RRSet was created concurrently, merging challenge key",
+			zap.String("rrSetName", initResolverRes.rrSetName),
+			zap.Int("attempt", attempt),
+		)
+
+		var rrSet *stackitdnsclient.RecordSet
+		rrSet, err = s.fetchRRSetForWrite(initResolverRes)
+		switch {
+		case err == nil:
+			err = s.updateExistingRRSet(initResolverRes, rrSet, challengeKey)
+			if !errors.Is(err, repository.ErrConflict) {
+				return err
+			}
+		case errors.Is(err, repository.ErrRRSetNotFound):
+			// The conflicting record set is not active yet.
+		default:
+			return err
+		}
+
+		if attempt < maxCreateConflictAttempts {
+			select {
+			case <-s.ctx.Done():
+				return s.ctx.Err()
+			case <-time.After(s.rrSetPollInterval):
+			}
+		}
+	}
+
+	return fmt.Errorf("merging challenge key into concurrently created record set %s failed after %d attempts: %w",
+		initResolverRes.rrSetName, maxCreateConflictAttempts, err)
+}
@@ -0,0 +1,103 @@
+package resolver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	repository_mock "github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository/mock"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandleRRSetNotFound_CreateConflict(t *testing.T) {
+	t.Parallel()
+
+	conflict := fmt.Errorf("create record set: %w", repository.ErrConflict)
+	hasKey := func(key string) gomock.Matcher {
+		return gomock.Cond(func(rrSet stackitdnsclient.RecordSet) bool {
+			return keyExists(rrSet.Records, key)
+		})
+	}
+
+	t.Run("merges the key into the concurrently created rrset", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+		rrSetRepository.EXPECT().ListRRSets(gomock.Any(), gomock.Any()).Return(rrSetSeq())
+		gomock.InOrder(
+			rrSetRepository.EXPECT().CreateRRSet(gomock.Any(), gomock.Any()).Return(conflict),
+			rrSetRepository.EXPECT().FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(&stackitdnsclient.RecordSet{Id: "other", Records: []stackitdnsclient.Record{{Content: "other-key"}}}, nil),
+			rrSetRepository.EXPECT().UpdateRRSet(gomock.Any(), gomock.All(hasKey("other-key"), hasKey("key"))).
+				Return(nil),
+		)
+
+		err := newRRSetStateTestResolver().handleRRSetNotFound(&initResolverContextResult{
+			rrSetRepository: rrSetRepository,
+			rrSetName:       "_acme-challenge.test.com.",
+		}, "key")
+		require.NoError(t, err)
+	})
+
+	t.Run("retries until the rrset becomes visible", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+		rrSetRepository.EXPECT().ListRRSets(gomock.Any(), gomock.Any()).Return(rrSetSeq())
+		gomock.InOrder(
+			rrSetRepository.EXPECT().CreateRRSet(gomock.Any(), gomock.Any()).Return(conflict),
+			rrSetRepository.EXPECT().FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(nil, repository.ErrRRSetNotFound),
+			rrSetRepository.EXPECT().FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(&stackitdnsclient.RecordSet{Id: "other"}, nil),
+			rrSetRepository.EXPECT().UpdateRRSet(gomock.Any(), hasKey("key")).Return(conflict),
+			rrSetRepository.EXPECT().FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(&stackitdnsclient.RecordSet{Id: "other"}, nil),
+			rrSetRepository.EXPECT().UpdateRRSet(gomock.Any(), hasKey("key")).Return(nil),
+		)
+
+		err := newRRSetStateTestResolver().handleRRSetNotFound(&initResolverContextResult{
+			rrSetRepository: rrSetRepository,
+			rrSetName:       "_acme-challenge.test.com.",
+		}, "key")
+		require.NoError(t, err)
+	})
+
+	t.Run("gives up after the maximum attempts", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+		rrSetRepository.EXPECT().ListRRSets(gomock.Any(), gomock.Any()).Return(rrSetSeq())
+		rrSetRepository.EXPECT().CreateRRSet(gomock.Any(), gomock.Any()).Return(conflict)
+		rrSetRepository.EXPECT().FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, repository.ErrRRSetNotFound).Times(maxCreateConflictAttempts)
+
+		err := newRRSetStateTestResolver().handleRRSetNotFound(&initResolverContextResult{
+			rrSetRepository: rrSetRepository,
+			rrSetName:       "_acme-challenge.test.com.",
+		}, "key")
+		require.ErrorIs(t, err, repository.ErrRRSetNotFound)
+		require.ErrorContains(t, err, "failed after 3 attempts")
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+		rrSetRepository.EXPECT().ListRRSets(gomock.Any(), gomock.Any()).Return(rrSetSeq())
+		rrSetRepository.EXPECT().CreateRRSet(gomock.Any(), gomock.Any()).
+			Return(fmt.Errorf("create record set: %w", repository.ErrValidation))
+
+		err := newRRSetStateTestResolver().handleRRSetNotFound(&initResolverContextResult{
+			rrSetRepository: rrSetRepository,
+			rrSetName:       "_acme-challenge.test.com.",
+		}, "key")
+		require.ErrorIs(t, err, repository.ErrValidation)
+	})
+}
@@ -0,0 +1,298 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Names of the built-in credential providers, usable as `authMethod` or in
+// `authMethods` of the issuer config.
+const (
+	AuthMethodServiceAccountKeyFile   = "serviceAccountKeyFile"
+	AuthMethodServiceAccountKeySecret = "serviceAccountKeySecret"
+	AuthMethodVault                   = "vault"
+	AuthMethodAuthTokenEnv            = "authTokenEnv"
+	AuthMethodAuthTokenFile           = "authTokenFile"
+	AuthMethodAuthTokenSecret         = "authTokenSecret"
+)
+
+// defaultAuthMethods is the chain used when the issuer config selects
+// nothing. It keeps the historic precedence: service account key, then the
+// STACKIT_AUTH_TOKEN environment variable, then the token Secret.
+var defaultAuthMethods = []string{
+	AuthMethodServiceAccountKeyFile,
+	AuthMethodServiceAccountKeySecret,
+	AuthMethodVault,
+	AuthMethodAuthTokenEnv,
+	AuthMethodAuthTokenFile,
+	AuthMethodAuthTokenSecret,
+}
+
+// ErrCredentialNotConfigured is returned by a CredentialProvider that has
+// nothing to offer for the given config, so the chain moves on.
+var ErrCredentialNotConfigured = errors.New("credential not configured")
+
+// Credential is what a CredentialProvider hands to the repositories. Either
+// AuthToken or one of the service account key fields is set. Origin names
+// where the credential was read from, e.g. a file path or Secret reference.
+type Credential struct {
+	AuthToken string
+	SaKeyPath string
+	SaKey     string
+	Origin    string
+}
+
+func (c Credential) isServiceAccountKey() bool {
+	return c.SaKey != "" || c.SaKeyPath != ""
+}
+
+// CredentialProvider loads the credential used to talk to the STACKIT DNS API.
+type CredentialProvider interface {
+	Name() string
+	Credential(cfg *StackitDnsProviderConfig) (Credential, error)
+}
+
+// credentialProviders returns the built-in providers by name. They are built
+// per call because the secretFetcher is swapped during Initialize and
+// bypassed when credentials are refreshed.
+func (s *stackitDnsProviderResolver) credentialProviders(
+	secretFetcher SecretFetcher,
+) map[string]CredentialProvider {
+	providers := []CredentialProvider{
+		&saKeyFileProvider{credentials: s.credentials, logger: s.logger},
+		&saKeySecretProvider{secretFetcher: secretFetcher, credentials: s.credentials},
+		&vaultProvider{
+			httpClient:  s.httpClient,
+			credentials: s.credentials,
+			address:     os.Getenv("VAULT_ADDR"),
+			token:       os.Getenv("VAULT_TOKEN"),
+			tokenPath:   os.Getenv("VAULT_TOKEN_PATH"),
+		},
+		&envTokenProvider{token: s.authToken},
+		&tokenFileProvider{
+			credentials: s.credentials,
+			defaultPath: s.authTokenPath,
+			allowedDir:  s.authTokenDir,
+		},
+		&secretTokenProvider{secretFetcher: secretFetcher, credentials: s.credentials},
+	}
+
+	byName := make(map[string]CredentialProvider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
+	return byName
+}
+
+// resolveCredential walks the configured provider chain and returns the first
+// credential found. An explicit `authMethod` must be configured; any error
+// other than ErrCredentialNotConfigured stops the chain.
+func (s *stackitDnsProviderResolver) resolveCredential(
+	cfg *StackitDnsProviderConfig,
+	secretFetcher SecretFetcher,
+) (Credential, string, error) {
+	providers := s.credentialProviders(secretFetcher)
+
+	for _, name := range authMethodChain(cfg) {
+		provider, ok := providers[name]
+		if !ok {
+			return Credential{}, "", fmt.Errorf("unknown auth method %q", name)
+		}
+
+		credential, err := provider.Credential(cfg)
+		if errors.Is(err, ErrCredentialNotConfigured) && cfg.AuthMethod == "" {
+			continue
+		}
+		if err != nil {
+			return Credential{}, "", fmt.Errorf("auth method %q: %w", name, err)
+		}
+
+		s.logger.Info("Using credential provider", zap.String("authMethod", name))
+		metrics.CredentialProviderSelections.WithLabelValues(name).Inc()
+
+		return credential, name, nil
+	}
+
+	return Credential{}, "", fmt.Errorf("no credential found, tried %s", strings.Join(authMethodChain(cfg), ", "))
+}
+
+func authMethodChain(cfg *StackitDnsProviderConfig) []string {
+	switch {
+	case cfg.AuthMethod != "":
+		return []string{cfg.AuthMethod}
+	case len(cfg.AuthMethods) > 0:
+		return cfg.AuthMethods
+	default:
+		return defaultAuthMethods
+	}
+}
+
+func isKnownAuthMethod(name string) bool {
+	return slices.Contains(defaultAuthMethods, name)
+}
+
+// envTokenProvider uses the STACKIT_AUTH_TOKEN environment variable.
+type envTokenProvider struct {
+	token string
+}
+
+func (p *envTokenProvider) Name() string { return AuthMethodAuthTokenEnv }
+
+func (p *envTokenProvider) Credential(*StackitDnsProviderConfig) (Credential, error) {
+	if p.token == "" {
+		return Credential{}, ErrCredentialNotConfigured
+	}
+
+	return Credential{AuthToken: p.token, Origin: "STACKIT_AUTH_TOKEN"}, nil
+}
+
+// tokenFileProvider reads a bearer token from a mounted file. An issuer may
+// only pick defaultPath (STACKIT_AUTH_TOKEN_PATH) or a file below allowedDir
+// (STACKIT_AUTH_TOKEN_DIR), so it cannot send arbitrary pod files, e.g. the
+// service account token, to its apiBasePath.
+type tokenFileProvider struct {
+	credentials *credentialWatcher
+	defaultPath string
+	allowedDir  string
+}
+
+func (p *tokenFileProvider) Name() string { return AuthMethodAuthTokenFile }
+
+func (p *tokenFileProvider) Credential(cfg *StackitDnsProviderConfig) (Credential, error) {
+	path := cfg.AuthTokenPath
+	if path == "" {
+		path = p.defaultPath
+	}
+	if path == "" {
+		return Credential{}, ErrCredentialNotConfigured
+	}
+	if !p.permits(path) {
+		return Credential{}, fmt.Errorf("authTokenPath %q is neither STACKIT_AUTH_TOKEN_PATH nor below STACKIT_AUTH_TOKEN_DIR", path)
+	}
+
+	token, err := p.credentials.fileContent(path, credentialSourceAuthTokenFile)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	return Credential{AuthToken: strings.TrimSpace(token), Origin: path}, nil
+}
+
+func (p *tokenFileProvider) permits(path string) bool {
+	if path == p.defaultPath {
+		return true
+	}
+	if p.allowedDir == "" {
+		return false
+	}
+
+	rel, err := filepath.Rel(p.allowedDir, path)
+
+	return err == nil && rel != "." && filepath.IsLocal(rel)
+}
+
+// saKeyFileProvider uses a mounted service account key.
+type saKeyFileProvider struct {
+	credentials *credentialWatcher
+	logger      *zap.Logger
+}
+
+func (p *saKeyFileProvider) Name() string { return AuthMethodServiceAccountKeyFile }
+
+func (p *saKeyFileProvider) Credential(cfg *StackitDnsProviderConfig) (Credential, error) {
+	path := cfg.ServiceAccountKeyPath
+	if path == "" {
+		path = os.Getenv("STACKIT_SERVICE_ACCOUNT_KEY_PATH")
+	}
+	if path == "" {
+		return Credential{}, ErrCredentialNotConfigured
+	}
+
+	saKey, err := p.credentials.fileContent(path, credentialSourceSaKeyFile)
+	if err != nil {
+		// Leave it to the SDK to report an unreadable key path.
+		p.logger.Warn("Error reading service account key", zap.Error(err), zap.String("saKeyPath", path))
+	}
+
+	return Credential{SaKeyPath: path, SaKey: saKey, Origin: path}, nil
+}
+
+// saKeySecretProvider reads a service account key from a Kubernetes Secret.
+type saKeySecretProvider struct {
+	secretFetcher SecretFetcher
+	credentials   *credentialWatcher
+}
+
+func (p *saKeySecretProvider) Name() string { return AuthMethodServiceAccountKeySecret }
+
+func (p *saKeySecretProvider) Credential(cfg *StackitDnsProviderConfig) (Credential, error) {
+	if cfg.ServiceAccountKeySecretRef == "" {
+		return Credential{}, ErrCredentialNotConfigured
+	}
+
+	saKey, err := p.secretFetcher.StringFromSecret(
+		cfg.AuthTokenSecretNamespace,
+		cfg.ServiceAccountKeySecretRef,
+		cfg.ServiceAccountKeySecretKey,
+	)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	p.credentials.observeSecret(
+		cfg.AuthTokenSecretNamespace,
+		cfg.ServiceAccountKeySecretRef,
+		cfg.ServiceAccountKeySecretKey,
+		saKey,
+	)
+
+	return Credential{SaKey: saKey, Origin: secretOrigin(
+		cfg.AuthTokenSecretNamespace,
+		cfg.ServiceAccountKeySecretRef,
+		cfg.ServiceAccountKeySecretKey,
+	)}, nil
+}
+
+// secretTokenProvider reads a bearer token from a Kubernetes Secret.
+type secretTokenProvider struct {
+	secretFetcher SecretFetcher
+	credentials   *credentialWatcher
+}
+
+func (p *secretTokenProvider) Name() string { return AuthMethodAuthTokenSecret }
+
+func (p *secretTokenProvider) Credential(cfg *StackitDnsProviderConfig) (Credential, error) {
+	token, err := p.secretFetcher.StringFromSecret(
+		cfg.AuthTokenSecretNamespace,
+		cfg.AuthTokenSecretRef,
+		cfg.AuthTokenSecretKey,
+	)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	p.credentials.observeSecret(
+		cfg.AuthTokenSecretNamespace,
+		cfg.AuthTokenSecretRef,
+		cfg.AuthTokenSecretKey,
+		token,
+	)
+
+	return Credential{AuthToken: token, Origin: secretOrigin(
+		cfg.AuthTokenSecretNamespace,
+		cfg.AuthTokenSecretRef,
+		cfg.AuthTokenSecretKey,
+	)}, nil
+}
+
+func secretOrigin(namespace, name, key string) string {
+	return "secret:" + namespace + "/" + name + "/" + key
+}
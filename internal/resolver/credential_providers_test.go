@@ -0,0 +1,216 @@
+package resolver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/metrics"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type staticSecretFetcher map[string]string
+
+func (f staticSecretFetcher) StringFromSecret(namespace, secretName, key string) (string, error) {
+	value, ok := f[namespace+"/"+secretName+"/"+key]
+	if !ok {
+		return "", fmt.Errorf("secret %s/%s not found", namespace, secretName)
+	}
+
+	return value, nil
+}
+
+func newProviderTestResolver(t *testing.T, envToken string, secrets staticSecretFetcher) *stackitDnsProviderResolver {
+	t.Helper()
+
+	return &stackitDnsProviderResolver{
+		logger:        zap.NewNop(),
+		authToken:     envToken,
+		secretFetcher: secrets,
+		credentials:   newCredentialWatcher(zap.NewNop(), nil),
+	}
+}
+
+func TestResolveCredential(t *testing.T) {
+	t.Parallel()
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("file-token\n"), 0o600))
+
+	secrets := staticSecretFetcher{
+		"ns/stackit-cert-manager-webhook/auth-token": "secret-token",
+		"ns/sa/sa.json": `{"id":"key"}`,
+	}
+	baseCfg := StackitDnsProviderConfig{
+		AuthTokenSecretNamespace:   "ns",
+		AuthTokenSecretRef:         "stackit-cert-manager-webhook",
+		AuthTokenSecretKey:         "auth-token",
+		ServiceAccountKeySecretKey: "sa.json",
+	}
+
+	testCases := []struct {
+		name       string
+		envToken   string
+		cfg        func(cfg *StackitDnsProviderConfig)
+		wantMethod string
+		want       Credential
+		wantErr    string
+	}{
+		{
+			name:       "default chain falls back to the token secret",
+			cfg:        func(*StackitDnsProviderConfig) {},
+			wantMethod: AuthMethodAuthTokenSecret,
+			want: Credential{
+				AuthToken: "secret-token",
+				Origin:    "secret:ns/stackit-cert-manager-webhook/auth-token",
+			},
+		},
+		{
+			name:       "default chain prefers the environment token over the secret",
+			envToken:   "env-token",
+			cfg:        func(*StackitDnsProviderConfig) {},
+			wantMethod: AuthMethodAuthTokenEnv,
+			want:       Credential{AuthToken: "env-token", Origin: "STACKIT_AUTH_TOKEN"},
+		},
+		{
+			name:     "default chain prefers a service account key over the environment token",
+			envToken: "env-token",
+			cfg: func(cfg *StackitDnsProviderConfig) {
+				cfg.ServiceAccountKeySecretRef = "sa"
+			},
+			wantMethod: AuthMethodServiceAccountKeySecret,
+			want:       Credential{SaKey: `{"id":"key"}`, Origin: "secret:ns/sa/sa.json"},
+		},
+		{
+			name:     "explicit auth method wins",
+			envToken: "env-token",
+			cfg: func(cfg *StackitDnsProviderConfig) {
+				cfg.AuthMethod = AuthMethodAuthTokenFile
+				cfg.AuthTokenPath = tokenPath
+			},
+			wantMethod: AuthMethodAuthTokenFile,
+			want:       Credential{AuthToken: "file-token", Origin: tokenPath},
+		},
+		{
+			name: "explicit auth method must be configured",
+			cfg: func(cfg *StackitDnsProviderConfig) {
+				cfg.AuthMethod = AuthMethodAuthTokenEnv
+			},
+			wantErr: `auth method "authTokenEnv": credential not configured`,
+		},
+		{
+			name:     "ordered chain skips unconfigured providers",
+			envToken: "env-token",
+			cfg: func(cfg *StackitDnsProviderConfig) {
+				cfg.AuthMethods = []string{AuthMethodVault, AuthMethodAuthTokenSecret, AuthMethodAuthTokenEnv}
+			},
+			wantMethod: AuthMethodAuthTokenSecret,
+			want: Credential{
+				AuthToken: "secret-token",
+				Origin:    "secret:ns/stackit-cert-manager-webhook/auth-token",
+			},
+		},
+		{
+			name: "ordered chain stops on errors",
+			cfg: func(cfg *StackitDnsProviderConfig) {
+				cfg.AuthMethods = []string{AuthMethodServiceAccountKeySecret, AuthMethodAuthTokenSecret}
+				cfg.ServiceAccountKeySecretRef = "missing"
+			},
+			wantErr: "secret ns/missing not found",
+		},
+		{
+			name: "ordered chain without any credential",
+			cfg: func(cfg *StackitDnsProviderConfig) {
+				cfg.AuthMethods = []string{AuthMethodVault, AuthMethodAuthTokenEnv}
+			},
+			wantErr: "no credential found, tried vault, authTokenEnv",
+		},
+		{
+			name: "token file outside of the allowed directory",
+			cfg: func(cfg *StackitDnsProviderConfig) {
+				cfg.AuthMethod = AuthMethodAuthTokenFile
+				cfg.AuthTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+			},
+			wantErr: "is neither STACKIT_AUTH_TOKEN_PATH nor below STACKIT_AUTH_TOKEN_DIR",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := newProviderTestResolver(t, tc.envToken, secrets)
+			r.authTokenDir = filepath.Dir(tokenPath)
+			cfg := baseCfg
+			tc.cfg(&cfg)
+
+			credential, method, err := r.resolveCredential(&cfg, r.secretFetcher)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantMethod, method)
+			require.Equal(t, tc.want, credential)
+		})
+	}
+}
+
+func TestTokenFileProviderPermits(t *testing.T) {
+	t.Parallel()
+
+	provider := &tokenFileProvider{defaultPath: "/etc/stackit/token", allowedDir: "/var/run/secrets/stackit-tokens"}
+
+	require.True(t, provider.permits("/etc/stackit/token"))
+	require.True(t, provider.permits("/var/run/secrets/stackit-tokens/team-a"))
+	require.False(t, provider.permits("/var/run/secrets/stackit-tokens/../kubernetes.io/serviceaccount/token"))
+	require.False(t, provider.permits("/var/run/secrets/stackit-tokens"))
+	require.False(t, provider.permits("/etc/passwd"))
+
+	provider.allowedDir = ""
+	require.False(t, provider.permits("/var/run/secrets/stackit-tokens/team-a"))
+}
+
+func TestResolveCredential_ExportsSelectedProvider(t *testing.T) {
+	t.Parallel()
+
+	r := newProviderTestResolver(t, "env-token", nil)
+	before := testutil.ToFloat64(metrics.CredentialProviderSelections.WithLabelValues(AuthMethodAuthTokenEnv))
+
+	_, method, err := r.resolveCredential(&StackitDnsProviderConfig{AuthMethod: AuthMethodAuthTokenEnv}, nil)
+	require.NoError(t, err)
+	require.Equal(t, AuthMethodAuthTokenEnv, method)
+	require.Equal(
+		t,
+		before+1,
+		testutil.ToFloat64(metrics.CredentialProviderSelections.WithLabelValues(AuthMethodAuthTokenEnv)),
+	)
+}
+
+func TestRefreshRepositoryConfig(t *testing.T) {
+	t.Parallel()
+
+	secrets := staticSecretFetcher{"ns/creds/auth-token": "token-v1"}
+	r := newProviderTestResolver(t, "", secrets)
+	cfg := &StackitDnsProviderConfig{
+		ProjectId:                "project",
+		AuthTokenSecretNamespace: "ns",
+		AuthTokenSecretRef:       "creds",
+		AuthTokenSecretKey:       "auth-token",
+	}
+
+	config, err := r.getRepositoryConfig(cfg)
+	require.NoError(t, err)
+	require.Equal(t, "token-v1", config.AuthToken)
+	require.NotNil(t, config.RefreshCredentials)
+
+	secrets["ns/creds/auth-token"] = "token-v2"
+
+	refreshed, err := config.RefreshCredentials()
+	require.NoError(t, err)
+	require.Equal(t, "token-v2", refreshed.AuthToken)
+}
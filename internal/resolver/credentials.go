@@ -0,0 +1,245 @@
+package resolver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/metrics"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	credentialSourceSaKeyFile      = "serviceAccountKeyFile"
+	credentialSourceAuthTokenFile  = "authTokenFile"
+	credentialSourceSecret         = "secret"
+	credentialSourceVaultTokenFile = "vaultTokenFile"
+
+	// credentialResyncInterval re-reads watched files even without a
+	// filesystem event, for volumes where inotify is unreliable.
+	credentialResyncInterval = time.Minute
+)
+
+// credentialWatcher keeps the current content of mounted credential files and
+// the fingerprint of credentials read from Secrets. Whenever one of them
+// changes the new value is swapped in and onRotate is called, so cached API
+// clients built from the old credentials can be dropped.
+type credentialWatcher struct {
+	logger   *zap.Logger
+	onRotate func(source string)
+
+	mu        sync.RWMutex
+	running   bool
+	fsWatcher *fsnotify.Watcher
+	files     map[string]watchedFile
+	secrets   map[string]string
+}
+
+type watchedFile struct {
+	source  string
+	content string
+}
+
+func newCredentialWatcher(logger *zap.Logger, onRotate func(source string)) *credentialWatcher {
+	return &credentialWatcher{
+		logger:   logger,
+		onRotate: onRotate,
+		files:    map[string]watchedFile{},
+		secrets:  map[string]string{},
+	}
+}
+
+// run watches the registered files until stopCh is closed.
+func (w *credentialWatcher) run(stopCh <-chan struct{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.running {
+		return nil
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	w.fsWatcher = fsWatcher
+	w.running = true
+	for path := range w.files {
+		w.addDirWatchLocked(path)
+	}
+
+	go w.loop(fsWatcher, stopCh)
+
+	return nil
+}
+
+func (w *credentialWatcher) loop(fsWatcher *fsnotify.Watcher, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(credentialResyncInterval)
+	defer ticker.Stop()
+	defer fsWatcher.Close()
+
+	for {
+		select {
+		case <-stopCh:
+			w.mu.Lock()
+			w.running = false
+			w.mu.Unlock()
+
+			return
+		case event, ok := <-fsWatcher.Events:
+			if !ok {
+				return
+			}
+			w.reloadDir(filepath.Dir(event.Name))
+		case err, ok := <-fsWatcher.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Error("Error watching credential files", zap.Error(err))
+		case <-ticker.C:
+			w.reloadAll()
+		}
+	}
+}
+
+// fileContent returns the current content of a credential file. Files are
+// registered for watching on first use. While the watcher is not running the
+// file is read on every call.
+func (w *credentialWatcher) fileContent(path, source string) (string, error) {
+	w.mu.RLock()
+	file, ok := w.files[path]
+	running := w.running
+	w.mu.RUnlock()
+
+	if ok && running {
+		return file.content, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	content := string(data)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if previous, known := w.files[path]; known && previous.content != content {
+		w.rotatedLocked(source, zap.String("path", path))
+	}
+	w.files[path] = watchedFile{source: source, content: content}
+	if w.running {
+		w.addDirWatchLocked(path)
+	}
+
+	return content, nil
+}
+
+// observeSecret records the value read from a Secret key. A value differing
+// from the previously observed one counts as a rotation.
+func (w *credentialWatcher) observeSecret(namespace, name, key, value string) {
+	ref := namespace + "/" + name + "/" + key
+	fingerprint := credentialFingerprint(value)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if previous, ok := w.secrets[ref]; ok && previous != fingerprint {
+		w.rotatedLocked(credentialSourceSecret, zap.String("secret", namespace+"/"+name))
+	}
+	w.secrets[ref] = fingerprint
+}
+
+func (w *credentialWatcher) reloadDir(dir string) {
+	w.mu.RLock()
+	paths := make([]string, 0, len(w.files))
+	for path := range w.files {
+		if filepath.Dir(path) == dir {
+			paths = append(paths, path)
+		}
+	}
+	w.mu.RUnlock()
+
+	for _, path := range paths {
+		w.reloadFile(path)
+	}
+}
+
+func (w *credentialWatcher) reloadAll() {
+	w.mu.RLock()
+	paths := make([]string, 0, len(w.files))
+	for path := range w.files {
+		paths = append(paths, path)
+	}
+	w.mu.RUnlock()
+
+	for _, path := range paths {
+		w.reloadFile(path)
+	}
+}
+
+func (w *credentialWatcher) reloadFile(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// Mounted Secrets are swapped via symlinks, the file can briefly
+		// vanish. Keep serving the last known content.
+		w.logger.Debug("Error re-reading credential file", zap.Error(err), zap.String("path", path))
+
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	file := w.files[path]
+	if file.content == string(data) {
+		return
+	}
+	file.content = string(data)
+	w.files[path] = file
+	w.rotatedLocked(file.source, zap.String("path", path))
+}
+
+func (w *credentialWatcher) addDirWatchLocked(path string) {
+	if err := w.fsWatcher.Add(filepath.Dir(path)); err != nil {
+		w.logger.Error("Error watching credential file", zap.Error(err), zap.String("path", path))
+	}
+}
+
+// secretUpdated is called by the Secret cache for every change it sees.
+func (w *credentialWatcher) secretUpdated(secret *corev1.Secret) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for key, value := range secret.Data {
+		ref := secret.Namespace + "/" + secret.Name + "/" + key
+		previous, ok := w.secrets[ref]
+		fingerprint := credentialFingerprint(string(value))
+		if !ok || previous == fingerprint {
+			continue
+		}
+		w.secrets[ref] = fingerprint
+		w.rotatedLocked(credentialSourceSecret, zap.String("secret", secret.Namespace+"/"+secret.Name))
+	}
+}
+
+func (w *credentialWatcher) rotatedLocked(source string, field zap.Field) {
+	w.logger.Info("Credential rotation detected", zap.String("source", source), field)
+	metrics.CredentialRotations.WithLabelValues(source).Inc()
+
+	if w.onRotate != nil {
+		w.onRotate(source)
+	}
+}
+
+func credentialFingerprint(value string) string {
+	sum := sha256.Sum256([]byte(value))
+
+	return hex.EncodeToString(sum[:])
+}
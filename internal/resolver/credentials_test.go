@@ -0,0 +1,97 @@
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCredentialWatcher_FileRotation(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sa.json")
+	require.NoError(t, os.WriteFile(path, []byte("key-v1"), 0o600))
+
+	var rotations atomic.Int32
+	w := newCredentialWatcher(zap.NewNop(), func(string) { rotations.Add(1) })
+
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	require.NoError(t, w.run(stopCh))
+
+	content, err := w.fileContent(path, credentialSourceSaKeyFile)
+	require.NoError(t, err)
+	require.Equal(t, "key-v1", content)
+
+	// Replace the file the way kubelet does: write elsewhere, then rename.
+	tmp := filepath.Join(dir, ".sa.json.tmp")
+	require.NoError(t, os.WriteFile(tmp, []byte("key-v2"), 0o600))
+	require.NoError(t, os.Rename(tmp, path))
+
+	require.Eventually(t, func() bool {
+		content, err := w.fileContent(path, credentialSourceSaKeyFile)
+
+		return err == nil && content == "key-v2"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, int32(1), rotations.Load())
+}
+
+func TestCredentialWatcher_NotRunningReadsFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("token-v1"), 0o600))
+
+	var rotations atomic.Int32
+	w := newCredentialWatcher(zap.NewNop(), func(string) { rotations.Add(1) })
+
+	content, err := w.fileContent(path, credentialSourceAuthTokenFile)
+	require.NoError(t, err)
+	require.Equal(t, "token-v1", content)
+
+	require.NoError(t, os.WriteFile(path, []byte("token-v2"), 0o600))
+
+	content, err = w.fileContent(path, credentialSourceAuthTokenFile)
+	require.NoError(t, err)
+	require.Equal(t, "token-v2", content)
+	require.Equal(t, int32(1), rotations.Load())
+
+	_, err = w.fileContent(filepath.Join(t.TempDir(), "missing"), credentialSourceAuthTokenFile)
+	require.Error(t, err)
+}
+
+func TestCredentialWatcher_SecretRotation(t *testing.T) {
+	t.Parallel()
+
+	var rotations atomic.Int32
+	w := newCredentialWatcher(zap.NewNop(), func(string) { rotations.Add(1) })
+
+	w.observeSecret("ns", "creds", "auth-token", "token-v1")
+	w.observeSecret("ns", "creds", "auth-token", "token-v1")
+	require.Equal(t, int32(0), rotations.Load())
+
+	// A watch event carrying a new value is a rotation.
+	w.secretUpdated(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "creds"},
+		Data: map[string][]byte{
+			"auth-token": []byte("token-v2"),
+			"unrelated":  []byte("ignored"),
+		},
+	})
+	require.Equal(t, int32(1), rotations.Load())
+
+	// Reading the value already seen through the watch is not.
+	w.observeSecret("ns", "creds", "auth-token", "token-v2")
+	require.Equal(t, int32(1), rotations.Load())
+
+	w.observeSecret("ns", "creds", "auth-token", "token-v3")
+	require.Equal(t, int32(2), rotations.Load())
+}
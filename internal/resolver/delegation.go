@@ -0,0 +1,394 @@
+package resolver
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const (
+	delegationCheckTimeout    = 30 * time.Second
+	delegationReportMaxAge    = 5 * time.Minute
+	delegationRecheckInterval = time.Minute
+)
+
+var (
+	ErrDelegationMissing  = errors.New("zone is not delegated by its parent")
+	ErrDelegationMismatch = errors.New("delegation by the parent does not match the nameservers of the zone")
+)
+
+// DelegationCheckConfig makes Present check that the parent zone delegates
+// the zone to the nameservers STACKIT assigned to it. ParentNameservers
+// overrides the nameservers of the parent zone to query, given as host or
+// host:port; by default they are looked up in DNS.
+type DelegationCheckConfig struct {
+	ParentNameservers []string `json:"parentNameservers"`
+}
+
+// DelegationReport is the result of a delegation check, as shown in the
+// delegation diagnostics report.
+type DelegationReport struct {
+	Zone             string    `json:"zone"`
+	ParentZone       string    `json:"parentZone,omitempty"`
+	ParentNameserver string    `json:"parentNameserver,omitempty"`
+	Expected         []string  `json:"expected,omitempty"`
+	Delegated        []string  `json:"delegated,omitempty"`
+	Missing          []string  `json:"missing,omitempty"`
+	Unexpected       []string  `json:"unexpected,omitempty"`
+	Error            string    `json:"error,omitempty"`
+	CheckedAt        time.Time `json:"checkedAt"`
+}
+
+// checkDelegation compares the NS records the parent zone serves for the zone
+// with the nameservers of the zone in the DNS API.
+func (s *stackitDnsProviderResolver) checkDelegation(
+	ctx context.Context,
+	initResolverRes *initResolverContextResult,
+	check *DelegationCheckConfig,
+) (DelegationReport, error) {
+	zone := dns.Fqdn(initResolverRes.zone.DnsName)
+	report := DelegationReport{Zone: initResolverRes.zone.DnsName, CheckedAt: time.Now()}
+
+	expected, err := apiNameservers(ctx, initResolverRes)
+	if err != nil {
+		return report, err
+	}
+	report.Expected = normalizeNameservers(expected)
+	if len(report.Expected) == 0 {
+		return report, fmt.Errorf("%w for zone %s", ErrNoNameservers, report.Zone)
+	}
+
+	parentZone, parentNameservers, err := s.parentNameservers(ctx, zone, check)
+	if err != nil {
+		return report, err
+	}
+	report.ParentZone = parentZone
+
+	delegated, parentNameserver, err := queryDelegation(ctx, parentNameservers, zone)
+	report.ParentNameserver = parentNameserver
+	if err != nil {
+		return report, err
+	}
+	report.Delegated = normalizeNameservers(delegated)
+
+	return report, compareDelegation(&report)
+}
+
+func compareDelegation(report *DelegationReport) error {
+	for _, nameserver := range report.Expected {
+		if !slices.Contains(report.Delegated, nameserver) {
+			report.Missing = append(report.Missing, nameserver)
+		}
+	}
+	for _, nameserver := range report.Delegated {
+		if !slices.Contains(report.Expected, nameserver) {
+			report.Unexpected = append(report.Unexpected, nameserver)
+		}
+	}
+
+	switch {
+	case len(report.Delegated) == 0:
+		return fmt.Errorf("%w: %s (asked %s) has no NS records for %s, expected %s",
+			ErrDelegationMissing, report.ParentZone, report.ParentNameserver, report.Zone,
+			strings.Join(report.Expected, ", "))
+	case len(report.Missing) > 0 || len(report.Unexpected) > 0:
+		return fmt.Errorf("%w: %s (asked %s) delegates %s to %s, expected %s",
+			ErrDelegationMismatch, report.ParentZone, report.ParentNameserver, report.Zone,
+			strings.Join(report.Delegated, ", "), strings.Join(report.Expected, ", "))
+	default:
+		return nil
+	}
+}
+
+// parentNameservers returns the closest enclosing zone that has NS records
+// and the addresses of its nameservers.
+func (s *stackitDnsProviderResolver) parentNameservers(
+	ctx context.Context,
+	zone string,
+	check *DelegationCheckConfig,
+) (string, []string, error) {
+	parent := parentName(zone)
+	if len(check.ParentNameservers) > 0 {
+		return parent, nameserverAddresses(check.ParentNameservers), nil
+	}
+
+	for ; parent != "."; parent = parentName(parent) {
+		records, err := s.nsResolver.LookupNS(ctx, parent)
+		if err != nil {
+			if dnsErr, ok := errors.AsType[*net.DNSError](err); ok && dnsErr.IsNotFound {
+				continue
+			}
+
+			return "", nil, fmt.Errorf("error looking up nameservers of %s: %w", parent, err)
+		}
+		if len(records) == 0 {
+			continue
+		}
+
+		hosts := make([]string, len(records))
+		for i, record := range records {
+			hosts[i] = record.Host
+		}
+
+		return parent, nameserverAddresses(hosts), nil
+	}
+
+	return "", nil, fmt.Errorf("no parent zone with nameservers found for %s", zone)
+}
+
+// queryDelegation asks the parent nameservers in turn for the NS records of
+// zone and returns those of the first one that answers. The delegation is
+// found in the authority section of a referral or, if the parent is also
+// authoritative for the zone, in the answer.
+func queryDelegation(ctx context.Context, parentNameservers []string, zone string) ([]string, string, error) {
+	var errs []error
+	for _, nameserver := range parentNameservers {
+		response, err := queryNameserver(ctx, nameserver, zone, dns.TypeNS)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", nameserver, err))
+
+			continue
+		}
+
+		var delegated []string
+		for _, record := range slices.Concat(response.Answer, response.Ns) {
+			if ns, ok := record.(*dns.NS); ok && strings.EqualFold(ns.Hdr.Name, zone) {
+				delegated = append(delegated, ns.Ns)
+			}
+		}
+
+		return delegated, nameserver, nil
+	}
+
+	return nil, "", fmt.Errorf("error querying parent nameservers for %s: %w", zone, errors.Join(errs...))
+}
+
+func parentName(name string) string {
+	labels := dns.SplitDomainName(name)
+	if len(labels) <= 1 {
+		return "."
+	}
+
+	return dns.Fqdn(strings.Join(labels[1:], "."))
+}
+
+func nameserverAddresses(hosts []string) []string {
+	addresses := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		address := nameserverAddress(host)
+		if !slices.Contains(addresses, address) {
+			addresses = append(addresses, address)
+		}
+	}
+
+	return addresses
+}
+
+func normalizeNameservers(hosts []string) []string {
+	normalized := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		host = dns.Fqdn(strings.ToLower(host))
+		if !slices.Contains(normalized, host) {
+			normalized = append(normalized, host)
+		}
+	}
+	slices.Sort(normalized)
+
+	return normalized
+}
+
+// delegationChecks caches the delegation reports of the zones Present
+// checked. A background loop checks a zone again once its report is older
+// than delegationReportMaxAge, so serving the diagnostics report never calls
+// the DNS API.
+type delegationChecks struct {
+	mu    sync.Mutex
+	zones map[string]delegationTarget
+}
+
+// delegationTarget holds what is needed to check a zone again. Repositories
+// are created from the solver config for every check, so credentials rotated
+// since Present are used.
+type delegationTarget struct {
+	key         string
+	config      *extapi.JSON
+	zoneDnsName string
+	report      DelegationReport
+}
+
+func newDelegationChecks() *delegationChecks {
+	return &delegationChecks{zones: map[string]delegationTarget{}}
+}
+
+func (d *delegationChecks) remember(target delegationTarget) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.zones[target.key] = target
+}
+
+// update stores the report of a new check unless Present checked the zone
+// again in the meantime.
+func (d *delegationChecks) update(key string, report DelegationReport) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	target, ok := d.zones[key]
+	if !ok || target.report.CheckedAt.After(report.CheckedAt) {
+		return
+	}
+	target.report = report
+	d.zones[key] = target
+}
+
+func (d *delegationChecks) targets() []delegationTarget {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	targets := slices.Collect(maps.Values(d.zones))
+	slices.SortFunc(targets, func(a, b delegationTarget) int {
+		return strings.Compare(a.zoneDnsName, b.zoneDnsName)
+	})
+
+	return targets
+}
+
+// delegationReport returns the cached delegation reports of the zones
+// checked by Present.
+func (s *stackitDnsProviderResolver) delegationReport() any {
+	targets := s.delegations.targets()
+	reports := make([]DelegationReport, len(targets))
+	for i, target := range targets {
+		reports[i] = target.report
+	}
+
+	return reports
+}
+
+// runDelegationRechecks checks stale delegation reports every interval until
+// stopCh is closed.
+func (s *stackitDnsProviderResolver) runDelegationRechecks(interval time.Duration, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			s.recheckStaleDelegations()
+		}
+	}
+}
+
+// recheckStaleDelegations checks the zones whose report is older than
+// delegationReportMaxAge again.
+func (s *stackitDnsProviderResolver) recheckStaleDelegations() {
+	for _, target := range s.delegations.targets() {
+		if time.Since(target.report.CheckedAt) >= delegationReportMaxAge {
+			s.delegations.update(target.key, s.recheckDelegation(target))
+		}
+	}
+}
+
+func (s *stackitDnsProviderResolver) recheckDelegation(target delegationTarget) DelegationReport {
+	ctx, cancel := context.WithTimeout(s.ctx, delegationCheckTimeout)
+	defer cancel()
+
+	report, err := s.checkDelegationTarget(ctx, target)
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	return report
+}
+
+// checkDelegationTarget loads the solver config and fetches the zone again
+// before checking its delegation.
+func (s *stackitDnsProviderResolver) checkDelegationTarget(
+	ctx context.Context,
+	target delegationTarget,
+) (DelegationReport, error) {
+	report := DelegationReport{Zone: target.zoneDnsName, CheckedAt: time.Now()}
+
+	cfg, err := s.configProvider.LoadConfig(target.config)
+	if err != nil {
+		return report, err
+	}
+	config, err := s.getRepositoryConfig(&cfg)
+	if err != nil {
+		return report, err
+	}
+	zoneRepository, err := s.zoneRepositoryFactory.NewZoneRepository(config)
+	if err != nil {
+		return report, err
+	}
+	zone, err := zoneRepository.FetchZone(ctx, target.zoneDnsName)
+	if err != nil {
+		return report, err
+	}
+	rrSetRepository, err := s.rrSetRepositoryFactory.NewRRSetRepository(config, zone.Id)
+	if err != nil {
+		return report, err
+	}
+
+	return s.checkDelegation(
+		ctx,
+		&initResolverContextResult{zone: zone, rrSetRepository: rrSetRepository},
+		cmp.Or(cfg.DelegationCheck, &DelegationCheckConfig{}),
+	)
+}
+
+// presentDelegationCheck fails Present early if the zone is not delegated to
+// its STACKIT nameservers, since the challenge could never succeed. The
+// report is kept for the delegation diagnostics report.
+func (s *stackitDnsProviderResolver) presentDelegationCheck(
+	ch *v1alpha1.ChallengeRequest,
+	initResolverRes *initResolverContextResult,
+) error {
+	ctx, cancel := context.WithTimeout(s.ctx, delegationCheckTimeout)
+	defer cancel()
+
+	report, err := s.checkDelegation(ctx, initResolverRes, initResolverRes.delegationCheck)
+	if err != nil {
+		report.Error = err.Error()
+	}
+	s.delegations.remember(delegationTarget{
+		key:         initResolverRes.projectId + "/" + initResolverRes.zoneId,
+		config:      ch.Config,
+		zoneDnsName: initResolverRes.zone.DnsName,
+		report:      report,
+	})
+
+	if err != nil {
+		s.logger.Error(
+			"Delegation check failed",
+			zap.Error(err),
+			zap.String("zoneDnsName", report.Zone),
+			zap.Strings("missing", report.Missing),
+			zap.Strings("unexpected", report.Unexpected),
+		)
+
+		return err
+	}
+
+	s.logger.Info(
+		"Delegation check passed",
+		zap.String("zoneDnsName", report.Zone),
+		zap.String("parentZone", report.ParentZone),
+	)
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package resolver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	repository_mock "github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository/mock"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+type staticConfigProvider StackitDnsProviderConfig
+
+func (p staticConfigProvider) LoadConfig(*extapi.JSON) (StackitDnsProviderConfig, error) {
+	return StackitDnsProviderConfig(p), nil
+}
+
+// stackitNameservers returns a repository whose zone test.com has the NS
+// records STACKIT assigns.
+func stackitNameservers(t *testing.T) repository.RRSetRepository {
+	t.Helper()
+
+	ctrl := gomock.NewController(t)
+	rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+	rrSetRepository.EXPECT().
+		ListRRSets(gomock.Any(), repository.RRSetListOptions{Name: "test.com.", Type: "NS", ActiveOnly: true}).
+		Return(rrSetSeq(stackitdnsclient.RecordSet{Records: []stackitdnsclient.Record{
+			{Content: "ns1.stackit.cloud."},
+			{Content: "ns2.stackit.zone."},
+		}})).
+		AnyTimes()
+
+	return rrSetRepository
+}
+
+func TestCheckDelegation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		delegated  map[string][]string
+		err        error
+		missing    []string
+		unexpected []string
+	}{
+		{
+			name:      "delegated",
+			delegated: map[string][]string{"test.com.": {"NS2.stackit.zone.", "ns1.stackit.cloud."}},
+		},
+		{
+			name:       "points elsewhere",
+			delegated:  map[string][]string{"test.com.": {"ns1.stackit.cloud.", "ns1.other.net."}},
+			err:        ErrDelegationMismatch,
+			missing:    []string{"ns2.stackit.zone."},
+			unexpected: []string{"ns1.other.net."},
+		},
+		{
+			name:    "missing",
+			err:     ErrDelegationMissing,
+			missing: []string{"ns1.stackit.cloud.", "ns2.stackit.zone."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			parent := startFakeNameserver(t, &fakeNameserver{ns: tt.delegated, referral: true})
+
+			s := propagationTestResolver()
+			report, err := s.checkDelegation(context.Background(), &initResolverContextResult{
+				rrSetRepository: stackitNameservers(t),
+				zone:            &stackitdnsclient.Zone{DnsName: "test.com"},
+			}, &DelegationCheckConfig{ParentNameservers: []string{parent}})
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				require.ErrorContains(t, err, parent)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, "com.", report.ParentZone)
+			require.Equal(t, parent, report.ParentNameserver)
+			require.Equal(t, []string{"ns1.stackit.cloud.", "ns2.stackit.zone."}, report.Expected)
+			require.Equal(t, tt.missing, report.Missing)
+			require.Equal(t, tt.unexpected, report.Unexpected)
+		})
+	}
+}
+
+func TestCheckDelegationParentUnreachable(t *testing.T) {
+	t.Parallel()
+
+	s := propagationTestResolver()
+	_, err := s.checkDelegation(context.Background(), &initResolverContextResult{
+		rrSetRepository: stackitNameservers(t),
+		zone:            &stackitdnsclient.Zone{DnsName: "test.com"},
+	}, &DelegationCheckConfig{ParentNameservers: []string{"127.0.0.1:1"}})
+	require.ErrorContains(t, err, "error querying parent nameservers for test.com.")
+}
+
+func TestParentNameservers(t *testing.T) {
+	t.Parallel()
+
+	// b.test.com is not a zone of its own, so the lookup walks up to test.com.
+	lookup := startFakeNameserver(t, &fakeNameserver{
+		ns: map[string][]string{"test.com.": {"ns1.test.com.", "ns2.test.com."}},
+	})
+	s := propagationTestResolver()
+	s.nsResolver = standInResolver(lookup)
+
+	parent, nameservers, err := s.parentNameservers(context.Background(), "a.b.test.com.", &DelegationCheckConfig{})
+	require.NoError(t, err)
+	require.Equal(t, "test.com.", parent)
+	require.ElementsMatch(t, []string{"ns1.test.com:53", "ns2.test.com:53"}, nameservers)
+
+	parent, nameservers, err = s.parentNameservers(context.Background(), "a.b.test.com.", &DelegationCheckConfig{
+		ParentNameservers: []string{"10.0.0.1", "10.0.0.2:5353"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "b.test.com.", parent)
+	require.Equal(t, []string{"10.0.0.1:53", "10.0.0.2:5353"}, nameservers)
+}
+
+func TestPresentFailsOnBrokenDelegation(t *testing.T) {
+	t.Parallel()
+
+	parentNameserver := &fakeNameserver{
+		ns:       map[string][]string{"test.com.": {"ns1.other.net."}},
+		referral: true,
+	}
+	parent := startFakeNameserver(t, parentNameserver)
+
+	// Present and the recheck of the stale report each create the
+	// repositories. No record set is fetched or written.
+	ctrl := gomock.NewController(t)
+	zoneRepository := repository_mock.NewMockZoneRepository(ctrl)
+	zoneRepository.EXPECT().
+		FetchZone(gomock.Any(), "test.com").
+		Return(&stackitdnsclient.Zone{Id: "zone", DnsName: "test.com"}, nil).
+		Times(2)
+	zoneRepositoryFactory := repository_mock.NewMockZoneRepositoryFactory(ctrl)
+	zoneRepositoryFactory.EXPECT().NewZoneRepository(gomock.Any()).Return(zoneRepository, nil).Times(2)
+	rrSetRepositoryFactory := repository_mock.NewMockRRSetRepositoryFactory(ctrl)
+	rrSetRepositoryFactory.EXPECT().NewRRSetRepository(gomock.Any(), "zone").Return(stackitNameservers(t), nil).Times(2)
+
+	solver := NewResolver(
+		&http.Client{},
+		zap.NewNop(),
+		zoneRepositoryFactory,
+		rrSetRepositoryFactory,
+		staticSecretFetcher{"//": "token"},
+		staticConfigProvider{DelegationCheck: &DelegationCheckConfig{ParentNameservers: []string{parent}}},
+	)
+	s, ok := solver.(*stackitDnsProviderResolver)
+	require.True(t, ok)
+
+	err := s.Present(&v1alpha1.ChallengeRequest{
+		Key:          validChallengeKey,
+		ResolvedZone: "test.com.",
+		ResolvedFQDN: "_acme-challenge.test.com.",
+	})
+	require.ErrorIs(t, err, ErrDelegationMismatch)
+
+	reports, ok := s.delegationReport().([]DelegationReport)
+	require.True(t, ok)
+	require.Len(t, reports, 1)
+	require.Equal(t, "test.com", reports[0].Zone)
+	require.Equal(t, []string{"ns1.other.net."}, reports[0].Unexpected)
+	require.Contains(t, reports[0].Error, ErrDelegationMismatch.Error())
+	// The report of Present is cached.
+	require.Equal(t, 1, parentNameserver.queryCount())
+
+	s.delegations.zones["/zone"] = delegationTarget{
+		key:         "/zone",
+		zoneDnsName: "test.com",
+		report:      DelegationReport{Zone: "test.com", CheckedAt: time.Now().Add(-delegationReportMaxAge)},
+	}
+	// Serving the report does not check stale zones, the background loop does.
+	reports, ok = s.delegationReport().([]DelegationReport)
+	require.True(t, ok)
+	require.Empty(t, reports[0].Unexpected)
+	require.Equal(t, 1, parentNameserver.queryCount())
+
+	s.recheckStaleDelegations()
+	reports, ok = s.delegationReport().([]DelegationReport)
+	require.True(t, ok)
+	require.Equal(t, []string{"ns1.other.net."}, reports[0].Unexpected)
+	require.WithinDuration(t, time.Now(), reports[0].CheckedAt, time.Minute)
+	require.Equal(t, 2, parentNameserver.queryCount())
+}
@@ -0,0 +1,115 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"go.uber.org/zap"
+)
+
+// fetchRRSetForWrite fetches the challenge record set for Present and
+// CleanUp. Reads leave duplicate TXT record sets of the name alone, e.g. those
+// left behind by concurrent creates, so they are merged here before writing to
+// them. Otherwise CleanUp could never remove the key from them.
+func (s *stackitDnsProviderResolver) fetchRRSetForWrite(
+	initResolverRes *initResolverContextResult,
+) (*stackitdnsclient.RecordSet, error) {
+	rrSet, err := initResolverRes.rrSetRepository.FetchRRSetForZone(
+		s.ctx,
+		initResolverRes.rrSetName,
+		typeTxtRecord,
+	)
+	if !errors.Is(err, repository.ErrAmbiguous) {
+		return rrSet, err
+	}
+
+	return s.mergeDuplicateRRSets(initResolverRes)
+}
+
+// mergeDuplicateRRSets moves the records of all active TXT record sets of the
+// challenge name into the one with the lowest ID and deletes the others.
+func (s *stackitDnsProviderResolver) mergeDuplicateRRSets(
+	initResolverRes *initResolverContextResult,
+) (*stackitdnsclient.RecordSet, error) {
+	rrSets, err := s.listActiveRRSets(initResolverRes)
+	if err != nil {
+		return nil, err
+	}
+	if len(rrSets) == 0 {
+		return nil, repository.ErrRRSetNotFound
+	}
+
+	ids := make([]string, len(rrSets))
+	for i := range rrSets {
+		if IsPersistentRRSet(initResolverRes.rrSetName, &rrSets[i]) {
+			return nil, fmt.Errorf("%w: not merging %s", ErrPersistentRecord, initResolverRes.rrSetName)
+		}
+		ids[i] = rrSets[i].Id
+	}
+
+	merged := mergeRRSetRecords(rrSets)
+	s.logger.Warn(
+		"Merging duplicate RRSets",
+		zap.String("rrSetName", initResolverRes.rrSetName),
+		zap.Strings("rrSetIds", ids),
+		zap.String("mergedInto", merged.Id),
+	)
+
+	if err := initResolverRes.rrSetRepository.UpdateRRSet(s.ctx, merged); err != nil {
+		return nil, fmt.Errorf("merging duplicate record sets %s into %s: %w", strings.Join(ids, ", "), merged.Id, err)
+	}
+	for _, duplicate := range rrSets[1:] {
+		err := initResolverRes.rrSetRepository.DeleteRRSet(s.ctx, duplicate.Id)
+		if err != nil && !errors.Is(err, repository.ErrRRSetNotFound) {
+			return nil, fmt.Errorf("deleting duplicate record set %s merged into %s: %w", duplicate.Id, merged.Id, err)
+		}
+	}
+
+	return &merged, nil
+}
+
+// listActiveRRSets returns the active TXT record sets of the challenge name,
+// ordered by ID.
+func (s *stackitDnsProviderResolver) listActiveRRSets(
+	initResolverRes *initResolverContextResult,
+) ([]stackitdnsclient.RecordSet, error) {
+	var rrSets []stackitdnsclient.RecordSet
+
+	listOptions := repository.RRSetListOptions{Name: initResolverRes.rrSetName, Type: typeTxtRecord, ActiveOnly: true}
+	for rrSet, err := range initResolverRes.rrSetRepository.ListRRSets(s.ctx, listOptions) {
+		if err != nil {
+			return nil, err
+		}
+		rrSets = append(rrSets, rrSet)
+	}
+
+	slices.SortFunc(rrSets, func(a, b stackitdnsclient.RecordSet) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	return rrSets, nil
+}
+
+// mergeRRSetRecords returns the first record set holding the records of all
+// of them. Records with the same value, however quoted, are kept once.
+func mergeRRSetRecords(rrSets []stackitdnsclient.RecordSet) stackitdnsclient.RecordSet {
+	merged := rrSets[0]
+	merged.Records = slices.Clone(merged.Records)
+
+	for _, duplicate := range rrSets[1:] {
+		for _, record := range duplicate.Records {
+			value := txtValue(record.Content)
+			if !slices.ContainsFunc(merged.Records, func(r stackitdnsclient.Record) bool {
+				return txtContentMatches(r.Content, value)
+			}) {
+				merged.Records = append(merged.Records, record)
+			}
+		}
+	}
+
+	return merged
+}
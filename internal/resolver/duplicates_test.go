@@ -0,0 +1,81 @@
+package resolver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	repository_mock "github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository/mock"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// recordContents matches record set a with records of the given contents.
+func recordContents(want ...string) gomock.Matcher {
+	return gomock.Cond(func(rrSet stackitdnsclient.RecordSet) bool {
+		got := make([]string, len(rrSet.Records))
+		for i, record := range rrSet.Records {
+			got[i] = record.Content
+		}
+
+		return rrSet.Id == "a" && fmt.Sprint(got) == fmt.Sprint(want)
+	})
+}
+
+func TestPresentChallengeKey_MergesDuplicateRRSets(t *testing.T) {
+	t.Parallel()
+
+	ambiguous := fmt.Errorf("%w: 2 TXT record sets", repository.ErrAmbiguous)
+
+	ctrl := gomock.NewController(t)
+	rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+	gomock.InOrder(
+		rrSetRepository.EXPECT().FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, ambiguous),
+		rrSetRepository.EXPECT().ListRRSets(gomock.Any(), repository.RRSetListOptions{
+			Name: "_acme-challenge.test.com.", Type: typeTxtRecord, ActiveOnly: true,
+		}).Return(rrSetSeq(
+			stackitdnsclient.RecordSet{Id: "b", Records: []stackitdnsclient.Record{
+				{Content: `"key-2"`}, {Content: "key-3"},
+			}},
+			stackitdnsclient.RecordSet{Id: "a", Records: []stackitdnsclient.Record{
+				{Content: "key-1"}, {Content: "key-2"},
+			}},
+		)),
+		// Differently quoted contents of the same value are kept once.
+		rrSetRepository.EXPECT().UpdateRRSet(gomock.Any(), recordContents("key-1", "key-2", "key-3")).Return(nil),
+		rrSetRepository.EXPECT().DeleteRRSet(gomock.Any(), "b").Return(nil),
+		rrSetRepository.EXPECT().UpdateRRSet(gomock.Any(), recordContents("key-1", "key-2", "key-3", "key")).Return(nil),
+	)
+
+	err := newRRSetStateTestResolver().presentChallengeKey(&initResolverContextResult{
+		rrSetRepository: rrSetRepository,
+		rrSetName:       "_acme-challenge.test.com.",
+	}, "key")
+	require.NoError(t, err)
+}
+
+func TestHandleRRSetCleanup_MergesDuplicateRRSets(t *testing.T) {
+	t.Parallel()
+
+	ambiguous := fmt.Errorf("%w: 2 TXT record sets", repository.ErrAmbiguous)
+
+	ctrl := gomock.NewController(t)
+	rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+	gomock.InOrder(
+		rrSetRepository.EXPECT().FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, ambiguous),
+		rrSetRepository.EXPECT().ListRRSets(gomock.Any(), gomock.Any()).Return(rrSetSeq(
+			stackitdnsclient.RecordSet{Id: "a", Records: []stackitdnsclient.Record{{Content: "key"}}},
+			stackitdnsclient.RecordSet{Id: "b", Records: []stackitdnsclient.Record{{Content: "key"}, {Content: "other"}}},
+		)),
+		rrSetRepository.EXPECT().UpdateRRSet(gomock.Any(), recordContents("key", "other")).Return(nil),
+		rrSetRepository.EXPECT().DeleteRRSet(gomock.Any(), "b").Return(nil),
+		rrSetRepository.EXPECT().UpdateRRSet(gomock.Any(), recordContents("other")).Return(nil),
+	)
+
+	err := newRRSetStateTestResolver().handleRRSetCleanup(&initResolverContextResult{
+		rrSetRepository: rrSetRepository,
+		rrSetName:       "_acme-challenge.test.com.",
+	}, "key")
+	require.NoError(t, err)
+}
@@ -0,0 +1,136 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	eventTimeout   = 5 * time.Second
+	eventComponent = "stackit-cert-manager-webhook"
+)
+
+var errChallengeNotFound = errors.New("no challenge with the key found in the resource namespace")
+
+// challengeResource is the cert-manager Challenge the events are recorded on.
+var challengeResource = schema.GroupVersionResource{
+	Group:    "acme.cert-manager.io",
+	Version:  "v1",
+	Resource: "challenges",
+}
+
+// challengeEvents records Kubernetes Events on the Challenge a request was
+// made for. The request names neither the Challenge nor its UID, so the
+// Challenge is looked up by its key in the resource namespace. That is the
+// namespace of the Challenge for Issuers; Challenges of ClusterIssuers are
+// not found and only logged about. A nil *challengeEvents, as before
+// Initialize, records nothing.
+type challengeEvents struct {
+	challenges dynamic.NamespaceableResourceInterface
+	events     corev1client.EventsGetter
+	instance   string
+	logger     *zap.Logger
+}
+
+func newChallengeEvents(
+	client dynamic.Interface,
+	events corev1client.EventsGetter,
+	instance string,
+	logger *zap.Logger,
+) *challengeEvents {
+	return &challengeEvents{
+		challenges: client.Resource(challengeResource),
+		events:     events,
+		instance:   instance,
+		logger:     logger,
+	}
+}
+
+// emit creates the Event, logging instead of failing the request if that is
+// not possible.
+func (e *challengeEvents) emit(ch *v1alpha1.ChallengeRequest, eventType, reason, message string) {
+	if e == nil || ch.ResourceNamespace == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+	defer cancel()
+
+	challenge, err := e.findChallenge(ctx, ch)
+	if err == nil {
+		_, err = e.events.Events(challenge.GetNamespace()).Create(ctx, e.event(challenge, eventType, reason, message),
+			metav1.CreateOptions{})
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, errChallengeNotFound) || apierrors.IsForbidden(err):
+		// ClusterIssuers, or namespaces not listed in challengeEvents.namespaces.
+		e.logger.Debug("Not recording event", zap.Error(err), zap.String("reason", reason),
+			zap.String("namespace", ch.ResourceNamespace))
+	default:
+		e.logger.Warn("Error recording event", zap.Error(err), zap.String("reason", reason),
+			zap.String("namespace", ch.ResourceNamespace))
+	}
+}
+
+func (e *challengeEvents) findChallenge(
+	ctx context.Context,
+	ch *v1alpha1.ChallengeRequest,
+) (*unstructured.Unstructured, error) {
+	challenges, err := e.challenges.Namespace(ch.ResourceNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range challenges.Items {
+		key, _, _ := unstructured.NestedString(challenges.Items[i].Object, "spec", "key")
+		if key == ch.Key {
+			return &challenges.Items[i], nil
+		}
+	}
+
+	return nil, errChallengeNotFound
+}
+
+func (e *challengeEvents) event(
+	challenge *unstructured.Unstructured,
+	eventType, reason, message string,
+) *corev1.Event {
+	now := metav1.Now()
+
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: challenge.GetName() + "-",
+			Namespace:    challenge.GetNamespace(),
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: challenge.GetAPIVersion(),
+			Kind:       challenge.GetKind(),
+			Namespace:  challenge.GetNamespace(),
+			Name:       challenge.GetName(),
+			UID:        challenge.GetUID(),
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Source: corev1.EventSource{
+			Component: eventComponent,
+			Host:      e.instance,
+		},
+	}
+}
@@ -0,0 +1,224 @@
+package resolver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/metrics"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultExpiryWarningThreshold is used when
+	// CREDENTIAL_EXPIRY_WARNING_THRESHOLD is not set.
+	defaultExpiryWarningThreshold = 7 * 24 * time.Hour
+	// expiryCheckInterval is how often observed credentials are checked
+	// again, so warnings keep coming while no challenge loads them.
+	expiryCheckInterval = time.Hour
+)
+
+var errNoExpiry = errors.New("credential carries no expiry")
+
+// credentialExpiry remembers when each loaded credential expires and warns
+// ahead of time, so an expiring key shows up before renewals start to fail.
+type credentialExpiry struct {
+	logger    *zap.Logger
+	threshold time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]CredentialExpiryReport
+}
+
+// CredentialExpiryReport is one entry of the credentials diagnostics report.
+type CredentialExpiryReport struct {
+	Source    string    `json:"source"`
+	Origin    string    `json:"origin"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	ExpiresIn string    `json:"expiresIn"`
+}
+
+func newCredentialExpiry(logger *zap.Logger, threshold time.Duration) *credentialExpiry {
+	return &credentialExpiry{
+		logger:    logger,
+		threshold: threshold,
+		now:       time.Now,
+		entries:   map[string]CredentialExpiryReport{},
+	}
+}
+
+// observe parses the expiry of a credential, records it and warns when it is
+// close.
+func (e *credentialExpiry) observe(source string, credential Credential) {
+	expiresAt, err := credentialExpiresAt(credential)
+	if err != nil {
+		if !errors.Is(err, errNoExpiry) {
+			e.logger.Debug("Error parsing credential expiry", zap.Error(err), zap.String("origin", credential.Origin))
+		}
+
+		return
+	}
+
+	metrics.CredentialExpiry.WithLabelValues(source, credential.Origin).Set(float64(expiresAt.Unix()))
+
+	entry := CredentialExpiryReport{
+		Source:    source,
+		Origin:    credential.Origin,
+		ExpiresAt: expiresAt,
+	}
+
+	e.mu.Lock()
+	e.entries[source+"|"+credential.Origin] = entry
+	e.mu.Unlock()
+
+	e.check(entry)
+}
+
+// run checks the observed credentials every interval until stopCh is closed.
+func (e *credentialExpiry) run(interval time.Duration, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			e.checkAll()
+		}
+	}
+}
+
+func (e *credentialExpiry) checkAll() {
+	e.mu.Lock()
+	entries := slices.Collect(maps.Values(e.entries))
+	e.mu.Unlock()
+
+	for _, entry := range entries {
+		e.check(entry)
+	}
+}
+
+// check warns when a credential is close to its expiry and logs an error once
+// it has expired.
+func (e *credentialExpiry) check(entry CredentialExpiryReport) {
+	remaining := entry.ExpiresAt.Sub(e.now())
+	fields := []zap.Field{
+		zap.String("authMethod", entry.Source),
+		zap.String("origin", entry.Origin),
+		zap.Time("expiresAt", entry.ExpiresAt),
+	}
+
+	switch {
+	case remaining <= 0:
+		e.logger.Error("Credential has expired", fields...)
+	case remaining <= e.threshold:
+		e.logger.Warn("Credential expires soon", append(fields, zap.Duration("expiresIn", remaining))...)
+	}
+}
+
+// expiryWarningThreshold reads CREDENTIAL_EXPIRY_WARNING_THRESHOLD, e.g.
+// "72h".
+func expiryWarningThreshold(logger *zap.Logger) time.Duration {
+	value := os.Getenv("CREDENTIAL_EXPIRY_WARNING_THRESHOLD")
+	if value == "" {
+		return defaultExpiryWarningThreshold
+	}
+
+	threshold, err := time.ParseDuration(value)
+	if err != nil {
+		logger.Warn(
+			"Invalid CREDENTIAL_EXPIRY_WARNING_THRESHOLD, using default",
+			zap.Error(err),
+			zap.Duration("default", defaultExpiryWarningThreshold),
+		)
+
+		return defaultExpiryWarningThreshold
+	}
+
+	return threshold
+}
+
+// report lists the observed credentials, soonest expiry first.
+func (e *credentialExpiry) report() any {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	reports := make([]CredentialExpiryReport, 0, len(e.entries))
+	for _, entry := range e.entries {
+		entry.ExpiresIn = entry.ExpiresAt.Sub(now).Round(time.Second).String()
+		reports = append(reports, entry)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].ExpiresAt.Before(reports[j].ExpiresAt)
+	})
+
+	return reports
+}
+
+func credentialExpiresAt(credential Credential) (time.Time, error) {
+	switch {
+	case credential.SaKey != "":
+		return serviceAccountKeyExpiresAt(credential.SaKey)
+	case credential.AuthToken != "":
+		return jwtExpiresAt(credential.AuthToken)
+	default:
+		return time.Time{}, errNoExpiry
+	}
+}
+
+// serviceAccountKeyExpiresAt reads `validUntil` of a STACKIT service account
+// key (sa.json).
+func serviceAccountKeyExpiresAt(saKey string) (time.Time, error) {
+	var key struct {
+		ValidUntil string `json:"validUntil"`
+	}
+	if err := json.Unmarshal([]byte(saKey), &key); err != nil {
+		return time.Time{}, err
+	}
+	if key.ValidUntil == "" {
+		return time.Time{}, errNoExpiry
+	}
+
+	return time.Parse(time.RFC3339, key.ValidUntil)
+}
+
+// jwtExpiresAt reads the `exp` claim of a JWT without verifying it. Tokens
+// that are not JWTs carry no expiry.
+func jwtExpiresAt(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errNoExpiry
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var claims struct {
+		Exp *json.Number `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, err
+	}
+	if claims.Exp == nil {
+		return time.Time{}, errNoExpiry
+	}
+
+	exp, err := claims.Exp.Int64()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(exp, 0).UTC(), nil
+}
@@ -0,0 +1,139 @@
+package resolver
+
+import (
+	"encoding/base64"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/metrics"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func testJWT(payload string) string {
+	return "eyJhbGciOiJSUzUxMiJ9." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".signature"
+}
+
+func TestCredentialExpiresAt(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		credential Credential
+		want       time.Time
+		wantErr    error
+	}{
+		{
+			name:       "service account key",
+			credential: Credential{SaKey: `{"id":"key","validUntil":"2026-11-01T10:00:00Z"}`},
+			want:       time.Date(2026, 11, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "service account key without validUntil",
+			credential: Credential{SaKey: `{"id":"key"}`},
+			wantErr:    errNoExpiry,
+		},
+		{
+			name:       "jwt",
+			credential: Credential{AuthToken: testJWT(`{"exp":1793527200}`)},
+			want:       time.Unix(1793527200, 0).UTC(),
+		},
+		{
+			name:       "jwt without exp",
+			credential: Credential{AuthToken: testJWT(`{"sub":"sa"}`)},
+			wantErr:    errNoExpiry,
+		},
+		{
+			name:       "opaque token",
+			credential: Credential{AuthToken: "token"},
+			wantErr:    errNoExpiry,
+		},
+		{
+			name:       "key path only",
+			credential: Credential{SaKeyPath: "/path/sa.json"},
+			wantErr:    errNoExpiry,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := credentialExpiresAt(tt.credential)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tt.want.Equal(got), "got %s", got)
+		})
+	}
+}
+
+func TestCredentialExpiry_Observe(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	core, logs := observer.New(zapcore.InfoLevel)
+	expiry := newCredentialExpiry(zap.New(core), 72*time.Hour)
+	expiry.now = func() time.Time { return now }
+
+	soon := now.Add(24 * time.Hour)
+	expiry.observe(AuthMethodServiceAccountKeySecret, Credential{
+		SaKey:  `{"validUntil":"` + soon.Format(time.RFC3339) + `"}`,
+		Origin: "secret:ns/expiry-test/sa.json",
+	})
+	require.Equal(t, 1, logs.FilterMessage("Credential expires soon").Len())
+	require.InDelta(t, float64(soon.Unix()), testutil.ToFloat64(
+		metrics.CredentialExpiry.WithLabelValues(AuthMethodServiceAccountKeySecret, "secret:ns/expiry-test/sa.json"),
+	), 0)
+
+	later := now.Add(30 * 24 * time.Hour)
+	expiry.observe(AuthMethodAuthTokenFile, Credential{
+		AuthToken: testJWT(`{"exp":` + strconv.FormatInt(later.Unix(), 10) + `}`),
+		Origin:    "/expiry-test/token",
+	})
+	expiry.observe(AuthMethodAuthTokenEnv, Credential{
+		AuthToken: testJWT(`{"exp":1}`),
+		Origin:    "STACKIT_AUTH_TOKEN",
+	})
+	require.Equal(t, 1, logs.FilterMessage("Credential expires soon").Len())
+	require.Equal(t, 1, logs.FilterMessage("Credential has expired").Len())
+
+	report, ok := expiry.report().([]CredentialExpiryReport)
+	require.True(t, ok)
+	require.Len(t, report, 3)
+	require.Equal(t, "STACKIT_AUTH_TOKEN", report[0].Origin)
+	require.Equal(t, "secret:ns/expiry-test/sa.json", report[1].Origin)
+	require.Equal(t, "24h0m0s", report[1].ExpiresIn)
+}
+
+func TestCredentialExpiry_Run(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	core, logs := observer.New(zapcore.InfoLevel)
+	expiry := newCredentialExpiry(zap.New(core), 72*time.Hour)
+	expiry.now = func() time.Time { return now }
+
+	expiry.observe(AuthMethodAuthTokenFile, Credential{
+		AuthToken: testJWT(`{"exp":` + strconv.FormatInt(now.Add(24*time.Hour).Unix(), 10) + `}`),
+		Origin:    "/expiry-run-test/token",
+	})
+	require.Equal(t, 1, logs.FilterMessage("Credential expires soon").Len())
+
+	// Without any further challenge the credential is checked again.
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	expiry.now = func() time.Time { return now.Add(48 * time.Hour) }
+	go expiry.run(time.Millisecond, stopCh)
+
+	require.Eventually(t, func() bool {
+		return logs.FilterMessage("Credential has expired").Len() > 0
+	}, time.Second, time.Millisecond)
+}
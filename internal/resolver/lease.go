@@ -0,0 +1,240 @@
+package resolver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationclient "k8s.io/client-go/kubernetes/typed/coordination/v1"
+)
+
+const (
+	defaultLeaseDuration      = 15 * time.Second
+	defaultLeaseWaitTimeout   = 30 * time.Second
+	defaultLeaseRetryInterval = 500 * time.Millisecond
+
+	leaseNamePrefix = "stackit-webhook-rrset-"
+)
+
+var ErrLeaseTimeout = errors.New("timed out waiting for record set lease")
+
+// leaseCoordinator serializes the read-modify-write of a record set across
+// webhook replicas with a coordination.k8s.io Lease per record set. A Lease
+// is renewed while held and can be taken over once it was not renewed for
+// longer than its duration, e.g. after the holder crashed.
+type leaseCoordinator struct {
+	leases        coordinationclient.LeaseInterface
+	identity      string
+	logger        *zap.Logger
+	leaseDuration time.Duration
+	waitTimeout   time.Duration
+	retryInterval time.Duration
+}
+
+func newLeaseCoordinator(
+	client coordinationclient.LeasesGetter,
+	namespace, identity string,
+	logger *zap.Logger,
+) *leaseCoordinator {
+	return &leaseCoordinator{
+		leases:        client.Leases(namespace),
+		identity:      identity,
+		logger:        logger,
+		leaseDuration: defaultLeaseDuration,
+		waitTimeout:   defaultLeaseWaitTimeout,
+		retryInterval: defaultLeaseRetryInterval,
+	}
+}
+
+// lockRRSet takes the Lease of the record set if coordination is enabled.
+func (s *stackitDnsProviderResolver) lockRRSet(initResolverRes *initResolverContextResult) (func(), error) {
+	if s.leases == nil {
+		return func() {}, nil
+	}
+
+	release, err := s.leases.acquire(s.ctx, initResolverRes.projectId, initResolverRes.zoneId, initResolverRes.rrSetName)
+	if err != nil {
+		s.logger.Error("Error acquiring record set lease", zap.Error(err), zap.String("rrSetName", initResolverRes.rrSetName))
+
+		return nil, err
+	}
+
+	return release, nil
+}
+
+// leaseIdentity names this replica in the Leases it holds.
+func leaseIdentity() string {
+	if podName := os.Getenv("POD_NAME"); podName != "" {
+		return podName
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "stackit-cert-manager-webhook"
+	}
+
+	return hostname
+}
+
+// leaseName derives the Lease name from the record set it guards.
+func leaseName(projectId, zoneId, rrSetName string) string {
+	sum := sha256.Sum256([]byte(projectId + "/" + zoneId + "/" + rrSetName))
+
+	return leaseNamePrefix + hex.EncodeToString(sum[:16])
+}
+
+// acquire blocks until the Lease for the record set is held or the wait
+// timeout passes. The returned function releases the Lease. Every acquisition
+// holds the Lease under its own identity, so concurrent challenges of one
+// replica exclude each other as well.
+func (c *leaseCoordinator) acquire(ctx context.Context, projectId, zoneId, rrSetName string) (func(), error) {
+	name := leaseName(projectId, zoneId, rrSetName)
+	identity := c.identity + "/" + rand.Text()
+	deadline := time.Now().Add(c.waitTimeout)
+
+	for {
+		lease, holder, err := c.tryAcquire(ctx, name, identity)
+		if err != nil {
+			return nil, err
+		}
+		if lease != nil {
+			return c.hold(lease), nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: lease %s for %s is held by %s", ErrLeaseTimeout, name, rrSetName, holder)
+		}
+
+		c.logger.Debug("Waiting for record set lease", zap.String("lease", name), zap.String("holder", holder))
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.retryInterval):
+		}
+	}
+}
+
+// tryAcquire creates or takes over the Lease for identity. It returns the held
+// Lease, or the current holder if someone else holds it.
+func (c *leaseCoordinator) tryAcquire(
+	ctx context.Context,
+	name, identity string,
+) (*coordinationv1.Lease, string, error) {
+	now := metav1.NewMicroTime(time.Now())
+
+	lease, err := c.leases.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease, err = c.leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &identity,
+				LeaseDurationSeconds: new(c.leaseDurationSeconds()),
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return nil, "another replica", nil
+		}
+
+		return lease, "", err
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	holder := ""
+	if lease.Spec.HolderIdentity != nil {
+		holder = *lease.Spec.HolderIdentity
+	}
+	if holder != "" && !leaseExpired(lease, now.Time) {
+		return nil, holder, nil
+	}
+	if holder != "" {
+		c.logger.Info("Taking over stale record set lease", zap.String("lease", name), zap.String("holder", holder))
+	}
+
+	lease.Spec.HolderIdentity = &identity
+	lease.Spec.LeaseDurationSeconds = new(c.leaseDurationSeconds())
+	lease.Spec.AcquireTime = &now
+	lease.Spec.RenewTime = &now
+
+	lease, err = c.leases.Update(ctx, lease, metav1.UpdateOptions{})
+	if apierrors.IsConflict(err) {
+		return nil, holder, nil
+	}
+
+	return lease, "", err
+}
+
+// leaseDurationSeconds rounds the lease duration up to whole seconds, of
+// which a Lease needs at least one.
+func (c *leaseCoordinator) leaseDurationSeconds() int32 {
+	return int32(max(1, math.Ceil(c.leaseDuration.Seconds())))
+}
+
+// hold renews the Lease until the returned function is called, which then
+// deletes it.
+func (c *leaseCoordinator) hold(lease *coordinationv1.Lease) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(c.leaseDuration / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				now := metav1.NewMicroTime(time.Now())
+				lease.Spec.RenewTime = &now
+
+				renewed, err := c.leases.Update(context.Background(), lease, metav1.UpdateOptions{})
+				if err != nil {
+					c.logger.Error("Error renewing record set lease", zap.Error(err), zap.String("lease", lease.Name))
+
+					continue
+				}
+				lease = renewed
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+
+		// Only delete the Lease if it was not taken over in the meantime.
+		err := c.leases.Delete(context.Background(), lease.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{ResourceVersion: &lease.ResourceVersion},
+		})
+		if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+			c.logger.Error("Error releasing record set lease", zap.Error(err), zap.String("lease", lease.Name))
+		}
+	}
+}
+
+func leaseExpired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+
+	expiry := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+
+	return now.After(expiry)
+}
@@ -0,0 +1,186 @@
+package resolver
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const leaseTestNamespace = "webhook"
+
+func newTestLeaseCoordinator(client *fake.Clientset, identity string) *leaseCoordinator {
+	c := newLeaseCoordinator(client.CoordinationV1(), leaseTestNamespace, identity, zap.NewNop())
+	c.waitTimeout = 200 * time.Millisecond
+	c.retryInterval = 10 * time.Millisecond
+
+	return c
+}
+
+func heldLease(name, holder string, renewed time.Time) *coordinationv1.Lease {
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: leaseTestNamespace},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       new(holder),
+			LeaseDurationSeconds: new(int32(15)),
+			RenewTime:            new(metav1.NewMicroTime(renewed)),
+		},
+	}
+}
+
+func TestLeaseName(t *testing.T) {
+	t.Parallel()
+
+	name := leaseName("project", "zone", "_acme-challenge.test.com.")
+	require.Equal(t, name, leaseName("project", "zone", "_acme-challenge.test.com."))
+	require.NotEqual(t, name, leaseName("project", "other-zone", "_acme-challenge.test.com."))
+	require.Len(t, name, len(leaseNamePrefix)+32)
+}
+
+func TestLeaseCoordinator_Acquire(t *testing.T) {
+	t.Parallel()
+
+	name := leaseName("project", "zone", "_acme-challenge.test.com.")
+
+	t.Run("creates and releases the lease", func(t *testing.T) {
+		t.Parallel()
+
+		client := fake.NewClientset()
+		release, err := newTestLeaseCoordinator(client, "replica-a").
+			acquire(context.Background(), "project", "zone", "_acme-challenge.test.com.")
+		require.NoError(t, err)
+
+		lease, err := client.CoordinationV1().Leases(leaseTestNamespace).Get(context.Background(), name, metav1.GetOptions{})
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(*lease.Spec.HolderIdentity, "replica-a/"))
+
+		release()
+		_, err = client.CoordinationV1().Leases(leaseTestNamespace).Get(context.Background(), name, metav1.GetOptions{})
+		require.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("times out while another replica holds the lease", func(t *testing.T) {
+		t.Parallel()
+
+		client := fake.NewClientset(heldLease(name, "replica-b", time.Now()))
+		_, err := newTestLeaseCoordinator(client, "replica-a").
+			acquire(context.Background(), "project", "zone", "_acme-challenge.test.com.")
+		require.ErrorIs(t, err, ErrLeaseTimeout)
+		require.ErrorContains(t, err, "held by replica-b")
+	})
+
+	t.Run("takes over a stale lease", func(t *testing.T) {
+		t.Parallel()
+
+		client := fake.NewClientset(heldLease(name, "replica-b", time.Now().Add(-time.Minute)))
+		release, err := newTestLeaseCoordinator(client, "replica-a").
+			acquire(context.Background(), "project", "zone", "_acme-challenge.test.com.")
+		require.NoError(t, err)
+		t.Cleanup(release)
+
+		lease, err := client.CoordinationV1().Leases(leaseTestNamespace).Get(context.Background(), name, metav1.GetOptions{})
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(*lease.Spec.HolderIdentity, "replica-a/"))
+	})
+
+	t.Run("waits for the holder to release the lease", func(t *testing.T) {
+		t.Parallel()
+
+		client := fake.NewClientset()
+		holder := newTestLeaseCoordinator(client, "replica-b")
+		release, err := holder.acquire(context.Background(), "project", "zone", "_acme-challenge.test.com.")
+		require.NoError(t, err)
+
+		var acquired atomic.Bool
+		done := make(chan error)
+		go func() {
+			releaseWaiter, err := newTestLeaseCoordinator(client, "replica-a").
+				acquire(context.Background(), "project", "zone", "_acme-challenge.test.com.")
+			if err == nil {
+				acquired.Store(true)
+				releaseWaiter()
+			}
+			done <- err
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		require.False(t, acquired.Load())
+		release()
+
+		require.NoError(t, <-done)
+		require.True(t, acquired.Load())
+	})
+}
+
+func TestLeaseCoordinator_AcquireSameIdentity(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewClientset()
+	c := newTestLeaseCoordinator(client, "replica-a")
+	release, err := c.acquire(context.Background(), "project", "zone", "_acme-challenge.test.com.")
+	require.NoError(t, err)
+
+	// A second challenge of the same replica waits as well.
+	var acquired atomic.Bool
+	done := make(chan error)
+	go func() {
+		releaseSecond, err := c.acquire(context.Background(), "project", "zone", "_acme-challenge.test.com.")
+		if err == nil {
+			acquired.Store(true)
+			releaseSecond()
+		}
+		done <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	require.False(t, acquired.Load())
+	release()
+
+	require.NoError(t, <-done)
+	require.True(t, acquired.Load())
+}
+
+func TestLeaseCoordinator_LeaseDurationSeconds(t *testing.T) {
+	t.Parallel()
+
+	c := newTestLeaseCoordinator(fake.NewClientset(), "replica-a")
+	for duration, want := range map[time.Duration]int32{
+		30 * time.Millisecond:   1,
+		1500 * time.Millisecond: 2,
+		15 * time.Second:        15,
+	} {
+		c.leaseDuration = duration
+		require.Equal(t, want, c.leaseDurationSeconds(), duration)
+	}
+}
+
+func TestLeaseCoordinator_Renews(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewClientset()
+	c := newTestLeaseCoordinator(client, "replica-a")
+	c.leaseDuration = 30 * time.Millisecond
+
+	release, err := c.acquire(context.Background(), "project", "zone", "_acme-challenge.test.com.")
+	require.NoError(t, err)
+	t.Cleanup(release)
+
+	name := leaseName("project", "zone", "_acme-challenge.test.com.")
+	lease, err := client.CoordinationV1().Leases(leaseTestNamespace).Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+	acquired := lease.Spec.RenewTime.Time
+
+	require.Eventually(t, func() bool {
+		lease, err := client.CoordinationV1().Leases(leaseTestNamespace).Get(context.Background(), name, metav1.GetOptions{})
+
+		return err == nil && lease.Spec.RenewTime.After(acquired)
+	}, time.Second, 10*time.Millisecond)
+}
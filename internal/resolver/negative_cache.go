@@ -0,0 +1,90 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	eventReasonPropagationDelay       = "PropagationDelay"
+	eventReasonPropagationDelayBudget = "PropagationDelayBudgetExceeded"
+)
+
+var ErrPropagationDelayBudget = errors.New("worst-case propagation delay exceeds propagationDelayBudget")
+
+// negativeCacheDelay is how long resolvers may keep answering NXDOMAIN for
+// the challenge record if it was queried before Present. Negative answers are
+// cached for the smaller of the SOA minimum, which is the zone's negative
+// cache TTL, and the TTL of the SOA record itself, which is the zone's default
+// TTL (RFC 2308, section 5).
+func negativeCacheDelay(zone *stackitdnsclient.Zone) time.Duration {
+	ttl := zone.NegativeCache
+	if zone.DefaultTTL > 0 && (ttl == 0 || zone.DefaultTTL < ttl) {
+		ttl = zone.DefaultTTL
+	}
+
+	return time.Duration(ttl) * time.Second
+}
+
+// checkPropagationDelay reports the worst-case delay caused by negative
+// caching and fails Present if it exceeds the configured budget, since the
+// ACME server would likely give up on the challenge before resolvers see it.
+// A delay within the budget is logged and recorded as an event once per
+// record set and delay, not on every retry of Present.
+func (s *stackitDnsProviderResolver) checkPropagationDelay(
+	ch *v1alpha1.ChallengeRequest,
+	initResolverRes *initResolverContextResult,
+) error {
+	zone := initResolverRes.zone
+	delay := negativeCacheDelay(zone)
+	if delay == 0 {
+		return nil
+	}
+
+	fields := []zap.Field{
+		zap.String("zoneDnsName", zone.DnsName),
+		zap.String("rrSetName", initResolverRes.rrSetName),
+		zap.Int32("negativeCache", zone.NegativeCache),
+		zap.Int32("defaultTTL", zone.DefaultTTL),
+		zap.Duration("worstCaseDelay", delay),
+	}
+
+	budget := initResolverRes.propagationDelayBudget
+	if budget > 0 && delay > budget {
+		err := fmt.Errorf("%w: resolvers may cache the absence of %s for up to %s (zone %s), budget is %s",
+			ErrPropagationDelayBudget, initResolverRes.rrSetName, delay, zone.DnsName, budget)
+		s.logger.Error("Propagation delay exceeds budget", append(fields, zap.Error(err))...)
+		s.events.emit(ch, corev1.EventTypeWarning, eventReasonPropagationDelayBudget, err.Error())
+
+		return err
+	}
+
+	if previous, loaded := s.loggedDelays.Swap(propagationDelayKey(initResolverRes), delay); loaded && previous == delay {
+		return nil
+	}
+
+	s.logger.Info("Resolvers may cache the absence of the challenge record", fields...)
+	s.events.emit(ch, corev1.EventTypeNormal, eventReasonPropagationDelay, fmt.Sprintf(
+		"Resolvers that queried %s before it was presented may not see it for up to %s "+
+			"(negative cache TTL of zone %s)",
+		initResolverRes.rrSetName, delay, zone.DnsName))
+
+	return nil
+}
+
+// forgetPropagationDelay lets the next Present for the record set report the
+// delay again. CleanUp calls it, so loggedDelays only holds record sets with
+// challenges in progress.
+func (s *stackitDnsProviderResolver) forgetPropagationDelay(initResolverRes *initResolverContextResult) {
+	s.loggedDelays.Delete(propagationDelayKey(initResolverRes))
+}
+
+func propagationDelayKey(initResolverRes *initResolverContextResult) string {
+	return initResolverRes.zoneId + "/" + initResolverRes.rrSetName
+}
@@ -0,0 +1,204 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newTestChallengeEvents returns challengeEvents backed by fake clients
+// holding a Challenge with key in namespace certs.
+func newTestChallengeEvents(t *testing.T, key string) (*challengeEvents, *fake.Clientset) {
+	t.Helper()
+
+	challenge := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "acme.cert-manager.io/v1",
+		"kind":       "Challenge",
+		"metadata":   map[string]any{"name": "cert-1-2-3", "namespace": "certs", "uid": "challenge-uid"},
+		"spec":       map[string]any{"key": key},
+	}}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{challengeResource: "ChallengeList"},
+		challenge,
+	)
+	client := fake.NewClientset()
+
+	return newChallengeEvents(dynamicClient, client.CoreV1(), "replica-a", zap.NewNop()), client
+}
+
+func TestNegativeCacheDelay(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		negativeCache int32
+		defaultTTL    int32
+		expected      time.Duration
+	}{
+		{name: "negative cache is smaller", negativeCache: 60, defaultTTL: 3600, expected: time.Minute},
+		{name: "default ttl is smaller", negativeCache: 3600, defaultTTL: 300, expected: 5 * time.Minute},
+		{name: "no default ttl", negativeCache: 900, expected: 15 * time.Minute},
+		{name: "unset", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			delay := negativeCacheDelay(&stackitdnsclient.Zone{NegativeCache: tt.negativeCache, DefaultTTL: tt.defaultTTL})
+			require.Equal(t, tt.expected, delay)
+		})
+	}
+}
+
+func TestCheckPropagationDelay(t *testing.T) {
+	t.Parallel()
+
+	ch := &v1alpha1.ChallengeRequest{Key: validChallengeKey, ResourceNamespace: "certs"}
+
+	tests := []struct {
+		name      string
+		budget    time.Duration
+		err       error
+		level     zapcore.Level
+		eventType string
+		reason    string
+	}{
+		{
+			name:      "no budget",
+			level:     zapcore.InfoLevel,
+			eventType: corev1.EventTypeNormal,
+			reason:    eventReasonPropagationDelay,
+		},
+		{
+			name:      "within budget",
+			budget:    time.Hour,
+			level:     zapcore.InfoLevel,
+			eventType: corev1.EventTypeNormal,
+			reason:    eventReasonPropagationDelay,
+		},
+		{
+			name:      "exceeds budget",
+			budget:    5 * time.Minute,
+			err:       ErrPropagationDelayBudget,
+			level:     zapcore.ErrorLevel,
+			eventType: corev1.EventTypeWarning,
+			reason:    eventReasonPropagationDelayBudget,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			core, logs := observer.New(zapcore.InfoLevel)
+			events, client := newTestChallengeEvents(t, validChallengeKey)
+			s := &stackitDnsProviderResolver{logger: zap.New(core), events: events}
+
+			err := s.checkPropagationDelay(ch, &initResolverContextResult{
+				zone:                   &stackitdnsclient.Zone{DnsName: "test.com", NegativeCache: 1800, DefaultTTL: 3600},
+				rrSetName:              "_acme-challenge.test.com.",
+				propagationDelayBudget: tt.budget,
+			})
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				require.ErrorContains(t, err, "30m0s")
+			} else {
+				require.NoError(t, err)
+			}
+
+			entries := logs.All()
+			require.Len(t, entries, 1)
+			require.Equal(t, tt.level, entries[0].Level)
+			require.Equal(t, "_acme-challenge.test.com.", entries[0].ContextMap()["rrSetName"])
+
+			recorded, err := client.CoreV1().Events("certs").List(context.Background(), metav1.ListOptions{})
+			require.NoError(t, err)
+			require.Len(t, recorded.Items, 1)
+			event := recorded.Items[0]
+			require.Equal(t, tt.eventType, event.Type)
+			require.Equal(t, tt.reason, event.Reason)
+			require.Contains(t, event.Message, "_acme-challenge.test.com.")
+			require.Equal(t, "Challenge", event.InvolvedObject.Kind)
+			require.Equal(t, "cert-1-2-3", event.InvolvedObject.Name)
+			require.Equal(t, "challenge-uid", string(event.InvolvedObject.UID))
+			require.Equal(t, "replica-a", event.Source.Host)
+		})
+	}
+}
+
+func TestCheckPropagationDelayWithoutChallenge(t *testing.T) {
+	t.Parallel()
+
+	// Challenges of ClusterIssuers are not in the resource namespace.
+	events, client := newTestChallengeEvents(t, "other-key")
+	s := &stackitDnsProviderResolver{logger: zap.NewNop(), events: events}
+
+	err := s.checkPropagationDelay(
+		&v1alpha1.ChallengeRequest{Key: validChallengeKey, ResourceNamespace: "certs"},
+		&initResolverContextResult{
+			zone:      &stackitdnsclient.Zone{DnsName: "test.com", NegativeCache: 1800},
+			rrSetName: "_acme-challenge.test.com.",
+		},
+	)
+	require.NoError(t, err)
+
+	recorded, err := client.CoreV1().Events("certs").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, recorded.Items)
+
+	// Before Initialize there are no Kubernetes clients to record events with.
+	s = &stackitDnsProviderResolver{logger: zap.NewNop()}
+	err = s.checkPropagationDelay(&v1alpha1.ChallengeRequest{ResourceNamespace: "certs"},
+		&initResolverContextResult{
+			zone:                   &stackitdnsclient.Zone{DnsName: "test.com", NegativeCache: 1800},
+			rrSetName:              "_acme-challenge.test.com.",
+			propagationDelayBudget: time.Minute,
+		})
+	require.ErrorIs(t, err, ErrPropagationDelayBudget)
+}
+
+func TestCheckPropagationDelayLogsOncePerRRSet(t *testing.T) {
+	t.Parallel()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	s := &stackitDnsProviderResolver{logger: zap.New(core)}
+	check := func(rrSetName string, negativeCache int32) {
+		require.NoError(t, s.checkPropagationDelay(&v1alpha1.ChallengeRequest{}, &initResolverContextResult{
+			zone:      &stackitdnsclient.Zone{DnsName: "test.com", NegativeCache: negativeCache},
+			zoneId:    "zone",
+			rrSetName: rrSetName,
+		}))
+	}
+
+	// Retries of Present log the delay once, until it changes.
+	check("_acme-challenge.test.com.", 1800)
+	check("_acme-challenge.test.com.", 1800)
+	require.Equal(t, 1, logs.Len())
+
+	check("_acme-challenge.test.com.", 600)
+	check("_acme-challenge.www.test.com.", 600)
+	require.Equal(t, 3, logs.Len())
+
+	// CleanUp drops the entry, so the next challenge reports the delay again.
+	s.forgetPropagationDelay(&initResolverContextResult{zoneId: "zone", rrSetName: "_acme-challenge.test.com."})
+	check("_acme-challenge.test.com.", 600)
+	require.Equal(t, 4, logs.Len())
+	_, ok := s.loggedDelays.Load("zone/_acme-challenge.test.com.")
+	require.True(t, ok)
+}
@@ -0,0 +1,156 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// persistLabel is the label of dns-persist-01 authorization records,
+	// _validation-persist.<domain>.
+	persistLabel = "_validation-persist"
+	// persistComment marks record sets written by the persist reconciler, so
+	// the webhook never adds challenge keys to them or removes them.
+	persistComment    = "This record set is managed by stackit-cert-manager-webhook (dns-persist-01)"
+	defaultPersistTTL = 3600
+
+	persistParamAccountURI   = "accounturi"
+	persistParamPolicy       = "policy"
+	persistParamPersistUntil = "persistuntil"
+	persistPolicyWildcard    = "wildcard"
+)
+
+var (
+	ErrPersistentRecord     = errors.New("record set holds persistent dns-persist-01 authorizations")
+	ErrInvalidAuthorization = errors.New("invalid dns-persist-01 authorization")
+)
+
+// PersistAuthorization is a single dns-persist-01 authorization, allowing the
+// CA IssuerDomain to issue for the domain to the ACME account AccountURI
+// without a challenge record per order.
+type PersistAuthorization struct {
+	IssuerDomain string `json:"issuerDomain"`
+	AccountURI   string `json:"accountURI"`
+	// Wildcard extends the authorization to wildcard names below the domain.
+	Wildcard bool `json:"wildcard,omitempty"`
+	// PersistUntil limits how long the CA may rely on the authorization.
+	PersistUntil *metav1.Time `json:"persistUntil,omitempty"`
+}
+
+// value returns the TXT record value, e.g.
+// `letsencrypt.org; accounturi=https://acme.test/acct/1; policy=wildcard`.
+func (a PersistAuthorization) value() string {
+	value := a.IssuerDomain + "; " + persistParamAccountURI + "=" + a.AccountURI
+	if a.Wildcard {
+		value += "; " + persistParamPolicy + "=" + persistPolicyWildcard
+	}
+	if a.PersistUntil != nil {
+		value += "; " + persistParamPersistUntil + "=" + strconv.FormatInt(a.PersistUntil.Unix(), 10)
+	}
+
+	return value
+}
+
+// sameAuthorizer reports whether a and b authorize the same account of the
+// same CA, so one replaces the other.
+func (a PersistAuthorization) sameAuthorizer(b PersistAuthorization) bool {
+	return strings.EqualFold(a.IssuerDomain, b.IssuerDomain) && a.AccountURI == b.AccountURI
+}
+
+// equal reports whether a and b authorize the same, however their record
+// values are written.
+func (a PersistAuthorization) equal(b PersistAuthorization) bool {
+	if !a.sameAuthorizer(b) || a.Wildcard != b.Wildcard || (a.PersistUntil == nil) != (b.PersistUntil == nil) {
+		return false
+	}
+
+	return a.PersistUntil == nil || a.PersistUntil.Unix() == b.PersistUntil.Unix()
+}
+
+func (a PersistAuthorization) expired(now time.Time) bool {
+	return a.PersistUntil != nil && a.PersistUntil.Time.Before(now)
+}
+
+func validatePersistAuthorization(a PersistAuthorization) error {
+	switch {
+	case a.IssuerDomain == "":
+		return fmt.Errorf("%w: issuerDomain must be specified", ErrInvalidAuthorization)
+	case a.AccountURI == "":
+		return fmt.Errorf("%w: accountURI must be specified", ErrInvalidAuthorization)
+	case strings.ContainsAny(a.IssuerDomain+a.AccountURI, "; \t"):
+		return fmt.Errorf("%w: issuerDomain and accountURI must not contain ';' or whitespace",
+			ErrInvalidAuthorization)
+	default:
+		return nil
+	}
+}
+
+// parsePersistAuthorization parses a TXT record value. Parameter names are
+// case-insensitive and unknown parameters are ignored.
+func parsePersistAuthorization(value string) (PersistAuthorization, error) {
+	fields := strings.Split(value, ";")
+	authorization := PersistAuthorization{IssuerDomain: strings.TrimSpace(fields[0])}
+
+	for _, field := range fields[1:] {
+		key, val, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return PersistAuthorization{}, fmt.Errorf("%w: parameter %q has no value", ErrInvalidAuthorization, field)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case persistParamAccountURI:
+			authorization.AccountURI = strings.TrimSpace(val)
+		case persistParamPolicy:
+			authorization.Wildcard = strings.EqualFold(strings.TrimSpace(val), persistPolicyWildcard)
+		case persistParamPersistUntil:
+			seconds, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64)
+			if err != nil {
+				return PersistAuthorization{}, fmt.Errorf("%w: persistUntil %q: %w", ErrInvalidAuthorization, val, err)
+			}
+			authorization.PersistUntil = new(metav1.NewTime(time.Unix(seconds, 0)))
+		}
+	}
+
+	if err := validatePersistAuthorization(authorization); err != nil {
+		return PersistAuthorization{}, err
+	}
+
+	return authorization, nil
+}
+
+// persistRRSetName returns the canonical name of the authorization record
+// set of domain, which must be inside zone.
+func persistRRSetName(domain, zone string) (string, error) {
+	zone, err := repository.CanonicalZoneName(zone)
+	if err != nil {
+		return "", err
+	}
+
+	name, err := repository.CanonicalRRSetName(persistLabel+"."+strings.TrimSuffix(domain, ".")+".", zone)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(name, "."+zone+".") {
+		return "", fmt.Errorf("%w: %s is outside of zone %s", repository.ErrInvalidName, name, zone)
+	}
+
+	return name, nil
+}
+
+// IsPersistentRRSet reports whether a record set holds dns-persist-01
+// authorizations, by its name or by the comment the reconciler sets.
+func IsPersistentRRSet(name string, rrSet *stackitdnsclient.RecordSet) bool {
+	first, _, _ := strings.Cut(name, ".")
+	if strings.EqualFold(first, persistLabel) {
+		return true
+	}
+
+	return rrSet != nil && rrSet.Comment != nil && *rrSet.Comment == persistComment
+}
@@ -0,0 +1,352 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const defaultPersistInterval = 10 * time.Minute
+
+// Kinds of PersistFinding.
+const (
+	PersistFindingMissing   = "missing"
+	PersistFindingDrifted   = "drifted"
+	PersistFindingUnmanaged = "unmanaged"
+	PersistFindingInvalid   = "invalid"
+	PersistFindingExpired   = "expired"
+	PersistFindingTTL       = "ttl"
+)
+
+// PersistConfig lists the dns-persist-01 authorization records to maintain.
+type PersistConfig struct {
+	// Interval is the pause between two reconciliations of Run.
+	Interval metav1.Duration       `json:"interval"`
+	Records  []PersistRecordConfig `json:"records"`
+}
+
+// PersistRecordConfig is the authorization record set of one domain.
+type PersistRecordConfig struct {
+	// Provider selects project and credentials like the solver config of an
+	// issuer.
+	Provider StackitDnsProviderConfig `json:"provider"`
+	Zone     string                   `json:"zone"`
+	Domain   string                   `json:"domain"`
+	TTL      int32                    `json:"ttl"`
+	// Prune removes records that are not listed in Authorizations, which are
+	// otherwise only reported.
+	Prune          bool                   `json:"prune"`
+	Authorizations []PersistAuthorization `json:"authorizations"`
+}
+
+// PersistFinding is a difference between an authorization record set and
+// its configuration.
+type PersistFinding struct {
+	Kind     string `json:"kind"`
+	Value    string `json:"value,omitempty"`
+	Expected string `json:"expected,omitempty"`
+}
+
+// PersistRecordReport is the result of reconciling one record set.
+type PersistRecordReport struct {
+	Zone     string           `json:"zone"`
+	Name     string           `json:"name"`
+	Findings []PersistFinding `json:"findings,omitempty"`
+	Changed  bool             `json:"changed"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// LoadPersistConfig reads a YAML or JSON persist config file.
+func LoadPersistConfig(path string) (PersistConfig, error) {
+	var cfg PersistConfig
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("error reading persist config: %w", err)
+	}
+
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("error decoding persist config: %w", err)
+	}
+
+	if err := validatePersistConfig(&cfg); err != nil {
+		return cfg, err
+	}
+
+	setPersistDefaultValues(&cfg)
+
+	return cfg, nil
+}
+
+func validatePersistConfig(cfg *PersistConfig) error {
+	if cfg.Interval.Duration < 0 {
+		return fmt.Errorf("interval must not be negative")
+	}
+
+	for i := range cfg.Records {
+		record := &cfg.Records[i]
+		if err := validateConfig(&record.Provider); err != nil {
+			return fmt.Errorf("records[%d].provider: %w", i, err)
+		}
+		if _, err := persistRRSetName(record.Domain, record.Zone); err != nil {
+			return fmt.Errorf("records[%d]: %w", i, err)
+		}
+		if record.TTL != 0 {
+			if err := ValidateTTL(record.TTL); err != nil {
+				return fmt.Errorf("records[%d].ttl: %w", i, err)
+			}
+		}
+		if len(record.Authorizations) == 0 {
+			return fmt.Errorf("records[%d]: authorizations must not be empty", i)
+		}
+		for j, authorization := range record.Authorizations {
+			if err := validatePersistAuthorization(authorization); err != nil {
+				return fmt.Errorf("records[%d].authorizations[%d]: %w", i, j, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func setPersistDefaultValues(cfg *PersistConfig) {
+	if cfg.Interval.Duration == 0 {
+		cfg.Interval.Duration = defaultPersistInterval
+	}
+
+	for i := range cfg.Records {
+		record := &cfg.Records[i]
+		setDefaultValues(&record.Provider)
+		if record.TTL == 0 {
+			record.TTL = defaultPersistTTL
+		}
+	}
+}
+
+// PersistReconciler creates, updates and audits dns-persist-01 authorization
+// records through the same repositories and credentials as the webhook.
+type PersistReconciler struct {
+	resolver *stackitDnsProviderResolver
+	config   PersistConfig
+	now      func() time.Time
+}
+
+func NewPersistReconciler(
+	httpClient *http.Client,
+	logger *zap.Logger,
+	zoneRepositoryFactory repository.ZoneRepositoryFactory,
+	rrSetRepositoryFactory repository.RRSetRepositoryFactory,
+	secretFetcher SecretFetcher,
+	config PersistConfig,
+) *PersistReconciler {
+	return &PersistReconciler{
+		resolver: newStandaloneResolver(httpClient, logger, zoneRepositoryFactory, rrSetRepositoryFactory, secretFetcher),
+		config:   config,
+		now:      time.Now,
+	}
+}
+
+// Reconcile compares every configured record set with its configuration and,
+// if apply is set, writes the differences. Errors of single record sets are
+// reported and joined into the returned error.
+func (p *PersistReconciler) Reconcile(ctx context.Context, apply bool) ([]PersistRecordReport, error) {
+	reports := make([]PersistRecordReport, len(p.config.Records))
+
+	var errs []error
+	for i := range p.config.Records {
+		record := &p.config.Records[i]
+		report, err := p.reconcileRecord(ctx, record, apply)
+		if err != nil {
+			report.Error = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", report.Name, err))
+		}
+		reports[i] = report
+
+		p.resolver.logger.Info(
+			"Reconciled persistent authorizations",
+			zap.String("rrSetName", report.Name),
+			zap.Int("findings", len(report.Findings)),
+			zap.Bool("changed", report.Changed),
+			zap.Error(err),
+		)
+	}
+
+	return reports, errors.Join(errs...)
+}
+
+// Run applies the config every interval until ctx is done.
+func (p *PersistReconciler) Run(ctx context.Context) {
+	for {
+		if _, err := p.Reconcile(ctx, true); err != nil {
+			p.resolver.logger.Error("Error reconciling persistent authorizations", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.config.Interval.Duration):
+		}
+	}
+}
+
+func (p *PersistReconciler) reconcileRecord(
+	ctx context.Context,
+	record *PersistRecordConfig,
+	apply bool,
+) (PersistRecordReport, error) {
+	report := PersistRecordReport{Zone: record.Zone}
+
+	name, err := persistRRSetName(record.Domain, record.Zone)
+	if err != nil {
+		return report, err
+	}
+	report.Name = name
+
+	rrSetRepository, err := p.resolver.writableRRSetRepository(ctx, &record.Provider, record.Zone)
+	if err != nil {
+		return report, err
+	}
+
+	existing, err := rrSetRepository.FetchRRSetForZone(ctx, name, typeTxtRecord)
+	if errors.Is(err, repository.ErrRRSetNotFound) {
+		existing = nil
+	} else if err != nil {
+		return report, err
+	}
+
+	records, findings := planPersistRecords(existing, record, p.now())
+	report.Findings = findings
+	if !apply || !persistRRSetChanged(existing, records, record.TTL) {
+		return report, nil
+	}
+
+	rrSet := stackitdnsclient.RecordSet{
+		Comment: new(persistComment),
+		Name:    name,
+		Records: records,
+		Ttl:     record.TTL,
+		Type:    typeTxtRecord,
+	}
+	if existing == nil {
+		err = rrSetRepository.CreateRRSet(ctx, rrSet)
+	} else {
+		rrSet.Id = existing.Id
+		err = rrSetRepository.UpdateRRSet(ctx, rrSet)
+	}
+	report.Changed = err == nil
+
+	return report, err
+}
+
+// planPersistRecords returns the records the record set should hold and the
+// findings that lead to them. Records of other authorizers are kept unless
+// the config prunes them, and expired authorizations are only reported.
+func planPersistRecords(
+	existing *stackitdnsclient.RecordSet,
+	record *PersistRecordConfig,
+	now time.Time,
+) ([]stackitdnsclient.Record, []PersistFinding) {
+	var current []stackitdnsclient.Record
+	var findings []PersistFinding
+	if existing != nil {
+		current = existing.Records
+		if existing.Ttl != record.TTL {
+			findings = append(findings, PersistFinding{
+				Kind:     PersistFindingTTL,
+				Value:    fmt.Sprint(existing.Ttl),
+				Expected: fmt.Sprint(record.TTL),
+			})
+		}
+	}
+
+	records := make([]stackitdnsclient.Record, 0, len(current)+len(record.Authorizations))
+	audit := persistAudit{
+		record:  record,
+		now:     now,
+		matched: make([]bool, len(record.Authorizations)),
+		current: make([]bool, len(record.Authorizations)),
+	}
+	for _, r := range current {
+		keep, finding := audit.check(txtValue(r.Content))
+		if finding != nil {
+			findings = append(findings, *finding)
+		}
+		if keep {
+			records = append(records, r)
+		}
+	}
+
+	for i, authorization := range record.Authorizations {
+		if !audit.matched[i] {
+			findings = append(findings, PersistFinding{Kind: PersistFindingMissing, Expected: authorization.value()})
+		}
+		if !audit.current[i] {
+			records = append(records, stackitdnsclient.Record{Content: encodeTXTContent(authorization.value())})
+		}
+	}
+
+	return records, findings
+}
+
+// persistAudit matches existing records to the configured authorizations.
+// matched marks authorizations with a record of the same authorizer, current
+// those whose record is up to date.
+type persistAudit struct {
+	record  *PersistRecordConfig
+	now     time.Time
+	matched []bool
+	current []bool
+}
+
+// check audits the value of an existing record and returns whether the
+// record is kept as it is.
+func (a *persistAudit) check(value string) (bool, *PersistFinding) {
+	authorization, err := parsePersistAuthorization(value)
+	if err != nil {
+		return !a.record.Prune, &PersistFinding{Kind: PersistFindingInvalid, Value: value}
+	}
+
+	for i, expected := range a.record.Authorizations {
+		if a.matched[i] || !expected.sameAuthorizer(authorization) {
+			continue
+		}
+		a.matched[i] = true
+
+		if !expected.equal(authorization) {
+			return false, &PersistFinding{Kind: PersistFindingDrifted, Value: value, Expected: expected.value()}
+		}
+		a.current[i] = true
+		if authorization.expired(a.now) {
+			return true, &PersistFinding{Kind: PersistFindingExpired, Value: value}
+		}
+
+		return true, nil
+	}
+
+	return !a.record.Prune, &PersistFinding{Kind: PersistFindingUnmanaged, Value: value}
+}
+
+// persistRRSetChanged reports whether records and ttl differ from the
+// existing record set.
+func persistRRSetChanged(existing *stackitdnsclient.RecordSet, records []stackitdnsclient.Record, ttl int32) bool {
+	if existing == nil || existing.Ttl != ttl || len(existing.Records) != len(records) {
+		return true
+	}
+
+	for i, record := range records {
+		if existing.Records[i].Content != record.Content {
+			return true
+		}
+	}
+
+	return false
+}
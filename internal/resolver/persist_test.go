@@ -0,0 +1,291 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	repository_mock "github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository/mock"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var persistNow = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestPersistAuthorization(t *testing.T) {
+	t.Parallel()
+
+	authorization := PersistAuthorization{
+		IssuerDomain: "letsencrypt.org",
+		AccountURI:   accountA,
+		Wildcard:     true,
+		PersistUntil: new(metav1.NewTime(persistNow)),
+	}
+	value := authorization.value()
+	require.Equal(t, "letsencrypt.org; accounturi="+accountA+"; policy=wildcard; persistuntil=1767225600", value)
+
+	parsed, err := parsePersistAuthorization(value)
+	require.NoError(t, err)
+	require.Equal(t, value, parsed.value())
+	require.True(t, parsed.sameAuthorizer(PersistAuthorization{IssuerDomain: "LetsEncrypt.org", AccountURI: accountA}))
+	require.False(t, parsed.sameAuthorizer(PersistAuthorization{IssuerDomain: "letsencrypt.org", AccountURI: accountB}))
+
+	parsed, err = parsePersistAuthorization("letsencrypt.org;AccountURI=" + accountA + ";foo=bar")
+	require.NoError(t, err)
+	require.Equal(t, PersistAuthorization{IssuerDomain: "letsencrypt.org", AccountURI: accountA}, parsed)
+
+	for _, invalid := range []string{
+		"letsencrypt.org",
+		"; accounturi=" + accountA,
+		"letsencrypt.org; accounturi",
+		"letsencrypt.org; accounturi=" + accountA + "; persistUntil=tomorrow",
+	} {
+		_, err := parsePersistAuthorization(invalid)
+		require.ErrorIs(t, err, ErrInvalidAuthorization, invalid)
+	}
+}
+
+func TestPersistRRSetName(t *testing.T) {
+	t.Parallel()
+
+	name, err := persistRRSetName("WWW.test.com", "test.com.")
+	require.NoError(t, err)
+	require.Equal(t, "_validation-persist.www.test.com.", name)
+
+	name, err = persistRRSetName("test.com.", "test.com")
+	require.NoError(t, err)
+	require.Equal(t, "_validation-persist.test.com.", name)
+
+	_, err = persistRRSetName("other.org", "test.com")
+	require.ErrorContains(t, err, "outside of zone test.com")
+
+	require.True(t, IsPersistentRRSet(name, nil))
+	require.True(t, IsPersistentRRSet("_acme-challenge.test.com.", &stackitdnsclient.RecordSet{
+		Comment: new(persistComment),
+	}))
+	require.False(t, IsPersistentRRSet("_acme-challenge.test.com.", &stackitdnsclient.RecordSet{}))
+}
+
+func TestPlanPersistRecords(t *testing.T) {
+	t.Parallel()
+
+	authA := PersistAuthorization{IssuerDomain: "letsencrypt.org", AccountURI: accountA}
+	authB := PersistAuthorization{IssuerDomain: "sectigo.com", AccountURI: accountB}
+	expired := PersistAuthorization{
+		IssuerDomain: "letsencrypt.org",
+		AccountURI:   accountA,
+		PersistUntil: new(metav1.NewTime(persistNow.Add(-time.Hour))),
+	}
+	until := PersistAuthorization{
+		IssuerDomain: "letsencrypt.org",
+		AccountURI:   accountA,
+		PersistUntil: new(metav1.NewTime(persistNow.Add(time.Hour))),
+	}
+	respelled := fmt.Sprintf("LetsEncrypt.org;accounturi=%s;  persistUntil=%d", accountA, until.PersistUntil.Unix())
+	rrSet := func(ttl int32, values ...string) *stackitdnsclient.RecordSet {
+		records := make([]stackitdnsclient.Record, len(values))
+		for i, value := range values {
+			records[i] = stackitdnsclient.Record{Content: encodeTXTContent(value)}
+		}
+
+		return &stackitdnsclient.RecordSet{Id: "1", Ttl: ttl, Records: records}
+	}
+
+	tests := []struct {
+		name     string
+		existing *stackitdnsclient.RecordSet
+		desired  []PersistAuthorization
+		prune    bool
+		records  []string
+		findings []string
+	}{
+		{name: "missing record set", desired: []PersistAuthorization{authA}, records: []string{authA.value()},
+			findings: []string{PersistFindingMissing}},
+		{name: "up to date", existing: rrSet(3600, authA.value()), desired: []PersistAuthorization{authA},
+			records: []string{authA.value()}},
+		{
+			name:     "differently written",
+			existing: rrSet(3600, respelled),
+			desired:  []PersistAuthorization{until},
+			records:  []string{respelled},
+		},
+		{
+			name:     "drifted",
+			existing: rrSet(3600, authA.value()+"; policy=wildcard"),
+			desired:  []PersistAuthorization{authA},
+			records:  []string{authA.value()},
+			findings: []string{PersistFindingDrifted},
+		},
+		{
+			name:     "other authorizers are kept",
+			existing: rrSet(3600, authB.value(), "garbage"),
+			desired:  []PersistAuthorization{authA},
+			records:  []string{authB.value(), "garbage", authA.value()},
+			findings: []string{PersistFindingUnmanaged, PersistFindingInvalid, PersistFindingMissing},
+		},
+		{
+			name:     "other authorizers are pruned",
+			existing: rrSet(3600, authB.value(), "garbage", authA.value()),
+			desired:  []PersistAuthorization{authA},
+			prune:    true,
+			records:  []string{authA.value()},
+			findings: []string{PersistFindingUnmanaged, PersistFindingInvalid},
+		},
+		{
+			name:     "expired",
+			existing: rrSet(3600, expired.value()),
+			desired:  []PersistAuthorization{expired},
+			records:  []string{expired.value()},
+			findings: []string{PersistFindingExpired},
+		},
+		{
+			name:     "ttl",
+			existing: rrSet(60, authA.value()),
+			desired:  []PersistAuthorization{authA},
+			records:  []string{authA.value()},
+			findings: []string{PersistFindingTTL},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			records, findings := planPersistRecords(tt.existing, &PersistRecordConfig{
+				TTL:            3600,
+				Prune:          tt.prune,
+				Authorizations: tt.desired,
+			}, persistNow)
+
+			values := make([]string, len(records))
+			for i, record := range records {
+				values[i] = txtValue(record.Content)
+			}
+			require.Equal(t, tt.records, values)
+
+			var kinds []string
+			for _, finding := range findings {
+				kinds = append(kinds, finding.Kind)
+			}
+			require.Equal(t, tt.findings, kinds)
+		})
+	}
+}
+
+func newTestPersistReconciler(
+	t *testing.T,
+	rrSets *memoryRRSetRepository,
+	records ...PersistRecordConfig,
+) *PersistReconciler {
+	t.Helper()
+
+	ctrl := gomock.NewController(t)
+	zoneRepository := repository_mock.NewMockZoneRepository(ctrl)
+	zoneRepository.EXPECT().
+		FetchZone(gomock.Any(), "test.com").
+		Return(&stackitdnsclient.Zone{Id: "zone", DnsName: "test.com"}, nil).
+		AnyTimes()
+	zoneRepositoryFactory := repository_mock.NewMockZoneRepositoryFactory(ctrl)
+	zoneRepositoryFactory.EXPECT().NewZoneRepository(gomock.Any()).Return(zoneRepository, nil).AnyTimes()
+	rrSetRepositoryFactory := repository_mock.NewMockRRSetRepositoryFactory(ctrl)
+	rrSetRepositoryFactory.EXPECT().NewRRSetRepository(gomock.Any(), "zone").Return(rrSets, nil).AnyTimes()
+
+	cfg := PersistConfig{Records: records}
+	require.NoError(t, validatePersistConfig(&cfg))
+	setPersistDefaultValues(&cfg)
+
+	reconciler := NewPersistReconciler(nil, zap.NewNop(), zoneRepositoryFactory, rrSetRepositoryFactory,
+		staticSecretFetcher{"certs/stackit-cert-manager-webhook/auth-token": "token"}, cfg)
+	reconciler.now = func() time.Time { return persistNow }
+
+	return reconciler
+}
+
+func TestPersistReconciler(t *testing.T) {
+	t.Parallel()
+
+	// Account URLs can be long enough to need more than one character-string.
+	longAccount := "https://acme.test/acme/acct/" + strings.Repeat("a", 300)
+	authorization := PersistAuthorization{IssuerDomain: "letsencrypt.org", AccountURI: longAccount, Wildcard: true}
+
+	rrSets := newMemoryRRSetRepository()
+	reconciler := newTestPersistReconciler(t, rrSets, PersistRecordConfig{
+		Provider:       StackitDnsProviderConfig{ProjectId: "project", AuthTokenSecretNamespace: "certs"},
+		Zone:           "test.com",
+		Domain:         "test.com",
+		Authorizations: []PersistAuthorization{authorization},
+	})
+
+	reports, err := reconciler.Reconcile(context.Background(), false)
+	require.NoError(t, err)
+	require.Equal(t, PersistFindingMissing, reports[0].Findings[0].Kind)
+	require.False(t, reports[0].Changed)
+	require.Empty(t, rrSets.records())
+
+	reports, err = reconciler.Reconcile(context.Background(), true)
+	require.NoError(t, err)
+	require.True(t, reports[0].Changed)
+
+	rrSet, err := rrSets.FetchRRSetForZone(context.Background(), "_validation-persist.test.com.", "TXT")
+	require.NoError(t, err)
+	require.Equal(t, persistComment, *rrSet.Comment)
+	require.Equal(t, int32(defaultPersistTTL), rrSet.Ttl)
+	strs, err := ParseTXTContent(rrSet.Records[0].Content)
+	require.NoError(t, err)
+	require.Len(t, strs, 2)
+	require.Equal(t, authorization.value(), strings.Join(strs, ""))
+
+	reports, err = reconciler.Reconcile(context.Background(), true)
+	require.NoError(t, err)
+	require.Empty(t, reports[0].Findings)
+	require.False(t, reports[0].Changed)
+}
+
+func TestLoadPersistConfig(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "persist.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+interval: 1h
+records:
+  - provider:
+      projectId: project
+    zone: test.com
+    domain: www.test.com
+    authorizations:
+      - issuerDomain: letsencrypt.org
+        accountURI: https://acme.test/acme/acct/1
+        persistUntil: "2027-01-01T00:00:00Z"
+`), 0o600))
+
+	cfg, err := LoadPersistConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, time.Hour, cfg.Interval.Duration)
+	require.Equal(t, int32(defaultPersistTTL), cfg.Records[0].TTL)
+	require.Equal(t, "https://dns.api.stackit.cloud", cfg.Records[0].Provider.ApiBasePath)
+	require.Equal(t, int64(1798761600), cfg.Records[0].Authorizations[0].PersistUntil.Unix())
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+records:
+  - provider:
+      projectId: project
+    zone: test.com
+    domain: www.other.org
+    authorizations:
+      - issuerDomain: letsencrypt.org
+        accountURI: https://acme.test/acme/acct/1
+`), 0o600))
+	_, err = LoadPersistConfig(path)
+	require.ErrorContains(t, err, "records[0]: invalid domain name")
+
+	require.NoError(t, os.WriteFile(path, []byte(`records: [{zone: test.com, domian: test.com}]`), 0o600))
+	_, err = LoadPersistConfig(path)
+	require.ErrorContains(t, err, "error decoding persist config")
+}
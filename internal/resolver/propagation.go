@@ -0,0 +1,346 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Values of `propagationCheck.nameserverSource`.
+const (
+	// NameserverSourceAPI uses the NS records of the zone as stored in the
+	// DNS API, falling back to the primary nameserver of the zone.
+	NameserverSourceAPI = "api"
+	// NameserverSourceDNS looks up the NS records of the zone in DNS.
+	NameserverSourceDNS = "dns"
+)
+
+// Values of `propagationCheck.mode`.
+const (
+	// PropagationModeTXT waits until the challenge key is served.
+	PropagationModeTXT = "txt"
+	// PropagationModeSOA waits until the zone serial reported by the DNS API
+	// after the change is served.
+	PropagationModeSOA = "soa"
+)
+
+const (
+	defaultPropagationInterval = 2 * time.Second
+	propagationQueryTimeout    = 5 * time.Second
+	typeNsRecord               = "NS"
+)
+
+var (
+	ErrPropagationTimeout = errors.New("timed out waiting for propagation to the authoritative nameservers")
+	ErrNotPropagated      = errors.New("change not yet served by all authoritative nameservers")
+	ErrNoNameservers      = errors.New("no authoritative nameservers found")
+)
+
+// PropagationCheckConfig makes Present fail until every authoritative
+// nameserver of the zone serves the change. Without a Timeout, Present queries
+// each nameserver once and returns ErrNotPropagated, so cert-manager retries it
+// instead of the webhook request blocking. Nameservers overrides the
+// nameservers to query, given as host or host:port.
+type PropagationCheckConfig struct {
+	Nameservers      []string        `json:"nameservers"`
+	NameserverSource string          `json:"nameserverSource"`
+	Mode             string          `json:"mode"`
+	Timeout          metav1.Duration `json:"timeout"`
+	Interval         metav1.Duration `json:"interval"`
+}
+
+func validatePropagationCheckConfig(check *PropagationCheckConfig) error {
+	if check == nil {
+		return nil
+	}
+
+	switch check.NameserverSource {
+	case "", NameserverSourceAPI, NameserverSourceDNS:
+	default:
+		return fmt.Errorf("unknown propagationCheck.nameserverSource %q", check.NameserverSource)
+	}
+
+	switch check.Mode {
+	case "", PropagationModeTXT, PropagationModeSOA:
+	default:
+		return fmt.Errorf("unknown propagationCheck.mode %q", check.Mode)
+	}
+
+	if check.Timeout.Duration < 0 || check.Interval.Duration < 0 {
+		return fmt.Errorf("propagationCheck.timeout and propagationCheck.interval must not be negative")
+	}
+
+	return nil
+}
+
+func setPropagationCheckDefaultValues(check *PropagationCheckConfig) {
+	if check.NameserverSource == "" {
+		check.NameserverSource = NameserverSourceAPI
+	}
+	if check.Mode == "" {
+		check.Mode = PropagationModeTXT
+	}
+	if check.Interval.Duration == 0 {
+		check.Interval.Duration = defaultPropagationInterval
+	}
+}
+
+// propagationCondition queries a single nameserver and reports whether it
+// serves the change.
+type propagationCondition func(ctx context.Context, nameserver string) (bool, error)
+
+// awaitPropagation polls the authoritative nameservers of the zone until all
+// of them serve the challenge key or the expected zone serial. Without a
+// timeout they are polled once.
+func (s *stackitDnsProviderResolver) awaitPropagation(
+	initResolverRes *initResolverContextResult,
+	challengeKey string,
+) error {
+	check := *initResolverRes.propagationCheck
+	setPropagationCheckDefaultValues(&check)
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	if check.Timeout.Duration > 0 {
+		ctx, cancel = context.WithTimeout(s.ctx, check.Timeout.Duration)
+	}
+	defer cancel()
+
+	nameservers, err := s.authoritativeNameservers(ctx, initResolverRes, &check)
+	if err != nil {
+		return err
+	}
+
+	condition, err := s.propagationCondition(ctx, initResolverRes, &check, challengeKey)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info(
+		"Waiting for propagation",
+		zap.String("rrSetName", initResolverRes.rrSetName),
+		zap.String("mode", check.Mode),
+		zap.Strings("nameservers", nameservers),
+	)
+
+	start := time.Now()
+	pending := nameservers
+	lastErrors := map[string]error{}
+	for {
+		pending = slices.DeleteFunc(pending, func(nameserver string) bool {
+			ok, err := condition(ctx, nameserver)
+			if err != nil {
+				lastErrors[nameserver] = err
+			}
+
+			return ok
+		})
+		if len(pending) == 0 {
+			s.logger.Info(
+				"Change propagated to all authoritative nameservers",
+				zap.String("rrSetName", initResolverRes.rrSetName),
+				zap.Duration("duration", time.Since(start)),
+			)
+
+			return nil
+		}
+
+		if check.Timeout.Duration == 0 {
+			return propagationError(ErrNotPropagated, pending, lastErrors)
+		}
+
+		select {
+		case <-ctx.Done():
+			return propagationError(ErrPropagationTimeout, pending, lastErrors)
+		case <-time.After(check.Interval.Duration):
+		}
+	}
+}
+
+func propagationError(sentinel error, pending []string, lastErrors map[string]error) error {
+	details := make([]string, len(pending))
+	for i, nameserver := range pending {
+		details[i] = nameserver
+		if err, ok := lastErrors[nameserver]; ok {
+			details[i] += " (" + err.Error() + ")"
+		}
+	}
+
+	return fmt.Errorf("%w: still waiting for %s", sentinel, strings.Join(details, ", "))
+}
+
+func (s *stackitDnsProviderResolver) propagationCondition(
+	ctx context.Context,
+	initResolverRes *initResolverContextResult,
+	check *PropagationCheckConfig,
+	challengeKey string,
+) (propagationCondition, error) {
+	if check.Mode == PropagationModeTXT {
+		return func(ctx context.Context, nameserver string) (bool, error) {
+			return nameserverServesTXT(ctx, nameserver, initResolverRes.rrSetName, challengeKey)
+		}, nil
+	}
+
+	// The serial is read after the change, so it is at least the one that
+	// includes it.
+	zone, err := initResolverRes.zoneRepository.FetchZone(ctx, initResolverRes.zone.DnsName)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching zone serial: %w", err)
+	}
+	expected := int64(zone.SerialNumber)
+
+	return func(ctx context.Context, nameserver string) (bool, error) {
+		serial, err := nameserverSOASerial(ctx, nameserver, initResolverRes.zone.DnsName)
+		if err != nil {
+			return false, err
+		}
+
+		return serialAtLeast(serial, expected), nil
+	}, nil
+}
+
+// authoritativeNameservers returns the host:port of every nameserver to query.
+func (s *stackitDnsProviderResolver) authoritativeNameservers(
+	ctx context.Context,
+	initResolverRes *initResolverContextResult,
+	check *PropagationCheckConfig,
+) ([]string, error) {
+	hosts := check.Nameservers
+	if len(hosts) == 0 {
+		var err error
+		if check.NameserverSource == NameserverSourceDNS {
+			hosts, err = s.lookupNameservers(ctx, initResolverRes.zone.DnsName)
+		} else {
+			hosts, err = apiNameservers(ctx, initResolverRes)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("%w for zone %s", ErrNoNameservers, initResolverRes.zone.DnsName)
+	}
+
+	return nameserverAddresses(hosts), nil
+}
+
+func (s *stackitDnsProviderResolver) lookupNameservers(ctx context.Context, zoneDnsName string) ([]string, error) {
+	records, err := s.nsResolver.LookupNS(ctx, zoneDnsName)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up nameservers of zone %s: %w", zoneDnsName, err)
+	}
+
+	hosts := make([]string, len(records))
+	for i, record := range records {
+		hosts[i] = record.Host
+	}
+
+	return hosts, nil
+}
+
+// apiNameservers reads the NS record set at the zone apex, falling back to the
+// primary nameserver of the zone.
+func apiNameservers(ctx context.Context, initResolverRes *initResolverContextResult) ([]string, error) {
+	zone := initResolverRes.zone
+
+	var hosts []string
+	rrSets := initResolverRes.rrSetRepository.ListRRSets(ctx, repository.RRSetListOptions{
+		Name:       zone.DnsName + ".",
+		Type:       typeNsRecord,
+		ActiveOnly: true,
+	})
+	for rrSet, err := range rrSets {
+		if err != nil {
+			return nil, fmt.Errorf("error listing nameservers of zone %s: %w", zone.DnsName, err)
+		}
+		for _, record := range rrSet.Records {
+			hosts = append(hosts, record.Content)
+		}
+	}
+
+	if len(hosts) == 0 && zone.PrimaryNameServer != "" {
+		hosts = append(hosts, zone.PrimaryNameServer)
+	}
+
+	return hosts, nil
+}
+
+func nameserverAddress(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+
+	return net.JoinHostPort(strings.TrimSuffix(host, "."), "53")
+}
+
+// queryNameserver sends a non-recursive query, retrying over TCP if the UDP
+// answer was truncated.
+func queryNameserver(ctx context.Context, nameserver, name string, qtype uint16) (*dns.Msg, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.RecursionDesired = false
+
+	ctx, cancel := context.WithTimeout(ctx, propagationQueryTimeout)
+	defer cancel()
+
+	client := &dns.Client{}
+	response, _, err := client.ExchangeContext(ctx, msg, nameserver)
+	if err == nil && response.Truncated {
+		client.Net = "tcp"
+		response, _, err = client.ExchangeContext(ctx, msg, nameserver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("query for %s returned %s", name, dns.RcodeToString[response.Rcode])
+	}
+
+	return response, nil
+}
+
+func nameserverServesTXT(ctx context.Context, nameserver, name, value string) (bool, error) {
+	response, err := queryNameserver(ctx, nameserver, name, dns.TypeTXT)
+	if err != nil {
+		return false, err
+	}
+
+	for _, answer := range response.Answer {
+		if txt, ok := answer.(*dns.TXT); ok && strings.Join(txt.Txt, "") == value {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func nameserverSOASerial(ctx context.Context, nameserver, zoneDnsName string) (uint32, error) {
+	response, err := queryNameserver(ctx, nameserver, zoneDnsName, dns.TypeSOA)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, answer := range response.Answer {
+		if soa, ok := answer.(*dns.SOA); ok {
+			return soa.Serial, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no SOA record for %s", zoneDnsName)
+}
+
+// serialAtLeast compares zone serials using serial number arithmetic
+// (RFC 1982), so a wrapped serial counts as newer.
+func serialAtLeast(serial uint32, expected int64) bool {
+	return (int64(serial)-expected)&0xffffffff < 1<<31
+}
@@ -0,0 +1,376 @@
+package resolver
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	repository_mock "github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository/mock"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeNameserver is an in-process authoritative nameserver for test.com.
+type fakeNameserver struct {
+	mu          sync.Mutex
+	txt         map[string][]string
+	ns          map[string][]string
+	serial      uint32
+	truncateUDP bool
+	// referral answers NS queries like a parent zone, in the authority
+	// section of a non-authoritative response.
+	referral bool
+	queries  int
+	// onQuery runs before every answer, e.g. to publish a record later.
+	onQuery func(n *fakeNameserver)
+}
+
+func (n *fakeNameserver) queryCount() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.queries
+}
+
+func (n *fakeNameserver) setTXT(name string, values ...string) {
+	n.txt[dns.Fqdn(name)] = values
+}
+
+func (n *fakeNameserver) ServeDNS(w dns.ResponseWriter, request *dns.Msg) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.queries++
+	if n.onQuery != nil {
+		n.onQuery(n)
+	}
+
+	response := new(dns.Msg)
+	response.SetReply(request)
+	response.Authoritative = true
+
+	_, udp := w.RemoteAddr().(*net.UDPAddr)
+	if udp && n.truncateUDP {
+		response.Truncated = true
+		_ = w.WriteMsg(response)
+
+		return
+	}
+
+	question := request.Question[0]
+	header := dns.RR_Header{Name: question.Name, Class: dns.ClassINET, Rrtype: question.Qtype, Ttl: 60}
+	switch question.Qtype {
+	case dns.TypeTXT:
+		for _, value := range n.txt[question.Name] {
+			response.Answer = append(response.Answer, &dns.TXT{Hdr: header, Txt: []string{value}})
+		}
+	case dns.TypeSOA:
+		response.Answer = append(response.Answer, &dns.SOA{
+			Hdr: header, Ns: "ns1.test.com.", Mbox: "hostmaster.test.com.", Serial: n.serial,
+		})
+	case dns.TypeNS:
+		nameservers, ok := n.ns[question.Name]
+		if !ok {
+			response.Rcode = dns.RcodeNameError
+		}
+		for _, ns := range nameservers {
+			if n.referral {
+				response.Authoritative = false
+				response.Ns = append(response.Ns, &dns.NS{Hdr: header, Ns: ns})
+			} else {
+				response.Answer = append(response.Answer, &dns.NS{Hdr: header, Ns: ns})
+			}
+		}
+	}
+
+	_ = w.WriteMsg(response)
+}
+
+// startFakeNameserver serves n over UDP and TCP on the same local port and
+// returns its address.
+func startFakeNameserver(t *testing.T, n *fakeNameserver) string {
+	t.Helper()
+
+	if n.txt == nil {
+		n.txt = map[string][]string{}
+	}
+
+	var (
+		listener net.Listener
+		conn     net.PacketConn
+		err      error
+	)
+	for range 10 {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		conn, err = net.ListenPacket("udp", listener.Addr().String())
+		if err == nil {
+			break
+		}
+		_ = listener.Close()
+	}
+	require.NoError(t, err)
+
+	for _, server := range []*dns.Server{
+		{Listener: listener, Handler: n},
+		{PacketConn: conn, Handler: n},
+	} {
+		started := make(chan struct{})
+		server.NotifyStartedFunc = func() { close(started) }
+		go func() { _ = server.ActivateAndServe() }()
+		<-started
+		t.Cleanup(func() { _ = server.Shutdown() })
+	}
+
+	return listener.Addr().String()
+}
+
+// standInResolver sends all lookups to the nameserver at address.
+func standInResolver(address string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, address)
+		},
+	}
+}
+
+func propagationTestResolver() *stackitDnsProviderResolver {
+	return &stackitDnsProviderResolver{ctx: context.Background(), logger: zap.NewNop(), nsResolver: net.DefaultResolver}
+}
+
+func propagationCheck(mode string, nameservers ...string) *PropagationCheckConfig {
+	return &PropagationCheckConfig{
+		Nameservers: nameservers,
+		Mode:        mode,
+		Timeout:     metav1.Duration{Duration: time.Second},
+		Interval:    metav1.Duration{Duration: 10 * time.Millisecond},
+	}
+}
+
+func TestAwaitPropagationTXT(t *testing.T) {
+	t.Parallel()
+
+	const name = "_acme-challenge.test.com."
+
+	ready := &fakeNameserver{}
+	ready.txt = map[string][]string{name: {"other-key", validChallengeKey}}
+
+	// The second nameserver only serves the key from its third query on.
+	lagging := &fakeNameserver{onQuery: func(n *fakeNameserver) {
+		if n.queries == 3 {
+			n.setTXT(name, validChallengeKey)
+		}
+	}}
+
+	check := propagationCheck(PropagationModeTXT, startFakeNameserver(t, ready), startFakeNameserver(t, lagging))
+
+	s := propagationTestResolver()
+	err := s.awaitPropagation(&initResolverContextResult{
+		zone:             &stackitdnsclient.Zone{DnsName: "test.com"},
+		rrSetName:        name,
+		propagationCheck: check,
+	}, validChallengeKey)
+	require.NoError(t, err)
+	require.Equal(t, 1, ready.queryCount())
+	require.Equal(t, 3, lagging.queryCount())
+}
+
+func TestAwaitPropagationTCPFallback(t *testing.T) {
+	t.Parallel()
+
+	const name = "_acme-challenge.test.com."
+	truncating := &fakeNameserver{truncateUDP: true}
+	truncating.txt = map[string][]string{name: {validChallengeKey}}
+
+	s := propagationTestResolver()
+	err := s.awaitPropagation(&initResolverContextResult{
+		zone:             &stackitdnsclient.Zone{DnsName: "test.com"},
+		rrSetName:        name,
+		propagationCheck: propagationCheck(PropagationModeTXT, startFakeNameserver(t, truncating)),
+	}, validChallengeKey)
+	require.NoError(t, err)
+	require.Equal(t, 2, truncating.queryCount())
+}
+
+func TestAwaitPropagationTimeout(t *testing.T) {
+	t.Parallel()
+
+	address := startFakeNameserver(t, &fakeNameserver{})
+	check := propagationCheck(PropagationModeTXT, address)
+	check.Timeout.Duration = 100 * time.Millisecond
+
+	s := propagationTestResolver()
+	err := s.awaitPropagation(&initResolverContextResult{
+		zone:             &stackitdnsclient.Zone{DnsName: "test.com"},
+		rrSetName:        "_acme-challenge.test.com.",
+		propagationCheck: check,
+	}, validChallengeKey)
+	require.ErrorIs(t, err, ErrPropagationTimeout)
+	require.ErrorContains(t, err, address)
+}
+
+func TestAwaitPropagationWithoutTimeout(t *testing.T) {
+	t.Parallel()
+
+	nameserver := &fakeNameserver{}
+	address := startFakeNameserver(t, nameserver)
+	check := propagationCheck(PropagationModeTXT, address)
+	check.Timeout.Duration = 0
+
+	// Without a timeout, Present fails after one round so cert-manager retries it.
+	s := propagationTestResolver()
+	err := s.awaitPropagation(&initResolverContextResult{
+		zone:             &stackitdnsclient.Zone{DnsName: "test.com"},
+		rrSetName:        "_acme-challenge.test.com.",
+		propagationCheck: check,
+	}, validChallengeKey)
+	require.ErrorIs(t, err, ErrNotPropagated)
+	require.ErrorContains(t, err, address)
+	require.Equal(t, 1, nameserver.queryCount())
+}
+
+func TestAwaitPropagationSOA(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	zoneRepository := repository_mock.NewMockZoneRepository(ctrl)
+	zoneRepository.EXPECT().
+		FetchZone(gomock.Any(), "test.com").
+		Return(&stackitdnsclient.Zone{DnsName: "test.com", SerialNumber: 2024010102}, nil)
+
+	nameserver := &fakeNameserver{serial: 2024010101, onQuery: func(n *fakeNameserver) {
+		if n.queries == 2 {
+			n.serial = 2024010102
+		}
+	}}
+
+	s := propagationTestResolver()
+	err := s.awaitPropagation(&initResolverContextResult{
+		zoneRepository:   zoneRepository,
+		zone:             &stackitdnsclient.Zone{DnsName: "test.com", SerialNumber: 2024010101},
+		rrSetName:        "_acme-challenge.test.com.",
+		propagationCheck: propagationCheck(PropagationModeSOA, startFakeNameserver(t, nameserver)),
+	}, validChallengeKey)
+	require.NoError(t, err)
+	require.Equal(t, 2, nameserver.queryCount())
+}
+
+func TestSerialAtLeast(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, serialAtLeast(5, 5))
+	require.True(t, serialAtLeast(6, 5))
+	require.False(t, serialAtLeast(4, 5))
+	require.True(t, serialAtLeast(1, 4294967295), "wrapped serial is newer")
+	require.False(t, serialAtLeast(4294967295, 1))
+}
+
+func TestAuthoritativeNameservers(t *testing.T) {
+	t.Parallel()
+
+	t.Run("overrides", func(t *testing.T) {
+		t.Parallel()
+
+		s := propagationTestResolver()
+		nameservers, err := s.authoritativeNameservers(context.Background(), &initResolverContextResult{
+			zone: &stackitdnsclient.Zone{DnsName: "test.com"},
+		}, &PropagationCheckConfig{Nameservers: []string{"ns1.test.com.", "10.0.0.1:5353", "ns1.test.com"}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"ns1.test.com:53", "10.0.0.1:5353"}, nameservers)
+	})
+
+	t.Run("api", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+		rrSetRepository.EXPECT().
+			ListRRSets(gomock.Any(), repository.RRSetListOptions{Name: "test.com.", Type: "NS", ActiveOnly: true}).
+			Return(rrSetSeq(stackitdnsclient.RecordSet{Records: []stackitdnsclient.Record{
+				{Content: "ns1.stackit.cloud."},
+				{Content: "ns2.stackit.zone."},
+			}}))
+
+		s := propagationTestResolver()
+		nameservers, err := s.authoritativeNameservers(context.Background(), &initResolverContextResult{
+			rrSetRepository: rrSetRepository,
+			zone:            &stackitdnsclient.Zone{DnsName: "test.com"},
+		}, &PropagationCheckConfig{NameserverSource: NameserverSourceAPI})
+		require.NoError(t, err)
+		require.Equal(t, []string{"ns1.stackit.cloud:53", "ns2.stackit.zone:53"}, nameservers)
+	})
+
+	t.Run("api falls back to primary nameserver", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+		rrSetRepository.EXPECT().ListRRSets(gomock.Any(), gomock.Any()).Return(rrSetSeq())
+
+		s := propagationTestResolver()
+		nameservers, err := s.authoritativeNameservers(context.Background(), &initResolverContextResult{
+			rrSetRepository: rrSetRepository,
+			zone:            &stackitdnsclient.Zone{DnsName: "test.com", PrimaryNameServer: "ns1.stackit.cloud"},
+		}, &PropagationCheckConfig{NameserverSource: NameserverSourceAPI})
+		require.NoError(t, err)
+		require.Equal(t, []string{"ns1.stackit.cloud:53"}, nameservers)
+	})
+
+	t.Run("api without nameservers", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+		rrSetRepository.EXPECT().ListRRSets(gomock.Any(), gomock.Any()).Return(rrSetSeq())
+
+		s := propagationTestResolver()
+		_, err := s.authoritativeNameservers(context.Background(), &initResolverContextResult{
+			rrSetRepository: rrSetRepository,
+			zone:            &stackitdnsclient.Zone{DnsName: "test.com"},
+		}, &PropagationCheckConfig{NameserverSource: NameserverSourceAPI})
+		require.ErrorIs(t, err, ErrNoNameservers)
+	})
+
+	t.Run("dns", func(t *testing.T) {
+		t.Parallel()
+
+		address := startFakeNameserver(t, &fakeNameserver{
+			ns: map[string][]string{"test.com.": {"ns1.test.com.", "ns2.test.com."}},
+		})
+		s := propagationTestResolver()
+		s.nsResolver = standInResolver(address)
+
+		nameservers, err := s.authoritativeNameservers(context.Background(), &initResolverContextResult{
+			zone: &stackitdnsclient.Zone{DnsName: "test.com"},
+		}, &PropagationCheckConfig{NameserverSource: NameserverSourceDNS})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"ns1.test.com:53", "ns2.test.com:53"}, nameservers)
+	})
+}
+
+func TestPropagationCheckConfig(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, validatePropagationCheckConfig(nil))
+	require.NoError(t, validatePropagationCheckConfig(&PropagationCheckConfig{Mode: PropagationModeSOA}))
+	require.EqualError(t, validatePropagationCheckConfig(&PropagationCheckConfig{Mode: "http"}),
+		`unknown propagationCheck.mode "http"`)
+	require.EqualError(t, validatePropagationCheckConfig(&PropagationCheckConfig{NameserverSource: "whois"}),
+		`unknown propagationCheck.nameserverSource "whois"`)
+
+	check := &PropagationCheckConfig{}
+	setPropagationCheckDefaultValues(check)
+	require.Equal(t, PropagationCheckConfig{
+		NameserverSource: NameserverSourceAPI,
+		Mode:             PropagationModeTXT,
+		Interval:         metav1.Duration{Duration: defaultPropagationInterval},
+	}, *check)
+}
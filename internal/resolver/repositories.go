@@ -0,0 +1,96 @@
+package resolver
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"go.uber.org/zap"
+)
+
+// RepositoryProvider gives front-ends that change record sets directly, like
+// the DNS UPDATE listener, the repositories of zones through the same
+// credentials as the webhook.
+type RepositoryProvider struct {
+	resolver *stackitDnsProviderResolver
+}
+
+func NewRepositoryProvider(
+	httpClient *http.Client,
+	logger *zap.Logger,
+	zoneRepositoryFactory repository.ZoneRepositoryFactory,
+	rrSetRepositoryFactory repository.RRSetRepositoryFactory,
+	secretFetcher SecretFetcher,
+) *RepositoryProvider {
+	return &RepositoryProvider{
+		resolver: newStandaloneResolver(httpClient, logger, zoneRepositoryFactory, rrSetRepositoryFactory, secretFetcher),
+	}
+}
+
+// RRSetRepository returns the record set repository of the zone selected by
+// cfg and zone. Zones that cannot be written to fail like in Present.
+func (p *RepositoryProvider) RRSetRepository(
+	ctx context.Context,
+	cfg *StackitDnsProviderConfig,
+	zone string,
+) (repository.RRSetRepository, error) {
+	return p.resolver.writableRRSetRepository(ctx, cfg, zone)
+}
+
+// newStandaloneResolver returns a resolver for use outside of cert-manager,
+// without Kubernetes clients and leases.
+func newStandaloneResolver(
+	httpClient *http.Client,
+	logger *zap.Logger,
+	zoneRepositoryFactory repository.ZoneRepositoryFactory,
+	rrSetRepositoryFactory repository.RRSetRepositoryFactory,
+	secretFetcher SecretFetcher,
+) *stackitDnsProviderResolver {
+	return &stackitDnsProviderResolver{
+		ctx:                    context.Background(),
+		httpClient:             httpClient,
+		secretFetcher:          secretFetcher,
+		zoneRepositoryFactory:  zoneRepositoryFactory,
+		rrSetRepositoryFactory: rrSetRepositoryFactory,
+		logger:                 logger,
+		authToken:              os.Getenv("STACKIT_AUTH_TOKEN"),
+		authTokenPath:          os.Getenv("STACKIT_AUTH_TOKEN_PATH"),
+		authTokenDir:           os.Getenv("STACKIT_AUTH_TOKEN_DIR"),
+		clientCache:            repository.NewClientCache(),
+		credentials:            newCredentialWatcher(logger, nil),
+	}
+}
+
+// writableRRSetRepository returns the record set repository of a zone,
+// failing for zones checkZoneWritable rejects.
+func (s *stackitDnsProviderResolver) writableRRSetRepository(
+	ctx context.Context,
+	cfg *StackitDnsProviderConfig,
+	zone string,
+) (repository.RRSetRepository, error) {
+	config, err := s.getRepositoryConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	zoneRepository, err := s.zoneRepositoryFactory.NewZoneRepository(config)
+	if err != nil {
+		return nil, err
+	}
+
+	zoneDnsName, err := repository.CanonicalZoneName(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	zoneResult, err := zoneRepository.FetchZone(ctx, zoneDnsName)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkZoneWritable(zoneResult); err != nil {
+		return nil, err
+	}
+
+	return s.rrSetRepositoryFactory.NewRRSetRepository(config, zoneResult.Id)
+}
@@ -4,23 +4,31 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"slices"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/cert-manager/cert-manager/pkg/acme/webhook"
 	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/metrics"
 	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
 	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
 	"go.uber.org/zap"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
 const typeTxtRecord = "TXT"
 
-var stackitAuthToken = os.Getenv("STACKIT_AUTH_TOKEN")
+// Operations of the solver, which some checks only apply to.
+const (
+	operationPresent = "present"
+	operationCleanUp = "cleanup"
+)
 
 func NewResolver(
 	httpClient *http.Client,
@@ -30,7 +38,11 @@ func NewResolver(
 	secretFetcher SecretFetcher,
 	configProvider ConfigProvider,
 ) webhook.Solver {
-	return &stackitDnsProviderResolver{
+	clientCache := repository.NewClientCache()
+	expiry := newCredentialExpiry(logger, expiryWarningThreshold(logger))
+	metrics.RegisterDiagnostic("credentials", expiry.report)
+
+	s := &stackitDnsProviderResolver{
 		ctx:                    context.Background(),
 		httpClient:             httpClient,
 		configProvider:         configProvider,
@@ -38,7 +50,22 @@ func NewResolver(
 		zoneRepositoryFactory:  zoneRepositoryFactory,
 		rrSetRepositoryFactory: rrSetRepositoryFactory,
 		logger:                 logger,
+		authToken:              os.Getenv("STACKIT_AUTH_TOKEN"),
+		authTokenPath:          os.Getenv("STACKIT_AUTH_TOKEN_PATH"),
+		authTokenDir:           os.Getenv("STACKIT_AUTH_TOKEN_DIR"),
+		clientCache:            clientCache,
+		credentials: newCredentialWatcher(logger, func(string) {
+			clientCache.Invalidate()
+		}),
+		expiry:            expiry,
+		rrSetPollInterval: defaultRRSetPollInterval,
+		zonePollInterval:  defaultZonePollInterval,
+		nsResolver:        net.DefaultResolver,
+		delegations:       newDelegationChecks(),
 	}
+	metrics.RegisterDiagnostic("delegation", s.delegationReport)
+
+	return s
 }
 
 type stackitDnsProviderResolver struct {
@@ -49,6 +76,19 @@ type stackitDnsProviderResolver struct {
 	zoneRepositoryFactory  repository.ZoneRepositoryFactory
 	rrSetRepositoryFactory repository.RRSetRepositoryFactory
 	logger                 *zap.Logger
+	authToken              string
+	authTokenPath          string
+	authTokenDir           string
+	clientCache            *repository.ClientCache
+	credentials            *credentialWatcher
+	expiry                 *credentialExpiry
+	rrSetPollInterval      time.Duration
+	zonePollInterval       time.Duration
+	leases                 *leaseCoordinator
+	nsResolver             *net.Resolver
+	delegations            *delegationChecks
+	events                 *challengeEvents
+	loggedDelays           sync.Map
 }
 
 // Name is used as the name for this DNS solver when referencing it on the ACME
@@ -67,23 +107,60 @@ func (s *stackitDnsProviderResolver) Name() string {
 // cert-manager itself will later perform a self check to ensure that the
 // solver has correctly configured the DNS provider.
 func (s *stackitDnsProviderResolver) Present(ch *v1alpha1.ChallengeRequest) error {
-	initResolverRes, err := s.initializeResolverContext(ch)
+	initResolverRes, err := s.initializeResolverContext(ch, operationPresent)
 	if err != nil {
 		return err
 	}
 
-	rrSet, err := initResolverRes.rrSetRepository.FetchRRSetForZone(
-		s.ctx,
-		initResolverRes.rrSetName,
-		typeTxtRecord,
-	)
-	if errors.Is(err, repository.ErrRRSetNotFound) {
-		return s.handleRRSetNotFound(initResolverRes, ch.Key)
-	} else if err != nil {
+	if err := s.checkPropagationDelay(ch, initResolverRes); err != nil {
+		return err
+	}
+
+	if initResolverRes.delegationCheck != nil {
+		if err := s.presentDelegationCheck(ch, initResolverRes); err != nil {
+			return err
+		}
+	}
+
+	if initResolverRes.caaCheck != nil {
+		if err := s.checkCAA(ch, initResolverRes); err != nil {
+			s.logger.Error("CAA check failed", zap.Error(err), zap.String("rrSetName", initResolverRes.rrSetName))
+
+			return err
+		}
+	}
+
+	if err := s.presentLocked(initResolverRes, ch.Key); err != nil {
+		return err
+	}
+
+	// Waiting for propagation does not need the record set lock.
+	if initResolverRes.propagationCheck != nil {
+		return s.awaitPropagation(initResolverRes, ch.Key)
+	}
+
+	return nil
+}
+
+func (s *stackitDnsProviderResolver) presentLocked(
+	initResolverRes *initResolverContextResult,
+	challengeKey string,
+) error {
+	release, err := s.lockRRSet(initResolverRes)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := s.presentChallengeKey(initResolverRes, challengeKey); err != nil {
 		return err
 	}
 
-	return s.updateExistingRRSet(initResolverRes, rrSet, ch.Key)
+	if initResolverRes.verifyWrites {
+		return s.verifyWrite(initResolverRes, challengeKey, operationPresent)
+	}
+
+	return nil
 }
 
 // CleanUp should delete the relevant TXT record from the DNS provider console.
@@ -93,12 +170,28 @@ func (s *stackitDnsProviderResolver) Present(ch *v1alpha1.ChallengeRequest) erro
 // This is in order to facilitate multiple DNS validations for the same domain
 // concurrently.
 func (s *stackitDnsProviderResolver) CleanUp(ch *v1alpha1.ChallengeRequest) error {
-	initResolverRes, err := s.initializeResolverContext(ch)
+	initResolverRes, err := s.initializeResolverContext(ch, operationCleanUp)
 	if err != nil {
 		return s.handleErrorDuringInitialization(err)
 	}
 
-	return s.handleRRSetCleanup(initResolverRes, ch.Key)
+	release, err := s.lockRRSet(initResolverRes)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	s.forgetPropagationDelay(initResolverRes)
+
+	if err := s.handleRRSetCleanup(initResolverRes, ch.Key); err != nil {
+		return err
+	}
+
+	if initResolverRes.verifyWrites {
+		return s.verifyWrite(initResolverRes, ch.Key, operationCleanUp)
+	}
+
+	return nil
 }
 
 // Initialize will be called when the webhook first starts.
@@ -123,9 +216,43 @@ func (s *stackitDnsProviderResolver) Initialize(
 		return err
 	}
 
-	s.secretFetcher = &kubeSecretFetcher{
-		client: cl,
-		ctx:    s.ctx,
+	secretCache := newCachedSecretFetcher(
+		cl,
+		s.ctx,
+		s.logger,
+		os.Getenv("SECRET_CACHE_LABEL_SELECTOR"),
+		stopCh,
+		s.credentials.secretUpdated,
+	)
+	s.secretFetcher = secretCache
+	metrics.RegisterDiagnostic("secretCache", secretCache.report)
+
+	dynamicClient, err := dynamic.NewForConfig(kubeClientConfig)
+	if err != nil {
+		s.logger.Error("Error initializing dynamic kubernetes client", zap.Error(err))
+
+		return err
+	}
+	s.events = newChallengeEvents(dynamicClient, cl.CoreV1(), leaseIdentity(), s.logger)
+
+	if err := s.credentials.run(stopCh); err != nil {
+		s.logger.Error("Error starting credential watcher", zap.Error(err))
+
+		return err
+	}
+	if s.expiry != nil {
+		go s.expiry.run(expiryCheckInterval, stopCh)
+	}
+	go s.runDelegationRechecks(delegationRecheckInterval, stopCh)
+
+	if namespace := os.Getenv("COORDINATION_LEASE_NAMESPACE"); namespace != "" {
+		identity := leaseIdentity()
+		s.leases = newLeaseCoordinator(cl.CoordinationV1(), namespace, identity, s.logger)
+		s.logger.Info(
+			"Coordinating record set changes via leases",
+			zap.String("namespace", namespace),
+			zap.String("identity", identity),
+		)
 	}
 
 	s.logger.Info("Stackit resolver initialized")
@@ -135,18 +262,23 @@ func (s *stackitDnsProviderResolver) Initialize(
 
 func (s *stackitDnsProviderResolver) initializeResolverContext(
 	ch *v1alpha1.ChallengeRequest,
+	operation string,
 ) (*initResolverContextResult, error) {
 	cfg, err := s.configProvider.LoadConfig(ch.Config)
 	if err != nil {
 		return nil, err
 	}
 
+	zoneDnsName, rrSetName, err := s.challengeNames(ch, &cfg, operation)
+	if err != nil {
+		return nil, err
+	}
+
 	config, err := s.getRepositoryConfig(&cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	zoneDnsName, rrSetName := getZoneDnsNameAndRRSetName(ch)
 	zoneRepository, err := s.zoneRepositoryFactory.NewZoneRepository(config)
 	if err != nil {
 		s.logger.Error("Error creating zone repository", zap.Error(err))
@@ -154,6 +286,71 @@ func (s *stackitDnsProviderResolver) initializeResolverContext(
 		return nil, err
 	}
 
+	zone, err := s.fetchZone(zoneRepository, zoneDnsName, cfg.ZoneUpdateTimeout.Duration, operation)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := s.writeTTL(&cfg, zone, operation)
+	if err != nil {
+		return nil, err
+	}
+
+	rrSetRepository, err := s.rrSetRepositoryFactory.NewRRSetRepository(config, zone.Id)
+	if err != nil {
+		s.logger.Error("Error creating RRSet repository", zap.Error(err))
+
+		return nil, err
+	}
+
+	return &initResolverContextResult{
+		rrSetRepository:        rrSetRepository,
+		zoneRepository:         zoneRepository,
+		zone:                   zone,
+		projectId:              cfg.ProjectId,
+		zoneId:                 zone.Id,
+		rrSetName:              rrSetName,
+		ttl:                    ttl,
+		ttlPolicy:              cfg.TTLPolicy,
+		verifyWrites:           cfg.VerifyWrites,
+		propagationCheck:       cfg.PropagationCheck,
+		delegationCheck:        cfg.DelegationCheck,
+		propagationDelayBudget: cfg.PropagationDelayBudget.Duration,
+		caaCheck:               cfg.CAACheck,
+	}, nil
+}
+
+// challengeNames returns the canonical zone and record set names of a
+// challenge after checking them.
+func (s *stackitDnsProviderResolver) challengeNames(
+	ch *v1alpha1.ChallengeRequest,
+	cfg *StackitDnsProviderConfig,
+	operation string,
+) (string, string, error) {
+	zoneDnsName, rrSetName, err := getZoneDnsNameAndRRSetName(ch, cfg.AccountURI)
+	if err != nil {
+		return "", "", err
+	}
+	if IsPersistentRRSet(rrSetName, nil) {
+		return "", "", fmt.Errorf("%w: %s is not a challenge record", ErrPersistentRecord, rrSetName)
+	}
+
+	if err := s.checkChallenge(cfg.ChallengeValidation, operation, ch, zoneDnsName, rrSetName); err != nil {
+		return "", "", err
+	}
+
+	return zoneDnsName, rrSetName, nil
+}
+
+// fetchZone fetches the zone. For Present it checks that record sets can be
+// written to it, waiting for a running zone update if configured. CleanUp only
+// skips secondary zones and otherwise just tries its delete.
+func (s *stackitDnsProviderResolver) fetchZone(
+	zoneRepository repository.ZoneRepository,
+	zoneDnsName string,
+	updateTimeout time.Duration,
+	operation string,
+) (*stackitdnsclient.Zone, error) {
 	s.logger.Info("Fetching zone", zap.String("zoneDnsName", zoneDnsName))
 
 	zone, err := zoneRepository.FetchZone(s.ctx, zoneDnsName)
@@ -169,18 +366,39 @@ func (s *stackitDnsProviderResolver) initializeResolverContext(
 
 	s.logger.Info("Zone fetched", zap.String("zoneDnsName", zoneDnsName))
 
-	rrSetRepository, err := s.rrSetRepositoryFactory.NewRRSetRepository(config, zone.Id)
+	if operation == operationPresent {
+		zone, err = s.awaitZoneUpdate(zoneRepository, zone, updateTimeout)
+		if err == nil {
+			err = checkZoneWritable(zone)
+		}
+	} else {
+		err = checkZonePrimary(zone)
+	}
 	if err != nil {
-		s.logger.Error("Error creating RRSet repository", zap.Error(err))
+		s.logger.Error("Zone cannot be changed", zap.Error(err), zap.String("zoneDnsName", zoneDnsName))
 
 		return nil, err
 	}
 
-	return &initResolverContextResult{
-		rrSetRepository:   rrSetRepository,
-		rrSetName:         rrSetName,
-		acmeTxtDefaultTTL: cfg.AcmeTxtRecordTTL,
-	}, nil
+	return zone, nil
+}
+
+func (s *stackitDnsProviderResolver) presentChallengeKey(
+	initResolverRes *initResolverContextResult,
+	challengeKey string,
+) error {
+	rrSet, err := s.fetchRRSetForWrite(initResolverRes)
+	if errors.Is(err, repository.ErrRRSetNotFound) {
+		return s.handleRRSetNotFound(initResolverRes, challengeKey)
+	} else if err != nil {
+		return err
+	}
+
+	if IsPersistentRRSet(initResolverRes.rrSetName, rrSet) {
+		return fmt.Errorf("%w: not adding a challenge key to %s", ErrPersistentRecord, initResolverRes.rrSetName)
+	}
+
+	return s.updateExistingRRSet(initResolverRes, rrSet, challengeKey)
 }
 
 func (s *stackitDnsProviderResolver) createRRSet(
@@ -194,7 +412,7 @@ func (s *stackitDnsProviderResolver) createRRSet(
 				Content: key,
 			},
 		},
-		Ttl:  initResolverRes.acmeTxtDefaultTTL,
+		Ttl:  initResolverRes.ttl,
 		Type: typeTxtRecord,
 	}
 
@@ -203,80 +421,88 @@ func (s *stackitDnsProviderResolver) createRRSet(
 	return initResolverRes.rrSetRepository.CreateRRSet(s.ctx, rrSet)
 }
 
-// getAuthToken from Kubernetes secretFetcher.
-func (s *stackitDnsProviderResolver) getAuthToken(cfg *StackitDnsProviderConfig) (string, error) {
-	if stackitAuthToken != "" {
-		return stackitAuthToken, nil
-	}
-
-	token, err := s.secretFetcher.StringFromSecret(
-		cfg.AuthTokenSecretNamespace,
-		cfg.AuthTokenSecretRef,
-		cfg.AuthTokenSecretKey,
-	)
-	if err != nil {
-		return "", err
-	}
-
-	return token, nil
+func (s *stackitDnsProviderResolver) getRepositoryConfig(
+	cfg *StackitDnsProviderConfig,
+) (repository.Config, error) {
+	return s.loadRepositoryConfig(cfg, s.secretFetcher)
 }
 
-// geSaKeyPath gets the Service Account Key Path from the environment.
-func (s *stackitDnsProviderResolver) getSaKeyPath(cfg *StackitDnsProviderConfig) string {
-	if cfg.ServiceAccountKeyPath != "" {
-		return cfg.ServiceAccountKeyPath
-	}
-
-	return os.Getenv("STACKIT_SERVICE_ACCOUNT_KEY_PATH")
-}
+// refreshRepositoryConfig reloads credentials after the DNS API rejected them,
+// re-reading mounted files and bypassing the Secret cache.
+func (s *stackitDnsProviderResolver) refreshRepositoryConfig(
+	cfg *StackitDnsProviderConfig,
+) (repository.Config, error) {
+	s.logger.Info("Credentials rejected by the DNS API, reloading them", zap.String("projectId", cfg.ProjectId))
+	s.credentials.reloadAll()
 
-func (s *stackitDnsProviderResolver) checkUseSaAuthentication(cfg *StackitDnsProviderConfig) bool {
-	return s.getSaKeyPath(cfg) != ""
+	return s.loadRepositoryConfig(cfg, liveSecretFetcher(s.secretFetcher))
 }
 
-func (s *stackitDnsProviderResolver) getRepositoryConfig(
+func (s *stackitDnsProviderResolver) loadRepositoryConfig(
 	cfg *StackitDnsProviderConfig,
+	secretFetcher SecretFetcher,
 ) (repository.Config, error) {
+	credential, authMethod, err := s.resolveCredential(cfg, secretFetcher)
+	if err != nil {
+		return repository.Config{}, err
+	}
+	if s.expiry != nil {
+		s.expiry.observe(authMethod, credential)
+	}
+
 	config := repository.Config{
 		ApiBasePath:           cfg.ApiBasePath,
 		ProjectId:             cfg.ProjectId,
 		HttpClient:            s.httpClient,
-		UseSaKey:              false,
+		AuthToken:             credential.AuthToken,
+		SaKeyPath:             credential.SaKeyPath,
+		SaKey:                 credential.SaKey,
+		UseSaKey:              credential.isServiceAccountKey(),
 		ServiceAccountBaseUrl: cfg.ServiceAccountBaseUrl,
-	}
-
-	switch {
-	case s.checkUseSaAuthentication(cfg):
-		config.SaKeyPath = s.getSaKeyPath(cfg)
-		config.UseSaKey = true
-		s.logger.Info(
-			"Using service account key for authentication",
-			zap.String("saKeyPath", config.SaKeyPath),
-			zap.String("serviceAccountBaseUrl", config.ServiceAccountBaseUrl),
-		)
-	default:
-		authToken, err := s.getAuthToken(cfg)
-		if err != nil {
-			return repository.Config{}, err
-		}
-		config.AuthToken = authToken
-		s.logger.Info("Using auth token for authentication")
+		ClientCache:           s.clientCache,
+		RefreshCredentials: func() (repository.Config, error) {
+			return s.refreshRepositoryConfig(cfg)
+		},
 	}
 
 	return config, nil
 }
 
-func getZoneDnsNameAndRRSetName(ch *v1alpha1.ChallengeRequest) (string, string) {
-	// Remove trailing . from domain
-	domain := strings.TrimSuffix(ch.ResolvedZone, ".")
+// getZoneDnsNameAndRRSetName canonicalizes the challenge names, so challenges
+// for mixed-case or internationalized domains use the same record set.
+func getZoneDnsNameAndRRSetName(ch *v1alpha1.ChallengeRequest, accountURI string) (string, string, error) {
+	zoneDnsName, err := repository.CanonicalZoneName(ch.ResolvedZone)
+	if err != nil {
+		return "", "", err
+	}
+
+	rrSetName, err := repository.CanonicalRRSetName(ch.ResolvedFQDN, zoneDnsName)
+	if err != nil {
+		return "", "", err
+	}
+
+	// dns-01 challenges are validated at _acme-challenge.<domain>, so only
+	// dns-account-01 challenges use the name of the account.
+	if ch.Type != challengeTypeDNSAccount01 {
+		accountURI = ""
+	}
+	rrSetName, err = accountScopedName(rrSetName, accountURI)
+	if err != nil {
+		return "", "", err
+	}
 
-	return domain, ch.ResolvedFQDN
+	return zoneDnsName, rrSetName, nil
 }
 
 func (s *stackitDnsProviderResolver) handleErrorDuringInitialization(
 	err error,
 ) error {
-	if errors.Is(err, repository.ErrZoneNotFound) {
+	// Nothing was written to a secondary zone, so there is nothing to clean up,
+	// and persistent authorizations are never cleaned up.
+	if errors.Is(err, repository.ErrZoneNotFound) || errors.Is(err, ErrSecondaryZone) ||
+		errors.Is(err, ErrPersistentRecord) {
+		s.logger.Info("Nothing to clean up", zap.Error(err))
+
 		return nil
 	}
 
@@ -289,15 +515,17 @@ func (s *stackitDnsProviderResolver) handleRRSetCleanup(
 ) error {
 	s.logger.Info("Cleaning up RRSet", zap.String("rrSetName", initResolverRes.rrSetName))
 
-	rrSet, err := initResolverRes.rrSetRepository.FetchRRSetForZone(
-		s.ctx,
-		initResolverRes.rrSetName,
-		typeTxtRecord,
-	)
+	rrSet, err := s.fetchRRSetForWrite(initResolverRes)
 	if err != nil {
 		return s.handleFetchRRSetError(err, initResolverRes.rrSetName)
 	}
 
+	if IsPersistentRRSet(initResolverRes.rrSetName, rrSet) {
+		s.logger.Warn("Not cleaning up persistent authorizations", zap.String("rrSetName", initResolverRes.rrSetName))
+
+		return nil
+	}
+
 	if rrSet == nil || len(rrSet.Records) == 0 {
 		return s.deleteRRSet(initResolverRes.rrSetRepository, rrSet, initResolverRes.rrSetName)
 	}
@@ -305,7 +533,7 @@ func (s *stackitDnsProviderResolver) handleRRSetCleanup(
 	originalLen := len(rrSet.Records)
 
 	rrSet.Records = slices.DeleteFunc(rrSet.Records, func(r stackitdnsclient.Record) bool {
-		return r.Content == challengeKey
+		return txtContentMatches(r.Content, challengeKey)
 	})
 
 	if len(rrSet.Records) == originalLen {
@@ -383,12 +611,30 @@ func (s *stackitDnsProviderResolver) handleRRSetNotFound(
 	initResolverRes *initResolverContextResult,
 	challengeKey string,
 ) error {
+	reusable, err := s.findReusableRRSet(initResolverRes)
+	if err != nil {
+		s.logger.Error(
+			"Error checking for inactive RRSet",
+			zap.Error(err),
+			zap.String("rrSetName", initResolverRes.rrSetName),
+		)
+
+		return err
+	}
+	if reusable != nil {
+		return s.reactivateRRSet(initResolverRes, reusable, challengeKey)
+	}
+
 	s.logger.Info(
 		"RRSet not found, creating new RRSet",
 		zap.String("rrSetName", initResolverRes.rrSetName),
 	)
 
-	if err := s.createRRSet(initResolverRes, challengeKey); err != nil {
+	err = s.createRRSet(initResolverRes, challengeKey)
+	if errors.Is(err, repository.ErrConflict) {
+		return s.recoverFromCreateConflict(initResolverRes, challengeKey)
+	}
+	if err != nil {
 		s.logger.Error(
 			"Error creating RRSet",
 			zap.Error(err),
@@ -405,7 +651,7 @@ func (s *stackitDnsProviderResolver) handleRRSetNotFound(
 
 func keyExists(records []stackitdnsclient.Record, challengeKey string) bool {
 	for _, record := range records {
-		if record.Content == challengeKey {
+		if txtContentMatches(record.Content, challengeKey) {
 			return true
 		}
 	}
@@ -428,7 +674,9 @@ func (s *stackitDnsProviderResolver) updateExistingRRSet(
 		rrSet.Records = append(rrSet.Records, newRecord)
 	}
 
-	rrSet.Ttl = initResolverRes.acmeTxtDefaultTTL
+	if initResolverRes.ttlPolicy != TTLPolicyKeep {
+		rrSet.Ttl = initResolverRes.ttl
+	}
 
 	if err := initResolverRes.rrSetRepository.UpdateRRSet(s.ctx, *rrSet); err != nil {
 		s.logger.Error(
@@ -446,7 +694,17 @@ func (s *stackitDnsProviderResolver) updateExistingRRSet(
 }
 
 type initResolverContextResult struct {
-	rrSetRepository   repository.RRSetRepository
-	rrSetName         string
-	acmeTxtDefaultTTL int32
+	rrSetRepository        repository.RRSetRepository
+	zoneRepository         repository.ZoneRepository
+	zone                   *stackitdnsclient.Zone
+	projectId              string
+	zoneId                 string
+	rrSetName              string
+	ttl                    int32
+	ttlPolicy              string
+	verifyWrites           bool
+	propagationCheck       *PropagationCheckConfig
+	delegationCheck        *DelegationCheckConfig
+	propagationDelayBudget time.Duration
+	caaCheck               *CAACheckConfig
 }
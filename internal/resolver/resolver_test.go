@@ -2,21 +2,30 @@ package resolver_test
 
 import (
 	"fmt"
+	"iter"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/cert-manager/cert-manager/pkg/acme/webhook"
 	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/metrics"
 	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
 	repository_mock "github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository/mock"
 	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/resolver"
 	resolver_mock "github.com/stackitcloud/stackit-cert-manager-webhook/internal/resolver/mock"
 	stackitdnsclient_new "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
 	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/rest"
 )
 
@@ -30,6 +39,9 @@ var (
 const (
 	targetKey = "delete-me"
 	keepKey   = "keep-me"
+
+	canonicalZoneName  = "xn--bcher-kva.example"
+	canonicalRRSetName = "_acme-challenge.xn--bcher-kva.example."
 )
 
 func TestName(t *testing.T) {
@@ -62,6 +74,55 @@ func TestInitialize(t *testing.T) {
 	})
 }
 
+func TestPresentPicksUpRotatedServiceAccountKey(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	keyPath := filepath.Join(t.TempDir(), "sa.json")
+	require.NoError(t, os.WriteFile(keyPath, []byte("key-v1"), 0o600))
+
+	configProvider := resolver_mock.NewMockConfigProvider(ctrl)
+	configProvider.EXPECT().
+		LoadConfig(gomock.Any()).
+		Return(resolver.StackitDnsProviderConfig{ServiceAccountKeyPath: keyPath}, nil).
+		AnyTimes()
+
+	var usedKey atomic.Value
+	zoneRepositoryFactory := repository_mock.NewMockZoneRepositoryFactory(ctrl)
+	zoneRepositoryFactory.EXPECT().
+		NewZoneRepository(gomock.Any()).
+		DoAndReturn(func(cfg repository.Config) (repository.ZoneRepository, error) {
+			usedKey.Store(cfg.SaKey)
+
+			return nil, fmt.Errorf("stop after credential selection")
+		}).
+		AnyTimes()
+
+	r := resolver.NewResolver(&http.Client{}, zap.NewNop(), zoneRepositoryFactory, nil, nil, configProvider)
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	require.NoError(t, r.Initialize(&rest.Config{}, stopCh))
+
+	require.Error(t, r.Present(challengeRequest))
+	require.Equal(t, "key-v1", usedKey.Load())
+
+	rotationsBefore := testutil.ToFloat64(metrics.CredentialRotations.WithLabelValues("serviceAccountKeyFile"))
+	tmp := keyPath + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte("key-v2"), 0o600))
+	require.NoError(t, os.Rename(tmp, keyPath))
+
+	require.Eventually(t, func() bool {
+		_ = r.Present(challengeRequest)
+
+		return usedKey.Load() == "key-v2"
+	}, 5*time.Second, 20*time.Millisecond)
+	require.Greater(
+		t,
+		testutil.ToFloat64(metrics.CredentialRotations.WithLabelValues("serviceAccountKeyFile")),
+		rotationsBefore,
+	)
+}
+
 type presentSuite struct {
 	suite.Suite
 	ctrl                       *gomock.Controller
@@ -202,6 +263,9 @@ func (s *presentSuite) TestSuccessCreateRRSet() {
 	s.mockRRSetRepository.EXPECT().
 		FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
 		Return(nil, repository.ErrRRSetNotFound)
+	s.mockRRSetRepository.EXPECT().
+		ListRRSets(gomock.Any(), gomock.Any()).
+		Return(rrSetSeq())
 	s.mockRRSetRepository.EXPECT().
 		CreateRRSet(gomock.Any(), gomock.Any()).
 		Return(nil)
@@ -369,6 +433,81 @@ func (s *presentSuite) TestPresentRRSetWithEmptyRecords() {
 	s.NoError(err)
 }
 
+func (s *presentSuite) TestPresentReactivatesInactiveRRSet() {
+	challenge := &v1alpha1.ChallengeRequest{Config: configJson, Key: "new-key"}
+	s.mockConfigProvider.EXPECT().
+		LoadConfig(gomock.Any()).
+		Return(resolver.StackitDnsProviderConfig{}, nil)
+	s.mockSecretFetcher.EXPECT().
+		StringFromSecret(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("", nil)
+	s.mockZoneRepositoryFactory.EXPECT().
+		NewZoneRepository(gomock.Any()).
+		Return(s.mockZoneRepository, nil)
+	s.mockZoneRepository.EXPECT().
+		FetchZone(gomock.Any(), gomock.Any()).
+		Return(&stackitdnsclient_new.Zone{Id: "test"}, nil)
+	s.mockRRSetRepositoryFactory.EXPECT().
+		NewRRSetRepository(gomock.Any(), gomock.Any()).
+		Return(s.mockRRSetRepository, nil)
+	s.mockRRSetRepository.EXPECT().
+		FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, repository.ErrRRSetNotFound)
+	s.mockRRSetRepository.EXPECT().
+		ListRRSets(gomock.Any(), gomock.Any()).
+		Return(rrSetSeq(stackitdnsclient_new.RecordSet{
+			Id:      "inactive",
+			Active:  new(false),
+			State:   stackitdnsclient_new.RECORDSETSTATE_UPDATE_SUCCEEDED,
+			Records: []stackitdnsclient_new.Record{{Content: "stale-key"}},
+		}))
+	s.mockRRSetRepository.EXPECT().
+		RestoreRRSet(gomock.Any(), "inactive").
+		Return(nil)
+	s.mockRRSetRepository.EXPECT().
+		UpdateRRSet(gomock.Any(), matchedBy(func(rrSet stackitdnsclient_new.RecordSet) bool {
+			return rrSet.Id == "inactive" && len(rrSet.Records) == 1 && rrSet.Records[0].Content == "new-key"
+		})).
+		Return(nil)
+
+	err := s.resolver.Present(challenge)
+	s.NoError(err)
+}
+
+func (s *presentSuite) TestPresentFailReactivateRRSet() {
+	s.mockConfigProvider.EXPECT().
+		LoadConfig(gomock.Any()).
+		Return(resolver.StackitDnsProviderConfig{}, nil)
+	s.mockSecretFetcher.EXPECT().
+		StringFromSecret(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("", nil)
+	s.mockZoneRepositoryFactory.EXPECT().
+		NewZoneRepository(gomock.Any()).
+		Return(s.mockZoneRepository, nil)
+	s.mockZoneRepository.EXPECT().
+		FetchZone(gomock.Any(), gomock.Any()).
+		Return(&stackitdnsclient_new.Zone{Id: "test"}, nil)
+	s.mockRRSetRepositoryFactory.EXPECT().
+		NewRRSetRepository(gomock.Any(), gomock.Any()).
+		Return(s.mockRRSetRepository, nil)
+	s.mockRRSetRepository.EXPECT().
+		FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, repository.ErrRRSetNotFound)
+	s.mockRRSetRepository.EXPECT().
+		ListRRSets(gomock.Any(), gomock.Any()).
+		Return(rrSetSeq(stackitdnsclient_new.RecordSet{
+			Id:     "inactive",
+			Active: new(false),
+			State:  stackitdnsclient_new.RECORDSETSTATE_CREATE_SUCCEEDED,
+		}))
+	s.mockRRSetRepository.EXPECT().
+		RestoreRRSet(gomock.Any(), "inactive").
+		Return(fmt.Errorf("error restoring rr set"))
+
+	err := s.resolver.Present(challengeRequest)
+	s.ErrorContains(err, "error restoring rr set")
+}
+
 func (s *presentSuite) TestFailCreateRRSet() {
 	s.mockConfigProvider.EXPECT().
 		LoadConfig(gomock.Any()).
@@ -388,6 +527,9 @@ func (s *presentSuite) TestFailCreateRRSet() {
 	s.mockRRSetRepository.EXPECT().
 		FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
 		Return(nil, repository.ErrRRSetNotFound)
+	s.mockRRSetRepository.EXPECT().
+		ListRRSets(gomock.Any(), gomock.Any()).
+		Return(rrSetSeq())
 	s.mockRRSetRepository.EXPECT().
 		CreateRRSet(gomock.Any(), gomock.Any()).
 		Return(fmt.Errorf("error creating rr set"))
@@ -449,6 +591,9 @@ func (s *presentSuite) TestTTLPropagation() {
 	s.mockRRSetRepository.EXPECT().
 		FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
 		Return(nil, repository.ErrRRSetNotFound)
+	s.mockRRSetRepository.EXPECT().
+		ListRRSets(gomock.Any(), gomock.Any()).
+		Return(rrSetSeq())
 	s.mockRRSetRepository.EXPECT().
 		CreateRRSet(gomock.Any(), matchedBy(func(rrSet stackitdnsclient_new.RecordSet) bool {
 			return rrSet.Ttl == ttl
@@ -487,6 +632,49 @@ func (s *presentSuite) TestTTLPropagation() {
 	s.NoError(err)
 }
 
+func (s *presentSuite) TestPresentZoneTTLPolicy() {
+	s.mockConfigProvider.EXPECT().
+		LoadConfig(gomock.Any()).
+		Return(resolver.StackitDnsProviderConfig{TTLPolicy: resolver.TTLPolicyZone, AcmeTxtRecordTTL: 600}, nil).
+		Times(2)
+	s.mockSecretFetcher.EXPECT().
+		StringFromSecret(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("", nil).
+		Times(2)
+	s.mockZoneRepositoryFactory.EXPECT().
+		NewZoneRepository(gomock.Any()).
+		Return(s.mockZoneRepository, nil).
+		Times(2)
+
+	// A TTL the API would reject fails before any record set is touched.
+	s.mockZoneRepository.EXPECT().
+		FetchZone(gomock.Any(), gomock.Any()).
+		Return(&stackitdnsclient_new.Zone{Id: "test", DefaultTTL: 3600, NegativeCache: 10}, nil)
+
+	err := s.resolver.Present(challengeRequest)
+	s.ErrorIs(err, resolver.ErrInvalidTTL)
+
+	s.mockZoneRepository.EXPECT().
+		FetchZone(gomock.Any(), gomock.Any()).
+		Return(&stackitdnsclient_new.Zone{Id: "test", DefaultTTL: 3600, NegativeCache: 300}, nil)
+	s.mockRRSetRepositoryFactory.EXPECT().
+		NewRRSetRepository(gomock.Any(), gomock.Any()).
+		Return(s.mockRRSetRepository, nil)
+	s.mockRRSetRepository.EXPECT().
+		FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, repository.ErrRRSetNotFound)
+	s.mockRRSetRepository.EXPECT().
+		ListRRSets(gomock.Any(), gomock.Any()).
+		Return(rrSetSeq())
+	s.mockRRSetRepository.EXPECT().
+		CreateRRSet(gomock.Any(), matchedBy(func(rrSet stackitdnsclient_new.RecordSet) bool {
+			return rrSet.Ttl == 300
+		})).
+		Return(nil)
+
+	s.NoError(s.resolver.Present(challengeRequest))
+}
+
 func (s *presentSuite) TestAuthMethodSelection() {
 	// Test Service Account
 	s.Run("Service Account", func() {
@@ -509,6 +697,9 @@ func (s *presentSuite) TestAuthMethodSelection() {
 		s.mockRRSetRepository.EXPECT().
 			FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
 			Return(nil, repository.ErrRRSetNotFound)
+		s.mockRRSetRepository.EXPECT().
+			ListRRSets(gomock.Any(), gomock.Any()).
+			Return(rrSetSeq())
 		s.mockRRSetRepository.EXPECT().
 			CreateRRSet(gomock.Any(), gomock.Any()).
 			Return(nil)
@@ -541,6 +732,9 @@ func (s *presentSuite) TestAuthMethodSelection() {
 		s.mockRRSetRepository.EXPECT().
 			FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
 			Return(nil, repository.ErrRRSetNotFound)
+		s.mockRRSetRepository.EXPECT().
+			ListRRSets(gomock.Any(), gomock.Any()).
+			Return(rrSetSeq())
 		s.mockRRSetRepository.EXPECT().
 			CreateRRSet(gomock.Any(), gomock.Any()).
 			Return(nil)
@@ -550,6 +744,156 @@ func (s *presentSuite) TestAuthMethodSelection() {
 	})
 }
 
+func (s *presentSuite) TestPresentCanonicalizesNames() {
+	req := &v1alpha1.ChallengeRequest{
+		Config:       configJson,
+		Key:          "new-key",
+		ResolvedZone: "Bücher.Example.",
+		ResolvedFQDN: "_ACME-Challenge.Bücher.Example.",
+	}
+
+	s.mockConfigProvider.EXPECT().
+		LoadConfig(gomock.Any()).
+		Return(resolver.StackitDnsProviderConfig{}, nil)
+	s.mockSecretFetcher.EXPECT().
+		StringFromSecret(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("", nil)
+	s.mockZoneRepositoryFactory.EXPECT().
+		NewZoneRepository(gomock.Any()).
+		Return(s.mockZoneRepository, nil)
+	s.mockZoneRepository.EXPECT().
+		FetchZone(gomock.Any(), canonicalZoneName).
+		Return(&stackitdnsclient_new.Zone{Id: "test"}, nil)
+	s.mockRRSetRepositoryFactory.EXPECT().
+		NewRRSetRepository(gomock.Any(), gomock.Any()).
+		Return(s.mockRRSetRepository, nil)
+	s.mockRRSetRepository.EXPECT().
+		FetchRRSetForZone(gomock.Any(), canonicalRRSetName, "TXT").
+		Return(nil, repository.ErrRRSetNotFound)
+	s.mockRRSetRepository.EXPECT().
+		ListRRSets(gomock.Any(), gomock.Any()).
+		Return(rrSetSeq())
+	s.mockRRSetRepository.EXPECT().
+		CreateRRSet(gomock.Any(), matchedBy(func(rrSet stackitdnsclient_new.RecordSet) bool {
+			return rrSet.Name == canonicalRRSetName
+		})).
+		Return(nil)
+
+	s.NoError(s.resolver.Present(req))
+}
+
+func (s *presentSuite) TestPresentInvalidName() {
+	req := &v1alpha1.ChallengeRequest{
+		Config:       configJson,
+		Key:          "new-key",
+		ResolvedZone: "test.com.",
+		ResolvedFQDN: "_acme-challenge..test.com.",
+	}
+
+	s.mockConfigProvider.EXPECT().
+		LoadConfig(gomock.Any()).
+		Return(resolver.StackitDnsProviderConfig{}, nil)
+
+	err := s.resolver.Present(req)
+	s.ErrorIs(err, repository.ErrInvalidName)
+}
+
+func (s *presentSuite) TestPresentRejectsInvalidChallengeWhenStrict() {
+	req := &v1alpha1.ChallengeRequest{
+		Config:       configJson,
+		Key:          "new-key",
+		ResolvedZone: "test.com.",
+		ResolvedFQDN: "www.othertest.com.",
+	}
+
+	// Neither credentials nor repositories are touched for a rejected request.
+	s.mockConfigProvider.EXPECT().
+		LoadConfig(gomock.Any()).
+		Return(resolver.StackitDnsProviderConfig{ChallengeValidation: resolver.ChallengeValidationStrict}, nil)
+
+	err := s.resolver.Present(req)
+	s.ErrorIs(err, resolver.ErrInvalidChallenge)
+}
+
+func (s *presentSuite) TestPresentRejectsSecondaryZone() {
+	s.mockConfigProvider.EXPECT().
+		LoadConfig(gomock.Any()).
+		Return(resolver.StackitDnsProviderConfig{}, nil)
+	s.mockSecretFetcher.EXPECT().
+		StringFromSecret(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("", nil)
+	s.mockZoneRepositoryFactory.EXPECT().
+		NewZoneRepository(gomock.Any()).
+		Return(s.mockZoneRepository, nil)
+	s.mockZoneRepository.EXPECT().
+		FetchZone(gomock.Any(), gomock.Any()).
+		Return(&stackitdnsclient_new.Zone{
+			Id:        "test",
+			DnsName:   "test.com",
+			Type:      stackitdnsclient_new.ZONETYPE_SECONDARY,
+			Primaries: []string{"192.0.2.1"},
+		}, nil)
+
+	// No record set repository is created for a zone that cannot be changed.
+	err := s.resolver.Present(challengeRequest)
+	s.ErrorIs(err, resolver.ErrSecondaryZone)
+	s.ErrorContains(err, "192.0.2.1")
+}
+
+func (s *presentSuite) TestPresentRefusesPersistentRecord() {
+	req := &v1alpha1.ChallengeRequest{
+		Config:       configJson,
+		ResolvedFQDN: "_validation-persist.test.com.",
+		ResolvedZone: "test.com.",
+	}
+	s.mockConfigProvider.EXPECT().
+		LoadConfig(gomock.Any()).
+		Return(resolver.StackitDnsProviderConfig{}, nil)
+
+	err := s.resolver.Present(req)
+	s.ErrorIs(err, resolver.ErrPersistentRecord)
+}
+
+func (s *presentSuite) TestPresentFailsOnCAA() {
+	req := &v1alpha1.ChallengeRequest{
+		Config:       configJson,
+		DNSName:      "www.test.com",
+		Key:          "key",
+		ResolvedZone: "test.com.",
+		ResolvedFQDN: "_acme-challenge.www.test.com.",
+	}
+
+	s.mockConfigProvider.EXPECT().
+		LoadConfig(gomock.Any()).
+		Return(resolver.StackitDnsProviderConfig{
+			CAACheck: &resolver.CAACheckConfig{IssuerDomain: "letsencrypt.org"},
+		}, nil)
+	s.mockSecretFetcher.EXPECT().
+		StringFromSecret(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("", nil)
+	s.mockZoneRepositoryFactory.EXPECT().
+		NewZoneRepository(gomock.Any()).
+		Return(s.mockZoneRepository, nil)
+	s.mockZoneRepository.EXPECT().
+		FetchZone(gomock.Any(), "test.com").
+		Return(&stackitdnsclient_new.Zone{Id: "test", DnsName: "test.com"}, nil)
+	s.mockRRSetRepositoryFactory.EXPECT().
+		NewRRSetRepository(gomock.Any(), gomock.Any()).
+		Return(s.mockRRSetRepository, nil)
+	// The challenge record is not written.
+	s.mockRRSetRepository.EXPECT().
+		ListRRSets(gomock.Any(), repository.RRSetListOptions{Type: "CAA", ActiveOnly: true}).
+		Return(rrSetSeq(stackitdnsclient_new.RecordSet{
+			Name:    "test.com.",
+			Type:    "CAA",
+			Records: []stackitdnsclient_new.Record{{Content: `0 issue "sectigo.com"`}},
+		}))
+
+	err := s.resolver.Present(req)
+	s.ErrorIs(err, resolver.ErrCAANotPermitted)
+	s.ErrorContains(err, "issue only allows sectigo.com")
+}
+
 type cleanSuite struct {
 	presentSuite
 }
@@ -606,6 +950,35 @@ func (s *cleanSuite) TestFailFetchNoRRSet() {
 	s.NoError(err)
 }
 
+func (s *cleanSuite) TestCleanUp_IgnoresInvalidTTL() {
+	s.mockConfigProvider.EXPECT().
+		LoadConfig(gomock.Any()).
+		Return(resolver.StackitDnsProviderConfig{TTLPolicy: resolver.TTLPolicyZone}, nil)
+	s.mockSecretFetcher.EXPECT().
+		StringFromSecret(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("", nil)
+	s.mockZoneRepositoryFactory.EXPECT().
+		NewZoneRepository(gomock.Any()).
+		Return(s.mockZoneRepository, nil)
+	s.mockZoneRepository.EXPECT().
+		FetchZone(gomock.Any(), gomock.Any()).
+		Return(&stackitdnsclient_new.Zone{Id: "test", DefaultTTL: 3600, NegativeCache: 10}, nil)
+	s.mockRRSetRepositoryFactory.EXPECT().
+		NewRRSetRepository(gomock.Any(), gomock.Any()).
+		Return(s.mockRRSetRepository, nil)
+
+	// CleanUp writes no TTL, so a TTL Present would reject does not block it.
+	s.mockRRSetRepository.EXPECT().
+		FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&stackitdnsclient_new.RecordSet{Id: "1234", Records: []stackitdnsclient_new.Record{{Content: targetKey}}}, nil)
+	s.mockRRSetRepository.EXPECT().
+		DeleteRRSet(gomock.Any(), "1234").
+		Return(nil)
+
+	err := s.resolver.CleanUp(&v1alpha1.ChallengeRequest{Config: configJson, Key: targetKey})
+	s.NoError(err)
+}
+
 func (s *cleanSuite) TestCleanUp_RemovesOnlyKey_DeletesRRSet() {
 	s.setupCommonMocks()
 
@@ -691,6 +1064,132 @@ func (s *cleanSuite) TestCleanUp_KeyNotFound_DoesNothing() {
 	s.NoError(err)
 }
 
+func (s *cleanSuite) TestCleanUp_CanonicalizesNames() {
+	req := &v1alpha1.ChallengeRequest{
+		Config:       configJson,
+		Key:          targetKey,
+		ResolvedZone: "XN--BCHER-KVA.example",
+		ResolvedFQDN: "_acme-challenge",
+	}
+
+	s.mockConfigProvider.EXPECT().
+		LoadConfig(gomock.Any()).
+		Return(resolver.StackitDnsProviderConfig{}, nil)
+	s.mockSecretFetcher.EXPECT().
+		StringFromSecret(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("", nil)
+	s.mockZoneRepositoryFactory.EXPECT().
+		NewZoneRepository(gomock.Any()).
+		Return(s.mockZoneRepository, nil)
+	s.mockZoneRepository.EXPECT().
+		FetchZone(gomock.Any(), canonicalZoneName).
+		Return(&stackitdnsclient_new.Zone{Id: "test"}, nil)
+	s.mockRRSetRepositoryFactory.EXPECT().
+		NewRRSetRepository(gomock.Any(), gomock.Any()).
+		Return(s.mockRRSetRepository, nil)
+	s.mockRRSetRepository.EXPECT().
+		FetchRRSetForZone(gomock.Any(), canonicalRRSetName, "TXT").
+		Return(&stackitdnsclient_new.RecordSet{
+			Id:      "1234",
+			Name:    canonicalRRSetName,
+			Records: []stackitdnsclient_new.Record{{Content: targetKey}},
+		}, nil)
+	s.mockRRSetRepository.EXPECT().
+		DeleteRRSet(gomock.Any(), "1234").
+		Return(nil)
+
+	s.NoError(s.resolver.CleanUp(req))
+}
+
+func (s *cleanSuite) TestCleanUp_SecondaryZone_DoesNothing() {
+	s.mockConfigProvider.EXPECT().
+		LoadConfig(gomock.Any()).
+		Return(resolver.StackitDnsProviderConfig{}, nil)
+	s.mockSecretFetcher.EXPECT().
+		StringFromSecret(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("", nil)
+	s.mockZoneRepositoryFactory.EXPECT().
+		NewZoneRepository(gomock.Any()).
+		Return(s.mockZoneRepository, nil)
+	s.mockZoneRepository.EXPECT().
+		FetchZone(gomock.Any(), gomock.Any()).
+		Return(&stackitdnsclient_new.Zone{Id: "test", Type: stackitdnsclient_new.ZONETYPE_SECONDARY}, nil)
+
+	s.NoError(s.resolver.CleanUp(challengeRequest))
+}
+
+func (s *cleanSuite) TestCleanUp_IgnoresZoneState() {
+	s.mockConfigProvider.EXPECT().
+		LoadConfig(gomock.Any()).
+		Return(resolver.StackitDnsProviderConfig{ZoneUpdateTimeout: metav1.Duration{Duration: time.Hour}}, nil)
+	s.mockSecretFetcher.EXPECT().
+		StringFromSecret(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("", nil)
+	s.mockZoneRepositoryFactory.EXPECT().
+		NewZoneRepository(gomock.Any()).
+		Return(s.mockZoneRepository, nil)
+	// The zone is fetched once, without waiting for the update.
+	s.mockZoneRepository.EXPECT().
+		FetchZone(gomock.Any(), gomock.Any()).
+		Return(&stackitdnsclient_new.Zone{Id: "test", State: stackitdnsclient_new.ZONESTATE_UPDATING}, nil)
+	s.mockRRSetRepositoryFactory.EXPECT().
+		NewRRSetRepository(gomock.Any(), gomock.Any()).
+		Return(s.mockRRSetRepository, nil)
+	s.mockRRSetRepository.EXPECT().
+		FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&stackitdnsclient_new.RecordSet{Id: "1234", Records: []stackitdnsclient_new.Record{{Content: targetKey}}}, nil)
+	s.mockRRSetRepository.EXPECT().
+		DeleteRRSet(gomock.Any(), "1234").
+		Return(nil)
+
+	s.NoError(s.resolver.CleanUp(&v1alpha1.ChallengeRequest{Config: configJson, Key: targetKey}))
+}
+
+func (s *cleanSuite) TestCleanUp_PersistentName_DoesNothing() {
+	req := &v1alpha1.ChallengeRequest{
+		Config:       configJson,
+		ResolvedFQDN: "_validation-persist.test.com.",
+		ResolvedZone: "test.com.",
+		Key:          targetKey,
+	}
+	s.mockConfigProvider.EXPECT().
+		LoadConfig(gomock.Any()).
+		Return(resolver.StackitDnsProviderConfig{}, nil)
+
+	s.NoError(s.resolver.CleanUp(req))
+}
+
+func (s *cleanSuite) TestCleanUp_PersistentComment_DoesNothing() {
+	s.setupCommonMocks()
+
+	req := &v1alpha1.ChallengeRequest{
+		Config: configJson,
+		Key:    targetKey,
+	}
+	rrset := stackitdnsclient_new.RecordSet{
+		Id:      "1234",
+		Comment: new("This record set is managed by stackit-cert-manager-webhook (dns-persist-01)"),
+		Records: []stackitdnsclient_new.Record{{Content: targetKey}},
+	}
+	s.mockRRSetRepository.EXPECT().
+		FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&rrset, nil)
+
+	// Neither DeleteRRSet nor UpdateRRSet is expected.
+	s.NoError(s.resolver.CleanUp(req))
+}
+
+// rrSetSeq returns an iterator over rrSets as returned by ListRRSets.
+func rrSetSeq(rrSets ...stackitdnsclient_new.RecordSet) iter.Seq2[stackitdnsclient_new.RecordSet, error] {
+	return func(yield func(stackitdnsclient_new.RecordSet, error) bool) {
+		for _, rrSet := range rrSets {
+			if !yield(rrSet, nil) {
+				return
+			}
+		}
+	}
+}
+
 func matchedBy[T any](fn func(T) bool) gomock.Matcher {
 	return matcher[T]{fn}
 }
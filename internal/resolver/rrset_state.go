@@ -0,0 +1,89 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"go.uber.org/zap"
+)
+
+// defaultRRSetPollInterval is how long to wait before fetching a record set
+// again, e.g. after a create conflict.
+const defaultRRSetPollInterval = 2 * time.Second
+
+// ErrRRSetPending is returned while an inactive record set of the challenge
+// name is still being created, updated or deleted. Creating a record set of
+// the same name would conflict with it, and cert-manager retries Present, so
+// the webhook request does not wait for it.
+var ErrRRSetPending = errors.New("record set has a pending operation")
+
+// findReusableRRSet looks for a record set of the challenge name that
+// FetchRRSetForZone skipped because it is not active. Only record sets that
+// were deactivated after their last operation succeeded are returned for
+// reuse. Deleted record sets and those of failed operations are left alone.
+func (s *stackitDnsProviderResolver) findReusableRRSet(
+	initResolverRes *initResolverContextResult,
+) (*stackitdnsclient.RecordSet, error) {
+	var reusable *stackitdnsclient.RecordSet
+
+	rrSets := initResolverRes.rrSetRepository.ListRRSets(s.ctx, repository.RRSetListOptions{
+		Name: initResolverRes.rrSetName,
+		Type: typeTxtRecord,
+	})
+	for rrSet, err := range rrSets {
+		if err != nil {
+			return nil, err
+		}
+		if rrSet.GetActive() {
+			continue
+		}
+
+		switch rrSet.State {
+		case stackitdnsclient.RECORDSETSTATE_CREATING,
+			stackitdnsclient.RECORDSETSTATE_UPDATING,
+			stackitdnsclient.RECORDSETSTATE_DELETING:
+			return nil, fmt.Errorf("%w: %s (%s) is %s, retrying later",
+				ErrRRSetPending, initResolverRes.rrSetName, rrSet.Id, rrSet.State)
+		case stackitdnsclient.RECORDSETSTATE_CREATE_SUCCEEDED, stackitdnsclient.RECORDSETSTATE_UPDATE_SUCCEEDED:
+			if reusable == nil {
+				reusable = &rrSet
+			}
+		default:
+		}
+	}
+
+	return reusable, nil
+}
+
+// reactivateRRSet restores a deactivated record set and sets the challenge
+// key as its only record. Records left in it belong to past challenges.
+func (s *stackitDnsProviderResolver) reactivateRRSet(
+	initResolverRes *initResolverContextResult,
+	rrSet *stackitdnsclient.RecordSet,
+	challengeKey string,
+) error {
+	s.logger.Info(
+		"Inactive RRSet found, reactivating RRSet",
+		zap.String("rrSetName", initResolverRes.rrSetName),
+		zap.String("rrSetId", rrSet.Id),
+		zap.String("state", string(rrSet.State)),
+	)
+
+	if err := initResolverRes.rrSetRepository.RestoreRRSet(s.ctx, rrSet.Id); err != nil {
+		s.logger.Error(
+			"Error reactivating RRSet",
+			zap.Error(err),
+			zap.String("rrSetName", initResolverRes.rrSetName),
+			zap.String("rrSetId", rrSet.Id),
+		)
+
+		return err
+	}
+
+	rrSet.Records = nil
+
+	return s.updateExistingRRSet(initResolverRes, rrSet, challengeKey)
+}
@@ -0,0 +1,117 @@
+package resolver
+
+import (
+	"context"
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	repository_mock "github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository/mock"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func rrSetSeq(rrSets ...stackitdnsclient.RecordSet) iter.Seq2[stackitdnsclient.RecordSet, error] {
+	return func(yield func(stackitdnsclient.RecordSet, error) bool) {
+		for _, rrSet := range rrSets {
+			if !yield(rrSet, nil) {
+				return
+			}
+		}
+	}
+}
+
+func newRRSetStateTestResolver() *stackitDnsProviderResolver {
+	return &stackitDnsProviderResolver{
+		ctx:               context.Background(),
+		logger:            zap.NewNop(),
+		rrSetPollInterval: time.Millisecond,
+	}
+}
+
+func TestHandleRRSetNotFound_InactiveRRSet(t *testing.T) {
+	t.Parallel()
+
+	inactive := func(state stackitdnsclient.RecordSetState) stackitdnsclient.RecordSet {
+		return stackitdnsclient.RecordSet{Id: string(state), Active: new(false), State: state}
+	}
+
+	t.Run("fails with a retryable error while the rrset is being deleted", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+		rrSetRepository.EXPECT().ListRRSets(gomock.Any(), repository.RRSetListOptions{
+			Name: "_acme-challenge.test.com.",
+			Type: typeTxtRecord,
+		}).Return(rrSetSeq(inactive(stackitdnsclient.RECORDSETSTATE_DELETING)))
+
+		err := newRRSetStateTestResolver().handleRRSetNotFound(&initResolverContextResult{
+			rrSetRepository: rrSetRepository,
+			rrSetName:       "_acme-challenge.test.com.",
+		}, "key")
+		require.ErrorIs(t, err, ErrRRSetPending)
+		require.ErrorContains(t, err, "_acme-challenge.test.com. (DELETING) is DELETING")
+	})
+
+	t.Run("reactivates a deactivated rrset", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+		gomock.InOrder(
+			rrSetRepository.EXPECT().ListRRSets(gomock.Any(), gomock.Any()).Return(rrSetSeq(
+				inactive(stackitdnsclient.RECORDSETSTATE_DELETE_SUCCEEDED),
+				inactive(stackitdnsclient.RECORDSETSTATE_UPDATE_SUCCEEDED),
+			)),
+			rrSetRepository.EXPECT().RestoreRRSet(gomock.Any(), "UPDATE_SUCCEEDED").Return(nil),
+			rrSetRepository.EXPECT().UpdateRRSet(gomock.Any(), gomock.Any()).Return(nil),
+		)
+
+		err := newRRSetStateTestResolver().handleRRSetNotFound(&initResolverContextResult{
+			rrSetRepository: rrSetRepository,
+			rrSetName:       "_acme-challenge.test.com.",
+		}, "key")
+		require.NoError(t, err)
+	})
+
+	t.Run("creates a new rrset next to deleted and failed ones", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+		gomock.InOrder(
+			rrSetRepository.EXPECT().ListRRSets(gomock.Any(), gomock.Any()).Return(rrSetSeq(
+				inactive(stackitdnsclient.RECORDSETSTATE_DELETE_SUCCEEDED),
+				inactive(stackitdnsclient.RECORDSETSTATE_CREATE_FAILED),
+			)),
+			rrSetRepository.EXPECT().CreateRRSet(gomock.Any(), gomock.Any()).Return(nil),
+		)
+
+		err := newRRSetStateTestResolver().handleRRSetNotFound(&initResolverContextResult{
+			rrSetRepository: rrSetRepository,
+			rrSetName:       "_acme-challenge.test.com.",
+		}, "key")
+		require.NoError(t, err)
+	})
+}
+
+func TestFindReusableRRSet_SkipsActive(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+	rrSetRepository.EXPECT().ListRRSets(gomock.Any(), gomock.Any()).Return(rrSetSeq(
+		stackitdnsclient.RecordSet{Id: "active", Active: new(true), State: stackitdnsclient.RECORDSETSTATE_CREATING},
+		stackitdnsclient.RecordSet{Id: "inactive", Active: new(false), State: stackitdnsclient.RECORDSETSTATE_CREATE_SUCCEEDED},
+	))
+
+	reusable, err := newRRSetStateTestResolver().findReusableRRSet(&initResolverContextResult{
+		rrSetRepository: rrSetRepository,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "inactive", reusable.Id)
+}
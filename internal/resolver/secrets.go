@@ -3,11 +3,26 @@ package resolver
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
 
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/metrics"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
+// secretCacheSyncTimeout bounds how long a first read in a namespace waits for
+// the informer before falling back to a live GET.
+const secretCacheSyncTimeout = 5 * time.Second
+
 //go:generate mockgen -destination=./mock/secrets.go -source=./secrets.go SecretFetcher
 type SecretFetcher interface {
 	StringFromSecret(namespace, secretName, key string) (string, error)
@@ -24,10 +39,14 @@ func (k *kubeSecretFetcher) StringFromSecret(namespace, secretName, key string)
 		return "", err
 	}
 
+	return stringFromSecretData(secret, key)
+}
+
+func stringFromSecretData(secret *corev1.Secret, key string) (string, error) {
 	binary, ok := secret.Data[key]
 	if !ok {
 		return "", fmt.Errorf("key `%q` not found in secretFetcher `%s/%s`",
-			key, namespace, secretName)
+			key, secret.Namespace, secret.Name)
 	}
 
 	return string(binary), nil
@@ -36,3 +55,224 @@ func (k *kubeSecretFetcher) StringFromSecret(namespace, secretName, key string)
 func NewSecretFetcher() SecretFetcher {
 	return &kubeSecretFetcher{}
 }
+
+// NewKubeconfigSecretFetcher reads Secrets for commands running outside the
+// webhook server. The client is configured like kubectl's, from KUBECONFIG or
+// ~/.kube/config, falling back to the in-cluster config. Without any of them
+// reading a Secret fails with the reason.
+func NewKubeconfigSecretFetcher(ctx context.Context) SecretFetcher {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{},
+	).ClientConfig()
+	if err != nil {
+		return unavailableSecretFetcher{err: err}
+	}
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return unavailableSecretFetcher{err: err}
+	}
+
+	return &kubeSecretFetcher{client: client, ctx: ctx}
+}
+
+type unavailableSecretFetcher struct {
+	err error
+}
+
+func (u unavailableSecretFetcher) StringFromSecret(namespace, secretName, _ string) (string, error) {
+	return "", fmt.Errorf("cannot read secret %s/%s: %w", namespace, secretName, u.err)
+}
+
+// cachedSecretFetcher serves Secrets from shared informers. Informers are
+// started lazily for the namespaces that are actually referenced, optionally
+// restricted by a label selector. Reads that miss the cache fall back to a
+// live GET.
+type cachedSecretFetcher struct {
+	live          *kubeSecretFetcher
+	logger        *zap.Logger
+	labelSelector string
+	stopCh        <-chan struct{}
+	onUpdate      func(secret *corev1.Secret)
+
+	mu         sync.Mutex
+	namespaces map[string]*namespaceSecretCache
+}
+
+type namespaceSecretCache struct {
+	informer cache.SharedIndexInformer
+	lister   corelisters.SecretLister
+
+	mu         sync.RWMutex
+	lastUpdate time.Time
+}
+
+func newCachedSecretFetcher(
+	client kubernetes.Interface,
+	ctx context.Context,
+	logger *zap.Logger,
+	labelSelector string,
+	stopCh <-chan struct{},
+	onUpdate func(secret *corev1.Secret),
+) *cachedSecretFetcher {
+	return &cachedSecretFetcher{
+		live:          &kubeSecretFetcher{client: client, ctx: ctx},
+		logger:        logger,
+		labelSelector: labelSelector,
+		stopCh:        stopCh,
+		onUpdate:      onUpdate,
+		namespaces:    map[string]*namespaceSecretCache{},
+	}
+}
+
+func (c *cachedSecretFetcher) StringFromSecret(namespace, secretName, key string) (string, error) {
+	nsCache := c.namespaceCache(namespace)
+
+	if nsCache.informer.HasSynced() {
+		secret, err := nsCache.lister.Secrets(namespace).Get(secretName)
+		if err == nil {
+			metrics.SecretCacheRequests.WithLabelValues("hit").Inc()
+
+			return stringFromSecretData(secret, key)
+		}
+	}
+
+	metrics.SecretCacheRequests.WithLabelValues("miss").Inc()
+	c.logger.Debug(
+		"Secret cache miss, reading secret from the API server",
+		zap.String("namespace", namespace),
+		zap.String("secretName", secretName),
+	)
+
+	return c.live.StringFromSecret(namespace, secretName, key)
+}
+
+// SecretCacheReport is one entry of the secretCache diagnostics report.
+type SecretCacheReport struct {
+	Namespace  string    `json:"namespace"`
+	Synced     bool      `json:"synced"`
+	LastUpdate time.Time `json:"lastUpdate,omitzero"`
+}
+
+// report returns the freshness of every namespace cache, for the
+// diagnostics endpoint.
+func (c *cachedSecretFetcher) report() any {
+	c.mu.Lock()
+	namespaces := slices.Sorted(maps.Keys(c.namespaces))
+	c.mu.Unlock()
+
+	reports := make([]SecretCacheReport, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		lastUpdate, synced := c.freshness(namespace)
+		reports = append(reports, SecretCacheReport{Namespace: namespace, Synced: synced, LastUpdate: lastUpdate})
+	}
+
+	return reports
+}
+
+// freshness returns when the cache of a namespace last received data from the
+// API server. The second return value is false if the namespace is not cached.
+func (c *cachedSecretFetcher) freshness(namespace string) (time.Time, bool) {
+	c.mu.Lock()
+	nsCache, ok := c.namespaces[namespace]
+	c.mu.Unlock()
+
+	if !ok || !nsCache.informer.HasSynced() {
+		return time.Time{}, false
+	}
+
+	nsCache.mu.RLock()
+	defer nsCache.mu.RUnlock()
+
+	return nsCache.lastUpdate, true
+}
+
+func (c *cachedSecretFetcher) namespaceCache(namespace string) *namespaceSecretCache {
+	c.mu.Lock()
+	nsCache, ok := c.namespaces[namespace]
+	if ok {
+		c.mu.Unlock()
+
+		return nsCache
+	}
+
+	nsCache = c.startNamespaceCache(namespace)
+	c.namespaces[namespace] = nsCache
+	c.mu.Unlock()
+
+	syncCh := make(chan struct{})
+	timer := time.AfterFunc(secretCacheSyncTimeout, func() { close(syncCh) })
+	defer timer.Stop()
+
+	if !cache.WaitForCacheSync(mergeStop(c.stopCh, syncCh), nsCache.informer.HasSynced) {
+		c.logger.Warn("Secret cache not synced yet", zap.String("namespace", namespace))
+	}
+
+	return nsCache
+}
+
+func (c *cachedSecretFetcher) startNamespaceCache(namespace string) *namespaceSecretCache {
+	informer := coreinformers.NewFilteredSecretInformer(
+		c.live.client,
+		namespace,
+		0,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+		func(options *metav1.ListOptions) {
+			options.LabelSelector = c.labelSelector
+		},
+	)
+	nsCache := &namespaceSecretCache{
+		informer: informer,
+		lister:   corelisters.NewSecretLister(informer.GetIndexer()),
+	}
+
+	touch := func(obj any) {
+		nsCache.mu.Lock()
+		nsCache.lastUpdate = time.Now()
+		nsCache.mu.Unlock()
+		metrics.SecretCacheLastUpdate.WithLabelValues(namespace).SetToCurrentTime()
+
+		if secret, ok := obj.(*corev1.Secret); ok && c.onUpdate != nil {
+			c.onUpdate(secret)
+		}
+	}
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    touch,
+		UpdateFunc: func(_, newObj any) { touch(newObj) },
+		DeleteFunc: func(any) { touch(nil) },
+	})
+	if err != nil {
+		c.logger.Error("Error registering secret cache handler", zap.Error(err), zap.String("namespace", namespace))
+	}
+
+	c.logger.Info("Starting secret cache", zap.String("namespace", namespace))
+
+	go informer.Run(c.stopCh)
+
+	return nsCache
+}
+
+// liveSecretFetcher returns a SecretFetcher that bypasses the cache of
+// secretFetcher, if it has one.
+func liveSecretFetcher(secretFetcher SecretFetcher) SecretFetcher {
+	if cached, ok := secretFetcher.(*cachedSecretFetcher); ok {
+		return cached.live
+	}
+
+	return secretFetcher
+}
+
+// mergeStop returns a channel closed as soon as either a or b is closed.
+func mergeStop(a, b <-chan struct{}) <-chan struct{} {
+	merged := make(chan struct{})
+	go func() {
+		defer close(merged)
+		select {
+		case <-a:
+		case <-b:
+		}
+	}()
+
+	return merged
+}
@@ -2,12 +2,18 @@ package resolver
 
 import (
 	"context"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestStringFromSecret(t *testing.T) {
@@ -42,3 +48,86 @@ func TestStringFromSecret(t *testing.T) {
 	_, err = fetcher.StringFromSecret("test-namespace", "non-existent-secret", "test-key")
 	assert.Error(t, err)
 }
+
+func TestCachedSecretFetcher(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "cached",
+				Namespace: "test-namespace",
+				Labels:    map[string]string{"webhook": "stackit"},
+			},
+			Data: map[string][]byte{"test-key": []byte("cached-value")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "unlabeled",
+				Namespace: "test-namespace",
+			},
+			Data: map[string][]byte{"test-key": []byte("live-value")},
+		},
+	)
+
+	var liveGets atomic.Int32
+	client.PrependReactor("get", "secrets", func(k8stesting.Action) (bool, runtime.Object, error) {
+		liveGets.Add(1)
+
+		return false, nil, nil
+	})
+
+	updates := make(chan *corev1.Secret, 10)
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+
+	fetcher := newCachedSecretFetcher(
+		client,
+		context.TODO(),
+		zap.NewNop(),
+		"webhook=stackit",
+		stopCh,
+		func(secret *corev1.Secret) { updates <- secret },
+	)
+
+	_, ok := fetcher.freshness("test-namespace")
+	assert.False(t, ok, "namespace should not be cached before first use")
+
+	// served from the informer
+	value, err := fetcher.StringFromSecret("test-namespace", "cached", "test-key")
+	require.NoError(t, err)
+	assert.Equal(t, "cached-value", value)
+	assert.Equal(t, int32(0), liveGets.Load())
+
+	freshness, _ := fetcher.freshness("test-namespace")
+	assert.False(t, freshness.IsZero())
+	want := SecretCacheReport{Namespace: "test-namespace", Synced: true, LastUpdate: freshness}
+	assert.Equal(t, []SecretCacheReport{want}, fetcher.report())
+
+	// filtered out by the label selector, falls back to a live GET
+	value, err = fetcher.StringFromSecret("test-namespace", "unlabeled", "test-key")
+	require.NoError(t, err)
+	assert.Equal(t, "live-value", value)
+	assert.Equal(t, int32(1), liveGets.Load())
+
+	_, err = fetcher.StringFromSecret("test-namespace", "cached", "non-existent-key")
+	assert.Error(t, err)
+
+	// updates are propagated to the cache and the update hook
+	_, err = client.CoreV1().Secrets("test-namespace").Update(context.TODO(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cached",
+			Namespace: "test-namespace",
+			Labels:    map[string]string{"webhook": "stackit"},
+		},
+		Data: map[string][]byte{"test-key": []byte("rotated-value")},
+	}, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		value, err := fetcher.StringFromSecret("test-namespace", "cached", "test-key")
+
+		return err == nil && value == "rotated-value"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NotEmpty(t, updates)
+}
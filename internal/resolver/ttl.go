@@ -0,0 +1,101 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"go.uber.org/zap"
+)
+
+// Values of `ttlPolicy` in the issuer config.
+const (
+	// TTLPolicyIssuer sets acmeTxtRecordTTL on created and updated record sets.
+	TTLPolicyIssuer = "issuer"
+	// TTLPolicyKeep sets acmeTxtRecordTTL on created record sets and keeps the
+	// TTL of existing ones.
+	TTLPolicyKeep = "keep"
+	// TTLPolicyZone derives the TTL from the zone, see zoneTTL.
+	TTLPolicyZone = "zone"
+)
+
+// The range the DNS API accepts for record set TTLs.
+const (
+	minRRSetTTL = 60
+	maxRRSetTTL = 99999999
+)
+
+var ErrInvalidTTL = errors.New("invalid ttl")
+
+func isKnownTTLPolicy(policy string) bool {
+	switch policy {
+	case TTLPolicyIssuer, TTLPolicyKeep, TTLPolicyZone:
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidateTTL checks ttl against the range the DNS API accepts, so an invalid
+// value fails before the API answers with a 400.
+func ValidateTTL(ttl int32) error {
+	if ttl < minRRSetTTL || ttl > maxRRSetTTL {
+		return fmt.Errorf("%w: %d is outside of %d..%d", ErrInvalidTTL, ttl, minRRSetTTL, maxRRSetTTL)
+	}
+
+	return nil
+}
+
+// rrSetTTL returns the TTL to write for the configured policy. An empty
+// policy behaves like TTLPolicyIssuer.
+func rrSetTTL(cfg *StackitDnsProviderConfig, zone *stackitdnsclient.Zone) (int32, error) {
+	if cfg.TTLPolicy == TTLPolicyZone {
+		return zoneTTL(zone)
+	}
+
+	if cfg.AcmeTxtRecordTTL != 0 {
+		if err := ValidateTTL(cfg.AcmeTxtRecordTTL); err != nil {
+			return 0, fmt.Errorf("acmeTxtRecordTTL: %w", err)
+		}
+	}
+
+	return cfg.AcmeTxtRecordTTL, nil
+}
+
+// writeTTL resolves the TTL Present writes. CleanUp never sets a TTL, so it
+// is neither resolved nor validated there.
+func (s *stackitDnsProviderResolver) writeTTL(
+	cfg *StackitDnsProviderConfig,
+	zone *stackitdnsclient.Zone,
+	operation string,
+) (int32, error) {
+	if operation != operationPresent {
+		return 0, nil
+	}
+
+	ttl, err := rrSetTTL(cfg, zone)
+	if err != nil {
+		s.logger.Error("Error determining TTL", zap.Error(err), zap.String("ttlPolicy", cfg.TTLPolicy))
+
+		return 0, err
+	}
+
+	return ttl, nil
+}
+
+// zoneTTL is the smaller of the zone's default TTL and negative cache TTL.
+// Resolvers cache the absence of the challenge record for the negative cache
+// TTL, so a challenge record cached for longer than that gains nothing.
+func zoneTTL(zone *stackitdnsclient.Zone) (int32, error) {
+	ttl := zone.DefaultTTL
+	if zone.NegativeCache > 0 && (ttl == 0 || zone.NegativeCache < ttl) {
+		ttl = zone.NegativeCache
+	}
+
+	if err := ValidateTTL(ttl); err != nil {
+		return 0, fmt.Errorf("zone %s (defaultTTL %d, negativeCache %d): %w",
+			zone.DnsName, zone.DefaultTTL, zone.NegativeCache, err)
+	}
+
+	return ttl, nil
+}
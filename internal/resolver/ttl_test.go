@@ -0,0 +1,102 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	repository_mock "github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository/mock"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestZoneTTL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		defaultTTL    int32
+		negativeCache int32
+		expected      int32
+		err           bool
+	}{
+		{name: "negative cache is smaller", defaultTTL: 3600, negativeCache: 300, expected: 300},
+		{name: "default ttl is smaller", defaultTTL: 120, negativeCache: 3600, expected: 120},
+		{name: "no negative cache", defaultTTL: 3600, expected: 3600},
+		{name: "no default ttl", negativeCache: 900, expected: 900},
+		{name: "nothing set", err: true},
+		{name: "below minimum", defaultTTL: 3600, negativeCache: 30, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ttl, err := zoneTTL(&stackitdnsclient.Zone{
+				DnsName:       "test.com",
+				DefaultTTL:    tt.defaultTTL,
+				NegativeCache: tt.negativeCache,
+			})
+			if tt.err {
+				require.ErrorIs(t, err, ErrInvalidTTL)
+
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, ttl)
+		})
+	}
+}
+
+func TestRRSetTTL(t *testing.T) {
+	t.Parallel()
+
+	zone := &stackitdnsclient.Zone{DefaultTTL: 3600, NegativeCache: 300}
+
+	for _, policy := range []string{"", TTLPolicyIssuer, TTLPolicyKeep} {
+		ttl, err := rrSetTTL(&StackitDnsProviderConfig{TTLPolicy: policy, AcmeTxtRecordTTL: 600}, zone)
+		require.NoError(t, err)
+		require.Equal(t, int32(600), ttl)
+	}
+
+	ttl, err := rrSetTTL(&StackitDnsProviderConfig{TTLPolicy: TTLPolicyZone, AcmeTxtRecordTTL: 600}, zone)
+	require.NoError(t, err)
+	require.Equal(t, int32(300), ttl)
+}
+
+func TestUpdateExistingRRSetTTLPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		policy   string
+		expected int32
+	}{
+		{policy: TTLPolicyIssuer, expected: 600},
+		{policy: TTLPolicyZone, expected: 600},
+		{policy: TTLPolicyKeep, expected: 7200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+			rrSetRepository.EXPECT().
+				UpdateRRSet(gomock.Any(), gomock.Cond(func(rrSet stackitdnsclient.RecordSet) bool {
+					return rrSet.Ttl == tt.expected
+				})).
+				Return(nil)
+
+			s := &stackitDnsProviderResolver{ctx: context.Background(), logger: zap.NewNop()}
+			err := s.updateExistingRRSet(&initResolverContextResult{
+				rrSetRepository: rrSetRepository,
+				rrSetName:       "_acme-challenge.test.com.",
+				ttl:             600,
+				ttlPolicy:       tt.policy,
+			}, &stackitdnsclient.RecordSet{Id: "rrset", Ttl: 7200}, validChallengeKey)
+			require.NoError(t, err)
+		})
+	}
+}
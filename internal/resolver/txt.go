@@ -0,0 +1,151 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxCharacterStringLength is the maximum length of an RFC 1035
+// character-string.
+const maxCharacterStringLength = 255
+
+// ParseTXTContent splits TXT record content into its RFC 1035
+// character-strings. Content not starting with a quote is taken literally as
+// a single string, which is how the challenge key is written. Quoted strings
+// may be separated by whitespace and use `\"`, `\\` and `\DDD` escapes.
+func ParseTXTContent(content string) ([]string, error) {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, `"`) {
+		return []string{content}, nil
+	}
+
+	var strs []string
+	for i := 0; i < len(content); {
+		if isTXTSpace(content[i]) {
+			i++
+
+			continue
+		}
+		if content[i] != '"' {
+			return nil, fmt.Errorf("unexpected %q at offset %d of TXT content", content[i], i)
+		}
+
+		str, next, err := parseCharacterString(content, i+1)
+		if err != nil {
+			return nil, err
+		}
+		if next < len(content) && !isTXTSpace(content[next]) {
+			return nil, fmt.Errorf("missing separator at offset %d of TXT content", next)
+		}
+
+		strs = append(strs, str)
+		i = next
+	}
+
+	return strs, nil
+}
+
+// parseCharacterString reads a quoted character-string starting after its
+// opening quote and returns it with the offset following the closing quote.
+func parseCharacterString(content string, start int) (string, int, error) {
+	var b strings.Builder
+
+	for i := start; i < len(content); {
+		switch c := content[i]; c {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 >= len(content) {
+				return "", 0, fmt.Errorf("dangling escape at offset %d of TXT content", i)
+			}
+			if !isDigit(content[i+1]) {
+				b.WriteByte(content[i+1])
+				i += 2
+
+				continue
+			}
+			if i+3 >= len(content) || !isDigit(content[i+2]) || !isDigit(content[i+3]) {
+				return "", 0, fmt.Errorf("invalid decimal escape at offset %d of TXT content", i)
+			}
+			value := int(content[i+1]-'0')*100 + int(content[i+2]-'0')*10 + int(content[i+3]-'0')
+			if value > 255 {
+				return "", 0, fmt.Errorf("decimal escape out of range at offset %d of TXT content", i)
+			}
+			b.WriteByte(byte(value))
+			i += 4
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated string in TXT content")
+}
+
+// encodeTXTContent quotes value as RFC 1035 character-strings, splitting it
+// into strings of at most 255 bytes.
+func encodeTXTContent(value string) string {
+	var strs []string
+	for start := 0; start < len(value); start += maxCharacterStringLength {
+		strs = append(strs, value[start:min(start+maxCharacterStringLength, len(value))])
+	}
+
+	return EncodeTXTStrings(strs)
+}
+
+// EncodeTXTStrings returns the TXT record content of character-strings, each
+// quoted and escaped as in the zone file format the API uses, e.g.
+// `"part 1" "part 2"`. It is the inverse of ParseTXTContent.
+func EncodeTXTStrings(strs []string) string {
+	if len(strs) == 0 {
+		return `""`
+	}
+
+	var b strings.Builder
+	for i, str := range strs {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+
+		b.WriteByte('"')
+		for _, c := range []byte(str) {
+			switch {
+			case c == '"' || c == '\\':
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			case c < ' ' || c > '~':
+				fmt.Fprintf(&b, "\\%03d", c)
+			default:
+				b.WriteByte(c)
+			}
+		}
+		b.WriteByte('"')
+	}
+
+	return b.String()
+}
+
+// txtValue returns the value of TXT content with its character-strings
+// joined. Content that cannot be parsed is returned as is.
+func txtValue(content string) string {
+	strs, err := ParseTXTContent(content)
+	if err != nil {
+		return content
+	}
+
+	return strings.Join(strs, "")
+}
+
+// txtContentMatches reports whether TXT content holds value, regardless of
+// quoting, splitting and escaping.
+func txtContentMatches(content, value string) bool {
+	return content == value || txtValue(content) == value
+}
+
+func isTXTSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,110 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTXTContent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{name: "unquoted", content: "abc", want: []string{"abc"}},
+		{name: "quoted", content: `"abc"`, want: []string{"abc"}},
+		{name: "split", content: `"ab" "c"`, want: []string{"ab", "c"}},
+		{name: "split without space", content: `"ab""c"`, wantErr: true},
+		{name: "escaped quote and backslash", content: `"a\"b\\c"`, want: []string{`a"b\c`}},
+		{name: "escaped character", content: `"a\bc"`, want: []string{"abc"}},
+		{name: "decimal escape", content: `"a\032b"`, want: []string{"a b"}},
+		{name: "empty string", content: `""`, want: []string{""}},
+		{name: "surrounding whitespace", content: ` "abc"  `, want: []string{"abc"}},
+		{name: "unterminated", content: `"abc`, wantErr: true},
+		{name: "dangling escape", content: `"abc\`, wantErr: true},
+		{name: "short decimal escape", content: `"a\03"`, wantErr: true},
+		{name: "decimal escape out of range", content: `"a\256"`, wantErr: true},
+		{name: "garbage between strings", content: `"a" b "c"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseTXTContent(tt.content)
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEncodeTXTContent(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, `"abc"`, encodeTXTContent("abc"))
+	require.Equal(t, `"a\"b\\c\010"`, encodeTXTContent("a\"b\\c\n"))
+	require.Equal(t, `""`, encodeTXTContent(""))
+
+	long := strings.Repeat("a", 300)
+	require.Equal(t, `"`+strings.Repeat("a", 255)+`" "`+strings.Repeat("a", 45)+`"`, encodeTXTContent(long))
+
+	require.Equal(t, `"part 1" "" "part;3"`, EncodeTXTStrings([]string{"part 1", "", "part;3"}))
+	require.Equal(t, `""`, EncodeTXTStrings(nil))
+}
+
+func TestKeyExistsNormalizesContent(t *testing.T) {
+	t.Parallel()
+
+	key := "0123456789abcdefghijklmnopqrstuvwxyzABCDEFG"
+	for _, content := range []string{key, `"` + key + `"`, `"0123456789" "abcdefghijklmnopqrstuvwxyzABCDEFG"`} {
+		require.True(t, keyExists([]stackitdnsclient.Record{{Content: content}}, key), content)
+	}
+	require.False(t, keyExists([]stackitdnsclient.Record{{Content: `"other"`}}, key))
+}
+
+func FuzzTXTContentRoundTrip(f *testing.F) {
+	for _, seed := range []string{"", "abc", `a"b\c`, "\x00\xff", strings.Repeat("x", 600)} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, value string) {
+		encoded := encodeTXTContent(value)
+
+		strs, err := ParseTXTContent(encoded)
+		require.NoError(t, err)
+		for _, str := range strs {
+			require.LessOrEqual(t, len(str), maxCharacterStringLength)
+		}
+		require.Equal(t, value, strings.Join(strs, ""))
+		require.True(t, txtContentMatches(encoded, value))
+	})
+}
+
+func FuzzParseTXTContent(f *testing.F) {
+	for _, seed := range []string{"abc", `"abc"`, `"a" "b"`, `"a\"b"`, `"\065"`, `"abc`, `"\`, `"a\25"`} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, content string) {
+		strs, err := ParseTXTContent(content)
+		if err != nil {
+			return
+		}
+
+		value := strings.Join(strs, "")
+		reparsed, err := ParseTXTContent(encodeTXTContent(value))
+		require.NoError(t, err)
+		require.Equal(t, value, strings.Join(reparsed, ""))
+	})
+}
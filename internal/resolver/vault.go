@@ -0,0 +1,188 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	vaultRequestTimeout = 10 * time.Second
+	vaultKvVersion1     = 1
+	vaultKvVersion2     = 2
+)
+
+// VaultConfig points to a credential stored in a Vault-compatible KV secrets
+// engine. Exactly one of TokenKey and ServiceAccountKeyKey is read. The Vault
+// server and the token used to log in are deployment settings, so an issuer
+// cannot send the webhook's Vault token elsewhere.
+type VaultConfig struct {
+	Namespace            string `json:"namespace"`
+	Mount                string `json:"mount"`
+	Path                 string `json:"path"`
+	KvVersion            int    `json:"kvVersion"`
+	TokenKey             string `json:"tokenKey"`
+	ServiceAccountKeyKey string `json:"serviceAccountKeyKey"`
+	// Address and TokenPath are no longer read from the issuer and are only
+	// kept to reject configs setting them, see VAULT_ADDR and VAULT_TOKEN_PATH.
+	Address   string `json:"address"`
+	TokenPath string `json:"tokenPath"`
+}
+
+// vaultProvider reads credentials from a Vault KV v1 or v2 engine over the
+// plain HTTP API. address is VAULT_ADDR; the token is read from tokenPath
+// (VAULT_TOKEN_PATH) or taken from token (VAULT_TOKEN).
+type vaultProvider struct {
+	httpClient  *http.Client
+	credentials *credentialWatcher
+	address     string
+	token       string
+	tokenPath   string
+}
+
+func (p *vaultProvider) Name() string { return AuthMethodVault }
+
+func (p *vaultProvider) Credential(cfg *StackitDnsProviderConfig) (Credential, error) {
+	if cfg.Vault == nil {
+		return Credential{}, ErrCredentialNotConfigured
+	}
+
+	data, err := p.readSecret(cfg.Vault)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	if cfg.Vault.ServiceAccountKeyKey != "" {
+		saKey, ok := data[cfg.Vault.ServiceAccountKeyKey]
+		if !ok {
+			return Credential{}, fmt.Errorf("key %q not found in vault secret %q", cfg.Vault.ServiceAccountKeyKey, cfg.Vault.Path)
+		}
+
+		return Credential{SaKey: saKey, Origin: vaultOrigin(cfg.Vault)}, nil
+	}
+
+	token, ok := data[cfg.Vault.TokenKey]
+	if !ok {
+		return Credential{}, fmt.Errorf("key %q not found in vault secret %q", cfg.Vault.TokenKey, cfg.Vault.Path)
+	}
+
+	return Credential{AuthToken: token, Origin: vaultOrigin(cfg.Vault)}, nil
+}
+
+func (p *vaultProvider) readSecret(vault *VaultConfig) (map[string]string, error) {
+	if p.address == "" {
+		return nil, fmt.Errorf("no vault address configured, set VAULT_ADDR")
+	}
+
+	vaultToken, err := p.vaultToken()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), vaultRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, vaultSecretURL(p.address, vault), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", vaultToken)
+	if vault.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", vault.Namespace)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error reading vault secret %q: %w", vault.Path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error reading vault secret %q: status code %d", vault.Path, resp.StatusCode)
+	}
+
+	return decodeVaultSecret(body, vault.KvVersion)
+}
+
+func (p *vaultProvider) vaultToken() (string, error) {
+	if p.tokenPath == "" {
+		if p.token == "" {
+			return "", fmt.Errorf("no vault token configured, set VAULT_TOKEN_PATH or VAULT_TOKEN")
+		}
+
+		return p.token, nil
+	}
+
+	token, err := p.credentials.fileContent(p.tokenPath, credentialSourceVaultTokenFile)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(token), nil
+}
+
+func vaultSecretURL(address string, vault *VaultConfig) string {
+	address = strings.TrimSuffix(address, "/")
+	mount := strings.Trim(vault.Mount, "/")
+	path := strings.Trim(vault.Path, "/")
+
+	if vault.KvVersion == vaultKvVersion1 {
+		return fmt.Sprintf("%s/v1/%s/%s", address, mount, path)
+	}
+
+	return fmt.Sprintf("%s/v1/%s/data/%s", address, mount, path)
+}
+
+func decodeVaultSecret(body []byte, kvVersion int) (map[string]string, error) {
+	var raw map[string]any
+
+	if kvVersion == vaultKvVersion1 {
+		var response struct {
+			Data map[string]any `json:"data"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("error decoding vault response: %w", err)
+		}
+		raw = response.Data
+	} else {
+		var response struct {
+			Data struct {
+				Data map[string]any `json:"data"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("error decoding vault response: %w", err)
+		}
+		raw = response.Data.Data
+	}
+
+	data := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			data[key] = v
+		default:
+			// Service account keys are often stored as JSON objects.
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			data[key] = string(encoded)
+		}
+	}
+
+	return data, nil
+}
+
+func vaultOrigin(vault *VaultConfig) string {
+	return "vault:" + vault.Mount + "/" + strings.Trim(vault.Path, "/")
+}
@@ -0,0 +1,138 @@
+package resolver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newVaultTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/secret/data/stackit/dns", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"data":{"auth-token":"kv2-token","sa.json":{"id":"key"}},"metadata":{"version":3}}}`))
+	})
+	mux.HandleFunc("/v1/kv/stackit/dns", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"auth-token":"kv1-token"}}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestVaultProvider(t *testing.T) {
+	t.Parallel()
+
+	server := newVaultTestServer(t)
+	tokenPath := filepath.Join(t.TempDir(), "vault-token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("vault-token\n"), 0o600))
+
+	provider := &vaultProvider{
+		httpClient:  server.Client(),
+		credentials: newCredentialWatcher(zap.NewNop(), nil),
+		address:     server.URL,
+		tokenPath:   tokenPath,
+	}
+
+	testCases := []struct {
+		name    string
+		vault   *VaultConfig
+		want    Credential
+		wantErr string
+	}{
+		{
+			name:  "kv v2 token",
+			vault: &VaultConfig{Path: "stackit/dns"},
+			want:  Credential{AuthToken: "kv2-token", Origin: "vault:secret/stackit/dns"},
+		},
+		{
+			name: "kv v2 service account key stored as object",
+			vault: &VaultConfig{
+				Path: "stackit/dns", ServiceAccountKeyKey: "sa.json",
+			},
+			want: Credential{SaKey: `{"id":"key"}`, Origin: "vault:secret/stackit/dns"},
+		},
+		{
+			name:  "kv v1 token",
+			vault: &VaultConfig{Mount: "kv", KvVersion: 1, Path: "stackit/dns"},
+			want:  Credential{AuthToken: "kv1-token", Origin: "vault:kv/stackit/dns"},
+		},
+		{
+			name:    "missing key",
+			vault:   &VaultConfig{Path: "stackit/dns", TokenKey: "other"},
+			wantErr: `key "other" not found in vault secret "stackit/dns"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			setVaultDefaultValues(tc.vault)
+			credential, err := provider.Credential(&StackitDnsProviderConfig{Vault: tc.vault})
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, credential)
+		})
+	}
+
+	_, err := provider.Credential(&StackitDnsProviderConfig{})
+	require.ErrorIs(t, err, ErrCredentialNotConfigured)
+
+	vault := &VaultConfig{Path: "stackit/dns"}
+	setVaultDefaultValues(vault)
+
+	unreadable := *provider
+	unreadable.tokenPath = filepath.Join(t.TempDir(), "none")
+	_, err = unreadable.Credential(&StackitDnsProviderConfig{Vault: vault})
+	require.ErrorContains(t, err, "no such file")
+
+	unconfigured := *provider
+	unconfigured.address = ""
+	_, err = unconfigured.Credential(&StackitDnsProviderConfig{Vault: vault})
+	require.ErrorContains(t, err, "no vault address configured, set VAULT_ADDR")
+}
+
+// TestVaultProvider_DevServer runs against a real Vault dev server, e.g.
+//
+//	vault server -dev -dev-root-token-id=root
+//	vault kv put secret/stackit/dns auth-token=dev-token
+//	VAULT_ADDR=http://127.0.0.1:8200 VAULT_TOKEN=root go test ./internal/resolver -run DevServer
+func TestVaultProvider_DevServer(t *testing.T) {
+	address := os.Getenv("VAULT_ADDR")
+	if address == "" || os.Getenv("VAULT_TOKEN") == "" {
+		t.Skip("VAULT_ADDR and VAULT_TOKEN not set")
+	}
+
+	provider := &vaultProvider{
+		httpClient:  http.DefaultClient,
+		credentials: newCredentialWatcher(zap.NewNop(), nil),
+		address:     address,
+		token:       os.Getenv("VAULT_TOKEN"),
+	}
+	vault := &VaultConfig{Path: "stackit/dns"}
+	setVaultDefaultValues(vault)
+
+	credential, err := provider.Credential(&StackitDnsProviderConfig{Vault: vault})
+	require.NoError(t, err)
+	require.NotEmpty(t, credential.AuthToken)
+}
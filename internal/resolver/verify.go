@@ -0,0 +1,114 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/metrics"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	"go.uber.org/zap"
+)
+
+// maxVerifyAttempts bounds how often a write is checked, re-applying it in
+// between.
+const maxVerifyAttempts = 3
+
+var ErrVerificationFailed = errors.New("challenge record verification failed")
+
+// verifyWrite re-fetches the record set after Present or CleanUp and checks
+// that the challenge key is present or absent respectively. If another writer
+// clobbered the change, it is re-applied.
+func (s *stackitDnsProviderResolver) verifyWrite(
+	initResolverRes *initResolverContextResult,
+	challengeKey string,
+	operation string,
+) error {
+	wantPresent := operation == operationPresent
+
+	for attempt := 1; ; attempt++ {
+		present, err := s.challengeKeyPresent(initResolverRes, challengeKey)
+		if err != nil {
+			metrics.ChallengeVerifications.WithLabelValues(operation, "error").Inc()
+
+			return err
+		}
+
+		if present == wantPresent {
+			result := "verified"
+			if attempt > 1 {
+				result = "reapplied"
+			}
+			metrics.ChallengeVerifications.WithLabelValues(operation, result).Inc()
+			s.logger.Info(
+				"Challenge record verified",
+				zap.String("rrSetName", initResolverRes.rrSetName),
+				zap.String("operation", operation),
+				zap.Int("attempt", attempt),
+			)
+
+			return nil
+		}
+
+		if attempt == maxVerifyAttempts {
+			metrics.ChallengeVerifications.WithLabelValues(operation, "failed").Inc()
+			s.logger.Error(
+				"Challenge record verification failed",
+				zap.String("rrSetName", initResolverRes.rrSetName),
+				zap.String("operation", operation),
+				zap.Int("attempts", attempt),
+			)
+
+			state := "missing"
+			if present {
+				state = "present"
+			}
+
+			return fmt.Errorf("%w: challenge key for %s is still %s after %d attempts",
+				ErrVerificationFailed, initResolverRes.rrSetName, state, attempt)
+		}
+
+		s.logger.Warn(
+			"Challenge record was changed by another writer, re-applying",
+			zap.String("rrSetName", initResolverRes.rrSetName),
+			zap.String("operation", operation),
+			zap.Int("attempt", attempt),
+		)
+
+		select {
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		case <-time.After(s.rrSetPollInterval):
+		}
+
+		if wantPresent {
+			err = s.presentChallengeKey(initResolverRes, challengeKey)
+		} else {
+			err = s.handleRRSetCleanup(initResolverRes, challengeKey)
+		}
+		if err != nil {
+			metrics.ChallengeVerifications.WithLabelValues(operation, "error").Inc()
+
+			return err
+		}
+	}
+}
+
+func (s *stackitDnsProviderResolver) challengeKeyPresent(
+	initResolverRes *initResolverContextResult,
+	challengeKey string,
+) (bool, error) {
+	rrSet, err := initResolverRes.rrSetRepository.FetchRRSetForZone(
+		s.ctx,
+		initResolverRes.rrSetName,
+		typeTxtRecord,
+	)
+	if errors.Is(err, repository.ErrRRSetNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return keyExists(rrSet.Records, challengeKey), nil
+}
@@ -0,0 +1,119 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/metrics"
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	repository_mock "github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository/mock"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func txtRRSet(keys ...string) *stackitdnsclient.RecordSet {
+	rrSet := &stackitdnsclient.RecordSet{Id: "rrset", Name: "_acme-challenge.test.com."}
+	for _, key := range keys {
+		rrSet.Records = append(rrSet.Records, stackitdnsclient.Record{Content: key})
+	}
+
+	return rrSet
+}
+
+func TestVerifyWrite(t *testing.T) {
+	t.Parallel()
+
+	initResolverRes := func(rrSetRepository repository.RRSetRepository) *initResolverContextResult {
+		return &initResolverContextResult{
+			rrSetRepository: rrSetRepository,
+			rrSetName:       "_acme-challenge.test.com.",
+			verifyWrites:    true,
+		}
+	}
+
+	t.Run("present verified", func(t *testing.T) {
+		before := testutil.ToFloat64(metrics.ChallengeVerifications.WithLabelValues("present", "verified"))
+
+		ctrl := gomock.NewController(t)
+		rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+		rrSetRepository.EXPECT().FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).Return(txtRRSet("key"), nil)
+
+		err := newRRSetStateTestResolver().verifyWrite(initResolverRes(rrSetRepository), "key", operationPresent)
+		require.NoError(t, err)
+		require.InDelta(t, before+1,
+			testutil.ToFloat64(metrics.ChallengeVerifications.WithLabelValues("present", "verified")), 0)
+	})
+
+	t.Run("present clobbered and re-applied", func(t *testing.T) {
+		before := testutil.ToFloat64(metrics.ChallengeVerifications.WithLabelValues("present", "reapplied"))
+
+		ctrl := gomock.NewController(t)
+		rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+		gomock.InOrder(
+			// verification finds the key overwritten by another writer
+			rrSetRepository.EXPECT().FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(txtRRSet("other"), nil),
+			// re-apply
+			rrSetRepository.EXPECT().FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(txtRRSet("other"), nil),
+			rrSetRepository.EXPECT().UpdateRRSet(gomock.Any(), gomock.Any()).Return(nil),
+			// second verification
+			rrSetRepository.EXPECT().FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(txtRRSet("other", "key"), nil),
+		)
+
+		err := newRRSetStateTestResolver().verifyWrite(initResolverRes(rrSetRepository), "key", operationPresent)
+		require.NoError(t, err)
+		require.InDelta(t, before+1,
+			testutil.ToFloat64(metrics.ChallengeVerifications.WithLabelValues("present", "reapplied")), 0)
+	})
+
+	t.Run("present keeps failing", func(t *testing.T) {
+		before := testutil.ToFloat64(metrics.ChallengeVerifications.WithLabelValues("present", "failed"))
+
+		ctrl := gomock.NewController(t)
+		rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+		rrSetRepository.EXPECT().FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(context.Context, string, string) (*stackitdnsclient.RecordSet, error) {
+				return txtRRSet("other"), nil
+			}).AnyTimes()
+		rrSetRepository.EXPECT().UpdateRRSet(gomock.Any(), gomock.Any()).Return(nil).Times(maxVerifyAttempts - 1)
+
+		err := newRRSetStateTestResolver().verifyWrite(initResolverRes(rrSetRepository), "key", operationPresent)
+		require.ErrorIs(t, err, ErrVerificationFailed)
+		require.ErrorContains(t, err, "is still missing after 3 attempts")
+		require.InDelta(t, before+1,
+			testutil.ToFloat64(metrics.ChallengeVerifications.WithLabelValues("present", "failed")), 0)
+	})
+
+	t.Run("cleanup verified when the rrset is gone", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+		rrSetRepository.EXPECT().FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, repository.ErrRRSetNotFound)
+
+		err := newRRSetStateTestResolver().verifyWrite(initResolverRes(rrSetRepository), "key", operationCleanUp)
+		require.NoError(t, err)
+	})
+
+	t.Run("cleanup re-applied when the key came back", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		rrSetRepository := repository_mock.NewMockRRSetRepository(ctrl)
+		gomock.InOrder(
+			rrSetRepository.EXPECT().FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(txtRRSet("key", "other"), nil),
+			rrSetRepository.EXPECT().FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(txtRRSet("key", "other"), nil),
+			rrSetRepository.EXPECT().UpdateRRSet(gomock.Any(), gomock.Cond(func(rrSet stackitdnsclient.RecordSet) bool {
+				return !keyExists(rrSet.Records, "key")
+			})).Return(nil),
+			rrSetRepository.EXPECT().FetchRRSetForZone(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(txtRRSet("other"), nil),
+		)
+
+		err := newRRSetStateTestResolver().verifyWrite(initResolverRes(rrSetRepository), "key", operationCleanUp)
+		require.NoError(t, err)
+	})
+}
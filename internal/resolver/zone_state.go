@@ -0,0 +1,101 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"go.uber.org/zap"
+)
+
+const defaultZonePollInterval = 2 * time.Second
+
+var (
+	ErrSecondaryZone = errors.New("zone is a secondary zone")
+	ErrZoneNotReady  = errors.New("zone is not ready for changes")
+	// ErrZoneDeleted wraps repository.ErrZoneNotFound, so CleanUp treats a
+	// zone that is being deleted like one that is gone.
+	ErrZoneDeleted = fmt.Errorf("zone is being deleted: %w", repository.ErrZoneNotFound)
+)
+
+// checkZonePrimary fails for secondary zones, whose record sets are
+// overwritten by the next zone transfer from their primaries. An empty type,
+// as returned by older API versions, counts as a primary zone.
+func checkZonePrimary(zone *stackitdnsclient.Zone) error {
+	if zone.Type == stackitdnsclient.ZONETYPE_SECONDARY {
+		return fmt.Errorf("%w: %s (%s) is transferred from %s and cannot be changed through the DNS API",
+			ErrSecondaryZone, zone.DnsName, zone.Id, strings.Join(zone.Primaries, ", "))
+	}
+
+	return nil
+}
+
+// checkZoneWritable fails for zones the webhook must not write to: secondary
+// zones and zones that are still being created, are being deleted or failed
+// their last operation. An empty state counts as a ready zone.
+func checkZoneWritable(zone *stackitdnsclient.Zone) error {
+	if err := checkZonePrimary(zone); err != nil {
+		return err
+	}
+
+	switch zone.State {
+	case stackitdnsclient.ZONESTATE_CREATING,
+		stackitdnsclient.ZONESTATE_CREATE_FAILED,
+		stackitdnsclient.ZONESTATE_UPDATE_FAILED,
+		stackitdnsclient.ZONESTATE_DELETE_FAILED:
+		return fmt.Errorf("%w: %s (%s) is in state %s", ErrZoneNotReady, zone.DnsName, zone.Id, zone.State)
+	case stackitdnsclient.ZONESTATE_DELETING, stackitdnsclient.ZONESTATE_DELETE_SUCCEEDED:
+		return fmt.Errorf("%w: %s (%s) is in state %s", ErrZoneDeleted, zone.DnsName, zone.Id, zone.State)
+	default:
+		return nil
+	}
+}
+
+// awaitZoneUpdate re-fetches a zone in state UPDATING until its update
+// finished or timeout passed, failing with ErrZoneNotReady after that. A
+// timeout of zero returns the zone as is, since record sets can be changed
+// while the zone is updating.
+func (s *stackitDnsProviderResolver) awaitZoneUpdate(
+	zoneRepository repository.ZoneRepository,
+	zone *stackitdnsclient.Zone,
+	timeout time.Duration,
+) (*stackitdnsclient.Zone, error) {
+	if timeout == 0 || zone.State != stackitdnsclient.ZONESTATE_UPDATING {
+		return zone, nil
+	}
+
+	s.logger.Info("Waiting for zone update", zap.String("zoneDnsName", zone.DnsName), zap.Duration("timeout", timeout))
+
+	ctx, cancel := context.WithTimeout(s.ctx, timeout)
+	defer cancel()
+
+	for zone.State == stackitdnsclient.ZONESTATE_UPDATING {
+		select {
+		case <-ctx.Done():
+			return nil, zoneStillUpdatingError(zone, timeout)
+		case <-time.After(s.zonePollInterval):
+		}
+
+		fetched, err := zoneRepository.FetchZone(ctx, zone.DnsName)
+		switch {
+		case err == nil:
+			zone = fetched
+		case ctx.Err() != nil:
+			// The fetch was cut off by the timeout, not by the API.
+			return nil, zoneStillUpdatingError(zone, timeout)
+		default:
+			return nil, err
+		}
+	}
+
+	return zone, nil
+}
+
+func zoneStillUpdatingError(zone *stackitdnsclient.Zone, timeout time.Duration) error {
+	return fmt.Errorf("%w: %s (%s) is still in state %s after %s",
+		ErrZoneNotReady, zone.DnsName, zone.Id, zone.State, timeout)
+}
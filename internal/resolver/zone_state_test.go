@@ -0,0 +1,140 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository"
+	repository_mock "github.com/stackitcloud/stackit-cert-manager-webhook/internal/repository/mock"
+	stackitdnsclient "github.com/stackitcloud/stackit-sdk-go/services/dns/v1api"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestCheckZoneWritable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		zone  stackitdnsclient.Zone
+		err   error
+		cause string
+	}{
+		{name: "type and state unset", zone: stackitdnsclient.Zone{}},
+		{
+			name: "primary",
+			zone: stackitdnsclient.Zone{
+				Type:  stackitdnsclient.ZONETYPE_PRIMARY,
+				State: stackitdnsclient.ZONESTATE_CREATE_SUCCEEDED,
+			},
+		},
+		{name: "updating", zone: stackitdnsclient.Zone{State: stackitdnsclient.ZONESTATE_UPDATING}},
+		{
+			name:  "secondary",
+			zone:  stackitdnsclient.Zone{Type: stackitdnsclient.ZONETYPE_SECONDARY, Primaries: []string{"192.0.2.1"}},
+			err:   ErrSecondaryZone,
+			cause: "transferred from 192.0.2.1",
+		},
+		{
+			name:  "creating",
+			zone:  stackitdnsclient.Zone{State: stackitdnsclient.ZONESTATE_CREATING},
+			err:   ErrZoneNotReady,
+			cause: "in state CREATING",
+		},
+		{
+			name:  "update failed",
+			zone:  stackitdnsclient.Zone{State: stackitdnsclient.ZONESTATE_UPDATE_FAILED},
+			err:   ErrZoneNotReady,
+			cause: "in state UPDATE_FAILED",
+		},
+		{
+			name:  "deleting",
+			zone:  stackitdnsclient.Zone{State: stackitdnsclient.ZONESTATE_DELETING},
+			err:   repository.ErrZoneNotFound,
+			cause: "being deleted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.zone.Id = "zone"
+			tt.zone.DnsName = "test.com"
+			err := checkZoneWritable(&tt.zone)
+			if tt.err == nil {
+				require.NoError(t, err)
+
+				return
+			}
+			require.ErrorIs(t, err, tt.err)
+			require.ErrorContains(t, err, "test.com (zone)")
+			require.ErrorContains(t, err, tt.cause)
+		})
+	}
+}
+
+func TestAwaitZoneUpdate(t *testing.T) {
+	t.Parallel()
+
+	updating := &stackitdnsclient.Zone{DnsName: "test.com", State: stackitdnsclient.ZONESTATE_UPDATING}
+	s := &stackitDnsProviderResolver{ctx: context.Background(), logger: zap.NewNop(), zonePollInterval: time.Millisecond}
+
+	t.Run("waits until the update finished", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		zoneRepository := repository_mock.NewMockZoneRepository(ctrl)
+		gomock.InOrder(
+			zoneRepository.EXPECT().FetchZone(gomock.Any(), "test.com").Return(updating, nil),
+			zoneRepository.EXPECT().FetchZone(gomock.Any(), "test.com").Return(&stackitdnsclient.Zone{
+				DnsName: "test.com",
+				State:   stackitdnsclient.ZONESTATE_UPDATE_SUCCEEDED,
+			}, nil),
+		)
+
+		zone, err := s.awaitZoneUpdate(zoneRepository, updating, time.Second)
+		require.NoError(t, err)
+		require.Equal(t, stackitdnsclient.ZONESTATE_UPDATE_SUCCEEDED, zone.State)
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		zoneRepository := repository_mock.NewMockZoneRepository(ctrl)
+		zoneRepository.EXPECT().FetchZone(gomock.Any(), "test.com").Return(updating, nil).AnyTimes()
+
+		_, err := s.awaitZoneUpdate(zoneRepository, updating, 20*time.Millisecond)
+		require.ErrorIs(t, err, ErrZoneNotReady)
+		require.ErrorContains(t, err, "still in state UPDATING")
+	})
+
+	t.Run("times out while fetching", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		zoneRepository := repository_mock.NewMockZoneRepository(ctrl)
+		zoneRepository.EXPECT().
+			FetchZone(gomock.Any(), "test.com").
+			DoAndReturn(func(ctx context.Context, _ string) (*stackitdnsclient.Zone, error) {
+				<-ctx.Done()
+
+				return nil, ctx.Err()
+			})
+
+		_, err := s.awaitZoneUpdate(zoneRepository, updating, 20*time.Millisecond)
+		require.ErrorIs(t, err, ErrZoneNotReady)
+		require.NotErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("does not wait without timeout", func(t *testing.T) {
+		t.Parallel()
+
+		zone, err := s.awaitZoneUpdate(nil, updating, 0)
+		require.NoError(t, err)
+		require.Same(t, updating, zone)
+	})
+}